@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"degen/pkg/accounts"
+	"degen/pkg/config"
+	"degen/pkg/journal"
+	"degen/pkg/models"
+	"degen/pkg/paper"
+	"degen/pkg/sizing"
+)
+
+// replay runs strategies on simulated exchanges fed with recorded
+// market data. Everything runs synchronously in the order of messages.
+// Orders are journaled if journal is set.
+type replay struct {
+	e *engine
+	// fills is a number of simulated fills.
+	fills int
+}
+
+func newReplay(ctx context.Context, cfg *config.Config, j *journal.Journal) (*replay, error) {
+	rp := &replay{e: newEngine(cfg, j)}
+	e := rp.e
+	orderHandlers := e.orderHandlers()
+	accountHandlers := e.accountHandlers()
+	var watchers []models.Handlers
+	emit := func(msg models.ExchangeMessage) {
+		if msg.MsgType == models.MsgTypeFill {
+			rp.fills++
+		}
+		orderHandlers.Handle(msg)
+		accountHandlers.Handle(msg)
+		for _, h := range watchers {
+			h.Handle(msg)
+		}
+	}
+
+	for _, exCfg := range cfg.Exchanges {
+		sim := paper.New(exCfg.Name, exCfg.Account, cfg.Paper, emit)
+		v, err := e.addVenue(exCfg, accounts.Credentials{}, sim, sim)
+		if err != nil {
+			return nil, err
+		}
+		v.paper = sim
+	}
+
+	if err := e.addStrategies(ctx); err != nil {
+		return nil, err
+	}
+	for _, r := range e.runners {
+		r := r
+		if r.targeting != nil {
+			r.targeting.OnSignal(func(t sizing.Target) {
+				e.placeTarget(ctx, r, t)
+			})
+		}
+		if h, ok := r.watchOrders(); ok {
+			watchers = append(watchers, h)
+		}
+	}
+
+	// Seeding accounts with simulated initial balances.
+	if err := e.reconcile(ctx); err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
+
+// see passes market data message to simulated exchanges and strategies.
+func (rp *replay) see(msg models.ExchangeMessage) {
+	e := rp.e
+	switch msg.MsgType {
+	case models.MsgTypeBBO:
+		bbo, _ := msg.BBO()
+		e.onBBO(msg, bbo)
+		e.tracker.OnBBO(msg.Exchange, msg.Symbol, bbo)
+	case models.MsgTypeFunding, models.MsgTypeTrade:
+	default:
+		return
+	}
+
+	for _, r := range e.runners {
+		if r.sees(msg) {
+			r.see(msg)
+		}
+	}
+}
+
+// backtest replays market data recorded with dump command through
+// strategies trading on simulated exchanges and reports resulting PnL.
+func backtest(ctx context.Context, cfg *config.Config, input string) error {
+	f, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("failed to open market data: %w", err)
+	}
+	defer f.Close()
+
+	rp, err := newReplay(ctx, cfg, nil)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	err = scanMarketData(f, func(msg models.ExchangeMessage) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		count++
+		rp.see(msg)
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read %s: %w", input, err)
+	}
+
+	logger.Info("backtest finished", "messages", count, "input", input)
+	rp.e.logPnL()
+
+	return nil
+}
+
+// scanMarketData calls fn with messages of recorded market data.
+func scanMarketData(f *os.File, fn func(msg models.ExchangeMessage) error) error {
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var msg models.ExchangeMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -3,101 +3,102 @@ package main
 import (
 	"context"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"os/signal"
-	"sort"
-	"strconv"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
 
-	"github.com/c-pro/rolling"
-
+	"degen/pkg/bus"
 	"degen/pkg/connectors/binance"
+	"degen/pkg/features"
+	"degen/pkg/logging"
 	"degen/pkg/models"
 )
 
-type (
-	featureFn   func(*rolling.Window) float64
-	featureSpec struct {
-		name string
-		fn   featureFn
-	}
-)
+var logger = logging.New("dumper")
 
-var (
-	symbols         = []string{"ethusdt", "btcusdt", "dogeusdt", "solusdt", "bnbusdt"}
-	windowIntervals = map[string]time.Duration{
-		"1_sec":  time.Second,
-		"5_sec":  5 * time.Second,
-		"30_sec": 30 * time.Second,
-		"1_min":  time.Minute,
-		"5_min":  5 * time.Minute,
-		"10_min": 10 * time.Minute,
-	}
-	dataFields = []string{"bid_price", "bid_size", "ask_price", "ask_size", "buy_volume", "sell_volume", "buy_price", "sell_price"}
-	features   = []featureSpec{
-		{"min", func(w *rolling.Window) float64 { return w.Min() }},
-		{"max", func(w *rolling.Window) float64 { return w.Max() }},
-		{"first", func(w *rolling.Window) float64 { return w.First() }},
-		{"last", func(w *rolling.Window) float64 { return w.Last() }},
-		{"mid", func(w *rolling.Window) float64 { return w.Mid() }},
-		{"avg", func(w *rolling.Window) float64 { return w.Avg() }},
-		{"sum", func(w *rolling.Window) float64 { return w.Sum() }},
-		{"count", func(w *rolling.Window) float64 { return float64(w.Count()) }},
+// openOutput opens CSV output for appending rows with the header. Header
+// is written to a new file, rows are not appended to a file with another
+// header. Schema is written next to the output.
+func openOutput(path string, f *features.Features) (*os.File, error) {
+	schema, err := json.MarshalIndent(f.Schema(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
 	}
-)
 
-func key(symbol, interval, field string) string {
-	return fmt.Sprintf("%s-%s-%s", symbol, interval, field)
-}
+	out, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output: %w", err)
+	}
 
-func getHeader(keys []string) []string {
-	row := []string{"timestamp"}
-	for _, k := range keys {
-		for _, spec := range features {
-			row = append(row, fmt.Sprintf("%s-%s", k, spec.name))
+	header, err := csv.NewReader(out).Read()
+	switch {
+	case errors.Is(err, io.EOF):
+		w := csv.NewWriter(out)
+		if err := w.Write(f.Header()); err != nil {
+			out.Close()
+			return nil, fmt.Errorf("failed to write header: %w", err)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			out.Close()
+			return nil, fmt.Errorf("failed to write header: %w", err)
 		}
+	case err != nil:
+		out.Close()
+		return nil, fmt.Errorf("failed to read header of %s: %w", path, err)
+	case strings.Join(header, ",") != strings.Join(f.Header(), ","):
+		out.Close()
+		return nil, fmt.Errorf("header of %s does not match features schema version %d, use another output",
+			path, features.SchemaVersion)
 	}
 
-	return row
+	if err := os.WriteFile(path+".schema.json", schema, 0o644); err != nil {
+		out.Close()
+		return nil, fmt.Errorf("failed to write schema: %w", err)
+	}
+	return out, nil
 }
 
-func getFeatures(windows map[string]*rolling.Window, keys []string) []string {
-	row := []string{}
-
-	for _, k := range keys {
-		window := windows[k]
-		row = append(row, strconv.FormatInt(time.Now().UnixMilli(), 10))
-		for _, spec := range features {
-			row = append(row, strconv.FormatFloat(spec.fn(window), 'f', -1, 64))
+func main() {
+	configPath := flag.String("config", "", "features config JSON, defaults are used if empty")
+	output := flag.String("output", "binance.csv", "output CSV file")
+	flag.Parse()
+
+	cfg := features.DefaultConfig()
+	if *configPath != "" {
+		var err error
+		if cfg, err = features.LoadConfig(*configPath); err != nil {
+			logger.Error("failed to load config", "err", err)
+			os.Exit(1)
 		}
 	}
+	feats, err := features.New(cfg)
+	if err != nil {
+		logger.Error("failed to create features", "err", err)
+		os.Exit(1)
+	}
 
-	return row
-}
+	f, err := openOutput(*output, feats)
+	if err != nil {
+		logger.Error("failed to open output", "err", err)
+		os.Exit(1)
+	}
+	w := csv.NewWriter(f)
+	defer func() {
+		w.Flush()
+		f.Close()
+	}()
 
-func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	// Initialize rolling windows for the feature vector.
-	keys := []string{}
-	windows := make(map[string]*rolling.Window)
-	for _, s := range symbols {
-		for n, d := range windowIntervals {
-			for _, f := range dataFields {
-				k := key(s, n, f)
-				windows[k] = rolling.NewWindow(86400, d)
-				keys = append(keys, k)
-			}
-		}
-	}
-	sort.Strings(keys)
-
 	ch := make(chan models.ExchangeMessage, 100)
 	go func() {
 		<-ctx.Done()
@@ -117,96 +118,56 @@ func main() {
 	}
 
 	go bnc.Listen(ctx, ch)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := bnc.Close(ctx); err != nil {
+			logger.Error("failed to close binance connector", "err", err)
+		}
+	}()
 
-	if err := bnc.SubscribeBookTickers(ctx, symbols); err != nil {
-		log.Printf("failed to subscribe: %v\n", err)
+	if err := bnc.SubscribeBookTickers(ctx, cfg.Symbols); err != nil {
+		logger.Error("failed to subscribe", "stream", "bookTicker", "err", err)
 		return
 	}
-	if err := bnc.SubscribeBookAggTrades(ctx, symbols); err != nil {
-		log.Printf("failed to subscribe: %v\n", err)
+	if err := bnc.SubscribeBookAggTrades(ctx, cfg.Symbols); err != nil {
+		logger.Error("failed to subscribe", "stream", "aggTrade", "err", err)
 		return
 	}
 
-	f, err := os.OpenFile("binance.csv", os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o644)
-	if err != nil {
-		log.Fatalf("failed to open file: %v", err)
-	}
-
-	w := csv.NewWriter(f)
-	mux := &sync.RWMutex{}
-
-	// If file is empty, write header.
-	if stat, err := f.Stat(); err == nil && stat.Size() == 0 {
-		if err := w.Write(getHeader(keys)); err != nil {
-			log.Fatalf("failed to write header to csv: %v", err)
-		}
-	}
-
-	defer func() {
-		w.Flush()
-		f.Close()
-	}()
-
 	go func() {
 		i := uint64(0)
-		t := time.NewTicker(time.Second)
+		t := time.NewTicker(cfg.RowInterval())
 		defer t.Stop()
 		for {
+			var now time.Time
 			select {
 			case <-ctx.Done():
 				return
-			case <-t.C:
+			case now = <-t.C:
 			}
 
-			mux.RLock()
-			row := getFeatures(windows, keys)
-			if i%60 == 0 {
-				log.Printf("%s: BTC: %06.02f",
-					time.Now().Format(time.RFC3339),
-					(windows[key("btcusdt", "1_sec", "bid_price")].Avg()+
-						windows[key("btcusdt", "1_sec", "ask_price")].Avg())/2,
-				)
-			}
-			mux.RUnlock()
-
-			err := w.Write(row)
-			if err != nil {
-				log.Printf("failed to write row to csv: %v", err)
+			if err := w.Write(feats.Row(now)); err != nil {
+				logger.Error("failed to write row to csv", "err", err)
 			}
 			w.Flush()
 			i++
+			if i%60 == 0 {
+				logger.Info("rows written", "count", i, "output", *output)
+			}
 		}
 	}()
 
-	for msg := range ch {
-		msg.Symbol = strings.ToLower(msg.Symbol)
-		switch msg.MsgType {
-		case models.MsgTypeBBO:
-			bbo := msg.Payload.(models.BBO)
-
-			for n := range windowIntervals {
-				mux.Lock()
-				windows[key(msg.Symbol, n, "ask_price")].Add(bbo.Ask.Price.InexactFloat64())
-				windows[key(msg.Symbol, n, "bid_price")].Add(bbo.Bid.Price.InexactFloat64())
-				windows[key(msg.Symbol, n, "ask_size")].Add(bbo.Ask.Size.InexactFloat64())
-				windows[key(msg.Symbol, n, "bid_size")].Add(bbo.Bid.Size.InexactFloat64())
-				mux.Unlock()
-			}
-		case models.MsgTypeTrade:
-			trade := msg.Payload.(models.Trade)
-			for n := range windowIntervals {
-				mux.Lock()
-				if trade.Side == models.OrderSideBuy {
-					windows[key(msg.Symbol, n, "buy_volume")].Add(trade.Size.InexactFloat64())
-					windows[key(msg.Symbol, n, "buy_price")].Add(trade.Price.InexactFloat64())
-				} else {
-					windows[key(msg.Symbol, n, "sell_volume")].Add(trade.Size.InexactFloat64())
-					windows[key(msg.Symbol, n, "sell_price")].Add(trade.Price.InexactFloat64())
-				}
-				mux.Unlock()
-			}
-		default:
-			continue
-		}
+	eventBus := bus.New()
+	sub := eventBus.Subscribe(bus.Options{
+		Name:      "dumper",
+		Filter:    bus.Filter{MsgTypes: []models.MsgType{models.MsgTypeBBO, models.MsgTypeTrade}},
+		QueueSize: 1000,
+		Policy:    bus.PolicyBlock,
+	})
+	go eventBus.Run(ctx, ch)
+
+	for msg := range sub.C() {
+		feats.Add(msg)
 	}
 }
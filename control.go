@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"degen/pkg/admin"
+	"degen/pkg/bus"
+	"degen/pkg/latency"
+	"degen/pkg/models"
+	"degen/pkg/pnl"
+)
+
+// Engine methods below implement admin.Backend.
+var _ admin.Backend = (*engine)(nil)
+
+func (e *engine) Accounts() []admin.Account {
+	var res []admin.Account
+	for _, acc := range e.accs.List() {
+		res = append(res, admin.Account{
+			Exchange:  acc.Exchange(),
+			Account:   acc.ID(),
+			Balances:  acc.Balances(),
+			Positions: acc.Positions(),
+		})
+	}
+	return res
+}
+
+func (e *engine) OpenOrders() []models.Order {
+	var orders []models.Order
+	for _, v := range e.venues {
+		orders = append(orders, v.orders.OpenOrders()...)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+	})
+	return orders
+}
+
+func (e *engine) PnL() pnl.Snapshot {
+	return e.tracker.Snapshot()
+}
+
+func (e *engine) Subscriptions() []bus.Stats {
+	if e.bus == nil {
+		return nil
+	}
+	return e.bus.Stats()
+}
+
+func (e *engine) Latency() []latency.Stats {
+	return e.latency.Stats()
+}
+
+func (e *engine) Strategies() []admin.Strategy {
+	res := make([]admin.Strategy, 0, len(e.runners))
+	for _, r := range e.runners {
+		params, err := json.Marshal(r.params.get())
+		if err != nil {
+			r.log.Error("failed to marshal strategy params", "err", err)
+		}
+		state, err := r.strategy.State()
+		if err != nil {
+			r.log.Error("failed to get strategy state", "err", err)
+		}
+		res = append(res, admin.Strategy{
+			Name:     r.name,
+			Type:     r.typ,
+			Exchange: r.venue.cfg.Name,
+			Paused:   r.paused.Load(),
+			Params:   params,
+			State:    state,
+		})
+	}
+	return res
+}
+
+// PauseStrategy pauses or resumes strategy, resting orders
+// of paused strategy are canceled.
+func (e *engine) PauseStrategy(ctx context.Context, name string, paused bool) error {
+	for _, r := range e.runners {
+		if r.name == name {
+			r.paused.Store(paused)
+			r.log.Info("strategy paused", "paused", paused)
+			if paused {
+				r.cancelOrders(ctx)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("strategy %s: %w", name, admin.ErrNotFound)
+}
+
+func (e *engine) CancelAll(ctx context.Context) (int, error) {
+	var (
+		total    int
+		firstErr error
+	)
+	for _, v := range e.venues {
+		n, err := v.orders.CancelAll(ctx, "")
+		total += n
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	logger.Info("canceled all orders", "count", total)
+	return total, firstErr
+}
+
+func (e *engine) Flatten(ctx context.Context, exchange, symbol string) ([]models.Order, error) {
+	var orders []models.Order
+	for _, v := range e.venues {
+		name := v.cfg.Name
+		if exchange != "" && name != exchange {
+			continue
+		}
+		order, err := v.orders.Flatten(ctx, symbol)
+		if err != nil {
+			return orders, fmt.Errorf("failed to flatten %s/%s %s: %w", name, v.acc.ID(), symbol, err)
+		}
+		if order != nil {
+			logger.Info("flattening position",
+				"exchange", name,
+				"account", v.acc.ID(),
+				"symbol", symbol,
+				"client_order_id", order.ClientOrderID,
+				"side", order.Side,
+				"size", order.Size,
+			)
+			orders = append(orders, *order)
+		}
+	}
+	return orders, nil
+}
+
+// Kill is a kill switch: strategies are paused, order management
+// accepts only orders reducing positions and all orders are canceled.
+// Trading can be resumed only by restart.
+func (e *engine) Kill(ctx context.Context) error {
+	logger.Warn("kill switch triggered, halting trading")
+	for _, r := range e.runners {
+		r.paused.Store(true)
+	}
+	for _, v := range e.venues {
+		v.orders.Halt()
+	}
+	_, err := e.CancelAll(ctx)
+	return err
+}
+
+func (e *engine) Reload() error {
+	return e.reloadParams()
+}
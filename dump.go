@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"degen/pkg/config"
+	"degen/pkg/connectors/binance"
+	"degen/pkg/models"
+)
+
+// dump records BBO, trades and funding of configured symbols as JSON lines,
+// which can be replayed with backtest command.
+func dump(ctx context.Context, cfg *config.Config) error {
+	f, err := os.OpenFile(cfg.Dump.Output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open output: %w", err)
+	}
+	defer f.Close()
+
+	ch := make(chan models.ExchangeMessage, 1000)
+	for _, exCfg := range cfg.Exchanges {
+		exCfg := exCfg
+		if !cfg.Primary(exCfg) {
+			continue
+		}
+		symbols := cfg.Dump.Symbols
+		if len(symbols) == 0 {
+			symbols = cfg.Symbols(exCfg.Name)
+		}
+
+		bnc := binance.NewBinance(ctx, "", "", exCfg.APIURL, exCfg.WSURL)
+		if bnc == nil {
+			return ctx.Err()
+		}
+		bnc.SetName(exCfg.Name)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := bnc.Close(ctx); err != nil {
+				logger.Error("failed to close connector", "exchange", exCfg.Name, "err", err)
+			}
+		}()
+
+		go bnc.Listen(ctx, ch)
+
+		if err := bnc.SubscribeBookTickers(ctx, symbols); err != nil {
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+		if err := bnc.SubscribeBookAggTrades(ctx, symbols); err != nil {
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+		if err := bnc.SubscribeMarkPrices(ctx, symbols); err != nil {
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+		logger.Info("recording market data", "exchange", exCfg.Name, "symbols", symbols, "output", cfg.Dump.Output)
+	}
+
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+	enc := json.NewEncoder(w)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	count := 0
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("recording finished", "messages", count)
+			return nil
+		case <-ticker.C:
+			if err := w.Flush(); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+		case msg := <-ch:
+			if err := enc.Encode(msg); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+			count++
+		}
+	}
+}
@@ -0,0 +1,842 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"degen/pkg/accounts"
+	"degen/pkg/bus"
+	"degen/pkg/config"
+	"degen/pkg/connectors"
+	"degen/pkg/execution"
+	"degen/pkg/journal"
+	"degen/pkg/latency"
+	"degen/pkg/logging"
+	"degen/pkg/models"
+	"degen/pkg/oms"
+	"degen/pkg/paper"
+	"degen/pkg/pnl"
+	"degen/pkg/sizing"
+	"degen/pkg/strategies"
+)
+
+var (
+	pnlLog   = logging.New("pnl")
+	orderLog = logging.New("orders")
+)
+
+// venueKey identifies venue by exchange and account.
+type venueKey struct {
+	exchange, account string
+}
+
+// venue is an exchange account strategies trade on.
+type venue struct {
+	cfg    config.Exchange
+	acc    *models.Account
+	orders *oms.OMS
+	state  connectors.AccountAPI
+	// paper is set when orders are simulated.
+	paper *paper.Exchange
+}
+
+// runner is a running strategy instance.
+type runner struct {
+	name  string
+	typ   string
+	venue *venue
+	// venues are all venues the strategy trades on, venue included.
+	venues  []*venue
+	symbols []string
+	// feeds are market data message types the strategy sees.
+	feeds    []models.MsgType
+	strategy strategies.Strategy
+	params   params
+	// targeting is set for strategies trading with target positions.
+	targeting strategies.Targeting
+	log       *logging.Logger
+	// paused strategy signals are ignored and its orders rejected.
+	paused atomic.Bool
+
+	// algos are execution algorithms working orders of the strategy.
+	algos    []execution.Algo
+	algosMux sync.Mutex
+}
+
+// params give access to typed parameters of the strategy.
+type params struct {
+	// get returns current parameters.
+	get func() interface{}
+	// parse parses and validates parameters from config.
+	parse func(config.Strategy) (interface{}, error)
+	// set applies parameters returned by parse.
+	set func(interface{}) error
+}
+
+func paramsOf[P any](
+	get func() P,
+	parse func(config.Strategy) (P, error),
+	set func(P) error,
+) params {
+	return params{
+		get:   func() interface{} { return get() },
+		parse: func(st config.Strategy) (interface{}, error) { return parse(st) },
+		set:   func(p interface{}) error { return set(p.(P)) },
+	}
+}
+
+// runnerOrders is an order API of the strategy instance: orders are
+// attributed to the strategy for PnL and rejected while it is paused.
+type runnerOrders struct {
+	e *engine
+	r *runner
+	v *venue
+}
+
+func (o runnerOrders) PlaceOrder(ctx context.Context, order models.Order) (*models.Order, error) {
+	if o.r.paused.Load() {
+		return nil, strategies.ErrPaused
+	}
+	return o.PlaceHedge(ctx, order)
+}
+
+// PlaceHedge places the order even while the strategy is paused,
+// see strategies.Hedger. OMS risk checks still apply.
+func (o runnerOrders) PlaceHedge(ctx context.Context, order models.Order) (*models.Order, error) {
+	v := o.v
+	o.e.tracker.TrackOrder(v.acc, o.r.name, order)
+	start := time.Now()
+	res, err := v.orders.PlaceOrder(ctx, order)
+	o.e.latency.Record(
+		latency.StreamOf(v.cfg.Name, order.Symbol, "order"),
+		latency.StageOrderAck,
+		time.Since(start),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	o.logPlaced(res)
+	return res, nil
+}
+
+// PlaceOrders places orders at once, see strategies.Batch.
+func (o runnerOrders) PlaceOrders(ctx context.Context, orders []models.Order) ([]*models.Order, error) {
+	if o.r.paused.Load() {
+		return nil, strategies.ErrPaused
+	}
+
+	v := o.v
+	start := time.Now()
+	res, err := v.orders.PlaceOrders(ctx, orders, func(order models.Order) {
+		o.e.tracker.TrackOrder(v.acc, o.r.name, order)
+	})
+	if errors.Is(err, oms.ErrNotUnwound) {
+		// Strategy is left with a naked leg, it is stopped until
+		// the position is dealt with.
+		o.r.paused.Store(true)
+		o.r.log.Error("strategy is paused, legs are not unwound", "err", err)
+	}
+	for _, order := range orders {
+		o.e.latency.Record(
+			latency.StreamOf(v.cfg.Name, order.Symbol, "order"),
+			latency.StageOrderAck,
+			time.Since(start),
+		)
+	}
+
+	for _, r := range res {
+		if r != nil {
+			o.logPlaced(r)
+		}
+	}
+	return res, err
+}
+
+func (o runnerOrders) logPlaced(res *models.Order) {
+	log := o.r.log
+	if len(o.r.symbols) > 1 {
+		log = log.With("symbol", res.Symbol)
+	}
+	log.Info("order placed",
+		"client_order_id", res.ClientOrderID,
+		"order_id", res.ExchangeOrderID,
+		"type", res.Type,
+		"side", res.Side,
+		"size", res.Size,
+		"price", res.Price,
+	)
+}
+
+func (o runnerOrders) CancelOrder(ctx context.Context, order models.Order) (*models.Order, error) {
+	return o.v.orders.CancelOrder(ctx, order)
+}
+
+func (o runnerOrders) OpenOrders() []models.Order {
+	return o.v.orders.OpenOrders()
+}
+
+// engine wires accounts, order management, PnL tracking and strategies
+// together. It is shared by live, paper trading and backtest modes.
+type engine struct {
+	cfg     *config.Config
+	accs    *accounts.Accounts
+	tracker *pnl.Tracker
+	journal *journal.Journal
+	venues  map[venueKey]*venue
+	runners []*runner
+	// bus is set when market data is distributed by the event bus.
+	bus     *bus.Bus
+	latency *latency.Recorder
+
+	reloadMux sync.Mutex
+}
+
+func newEngine(cfg *config.Config, j *journal.Journal) *engine {
+	return &engine{
+		cfg:     cfg,
+		accs:    accounts.NewAccounts(),
+		tracker: pnl.NewTracker(),
+		journal: j,
+		venues:  make(map[venueKey]*venue),
+		latency: latency.NewRecorder(1000),
+	}
+}
+
+// addVenue registers the account with its credentials, sub-accounts
+// are registered under their parent accounts.
+func (e *engine) addVenue(
+	cfg config.Exchange,
+	creds accounts.Credentials,
+	api connectors.OrderAPI,
+	state connectors.AccountAPI,
+) (*venue, error) {
+	var (
+		acc *models.Account
+		err error
+	)
+	if cfg.Parent != "" {
+		acc, err = e.accs.AddSubAccount(cfg.Parent, cfg.Account, cfg.Name, creds)
+	} else {
+		acc, err = e.accs.AddAccountWithCredentials(cfg.Account, cfg.Name, creds)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	orders := oms.New(api, acc, e.journal)
+	orders.SetLimits(e.cfg.Risk)
+
+	v := &venue{
+		cfg:    cfg,
+		acc:    acc,
+		orders: orders,
+		state:  state,
+	}
+	e.venues[venueKey{cfg.Name, cfg.Account}] = v
+	return v, nil
+}
+
+// venue returns venue of the config account.
+func (e *engine) venue(ref config.Venue) (*venue, bool) {
+	cfg, ok := e.cfg.Account(ref)
+	if !ok {
+		return nil, false
+	}
+	v, ok := e.venues[venueKey{cfg.Name, cfg.Account}]
+	return v, ok
+}
+
+func (e *engine) addStrategies(ctx context.Context) error {
+	for _, st := range e.cfg.Strategies {
+		refs, err := st.Venues()
+		if err != nil {
+			return fmt.Errorf("strategy %s: %w", st.Name, err)
+		}
+		var venues []*venue
+		for _, ref := range refs {
+			v, ok := e.venue(ref)
+			if !ok {
+				return fmt.Errorf("strategy %s: exchange %s is not connected", st.Name, ref)
+			}
+			venues = append(venues, v)
+		}
+		v := venues[0]
+
+		symbols, err := st.Symbols()
+		if err != nil {
+			return fmt.Errorf("strategy %s: %w", st.Name, err)
+		}
+
+		r := &runner{
+			name:    st.Name,
+			typ:     st.Type,
+			venue:   v,
+			venues:  venues,
+			symbols: symbols,
+			feeds:   []models.MsgType{models.MsgTypeBBO},
+			log:     logger.With("strategy", st.Name, "exchange", st.Exchange, "account", v.acc.ID()),
+		}
+		if len(symbols) == 1 {
+			r.log = r.log.With("symbol", symbols[0])
+		}
+		orders := runnerOrders{e, r, v}
+
+		switch st.Type {
+		case config.StrategyMonkey:
+			p, err := st.MonkeyParams()
+			if err != nil {
+				return fmt.Errorf("strategy %s: %w", st.Name, err)
+			}
+			m := strategies.NewMonkey(ctx, v.acc, p)
+			r.strategy, r.targeting = m, m
+			if p.Execution.Algo == execution.AlgoParticipation {
+				r.feeds = append(r.feeds, models.MsgTypeTrade)
+			}
+			r.params = paramsOf(m.Params, config.Strategy.MonkeyParams, m.SetParams)
+		case config.StrategyMarketMaker:
+			p, err := st.MarketMakerParams()
+			if err != nil {
+				return fmt.Errorf("strategy %s: %w", st.Name, err)
+			}
+			mm := strategies.NewMarketMaker(ctx, v.acc, orders, p)
+			r.strategy = mm
+			r.params = paramsOf(mm.Params, config.Strategy.MarketMakerParams, mm.SetParams)
+		case config.StrategyGrid:
+			p, err := st.GridParams()
+			if err != nil {
+				return fmt.Errorf("strategy %s: %w", st.Name, err)
+			}
+			g := strategies.NewGrid(ctx, orders, p)
+			r.strategy = g
+			r.params = paramsOf(g.Params, config.Strategy.GridParams, g.SetParams)
+		case config.StrategyPairs:
+			p, err := st.PairsParams()
+			if err != nil {
+				return fmt.Errorf("strategy %s: %w", st.Name, err)
+			}
+			pairs := strategies.NewPairs(ctx, orders, p)
+			r.strategy = pairs
+			r.params = paramsOf(pairs.Params, config.Strategy.PairsParams, pairs.SetParams)
+		case config.StrategyArbitrage:
+			p, err := st.ArbitrageParams()
+			if err != nil {
+				return fmt.Errorf("strategy %s: %w", st.Name, err)
+			}
+			legs := make([]strategies.Venue, len(venues))
+			for i, v := range venues {
+				legs[i] = strategies.Venue{Exchange: v.cfg.Name, Account: v.acc, Orders: runnerOrders{e, r, v}}
+			}
+			a, err := strategies.NewArbitrage(ctx, legs, p)
+			if err != nil {
+				return fmt.Errorf("strategy %s: %w", st.Name, err)
+			}
+			r.strategy = a
+			r.params = paramsOf(a.Params, config.Strategy.ArbitrageParams, a.SetParams)
+		case config.StrategyFundingCarry:
+			p, err := st.FundingCarryParams()
+			if err != nil {
+				return fmt.Errorf("strategy %s: %w", st.Name, err)
+			}
+			spot := v
+			if len(venues) > 1 {
+				spot = venues[1]
+			}
+			c, err := strategies.NewFundingCarry(ctx,
+				strategies.Venue{Exchange: v.cfg.Name, Account: v.acc, Orders: orders},
+				strategies.Venue{Exchange: spot.cfg.Name, Account: spot.acc, Orders: runnerOrders{e, r, spot}},
+				p,
+			)
+			if err != nil {
+				return fmt.Errorf("strategy %s: %w", st.Name, err)
+			}
+			r.strategy = c
+			r.feeds = append(r.feeds, models.MsgTypeFunding)
+			r.params = paramsOf(c.Params, config.Strategy.FundingCarryParams, c.SetParams)
+		default:
+			return fmt.Errorf("strategy %s: unsupported type %q", st.Name, st.Type)
+		}
+
+		e.runners = append(e.runners, r)
+	}
+
+	return nil
+}
+
+// trades tells if the runner trades the symbol on the exchange.
+func (r *runner) trades(exchange, symbol string) bool {
+	if r.venueOf(exchange) == nil {
+		return false
+	}
+	for _, s := range r.symbols {
+		if s == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// sees tells if the runner strategy is fed with the market data message.
+func (r *runner) sees(msg models.ExchangeMessage) bool {
+	for _, t := range r.feeds {
+		if t == msg.MsgType {
+			return r.trades(msg.Exchange, msg.Symbol)
+		}
+	}
+	return false
+}
+
+// venueOf returns the runner venue on the exchange or nil.
+func (r *runner) venueOf(exchange string) *venue {
+	for _, v := range r.venues {
+		if v.cfg.Name == exchange {
+			return v
+		}
+	}
+	return nil
+}
+
+// owns tells if the account is one of the runner venues.
+func (r *runner) owns(exchange, account string) bool {
+	for _, v := range r.venues {
+		if v.cfg.Name == exchange && v.acc.ID() == account {
+			return true
+		}
+	}
+	return false
+}
+
+// exchanges returns names of the runner venues.
+func (r *runner) exchanges() []string {
+	names := make([]string, len(r.venues))
+	for i, v := range r.venues {
+		names[i] = v.cfg.Name
+	}
+	return names
+}
+
+// see feeds the strategy and its execution algorithms with market data.
+func (r *runner) see(msg models.ExchangeMessage) {
+	if msg.MsgType != models.MsgTypeTrade {
+		r.strategy.See(msg)
+	}
+	for _, a := range r.working() {
+		a.See(msg)
+	}
+	r.reapAlgos()
+}
+
+// working returns execution algorithms of the runner.
+func (r *runner) working() []execution.Algo {
+	r.algosMux.Lock()
+	defer r.algosMux.Unlock()
+
+	return append([]execution.Algo(nil), r.algos...)
+}
+
+// reapAlgos removes finished execution algorithms logging their results.
+func (r *runner) reapAlgos() {
+	r.algosMux.Lock()
+	defer r.algosMux.Unlock()
+
+	working := r.algos[:0]
+	for _, a := range r.algos {
+		if !a.Done() {
+			working = append(working, a)
+			continue
+		}
+		p := a.Progress()
+		r.log.Info("execution finished",
+			"algo", p.Algo,
+			"side", p.Side,
+			"size", p.Size,
+			"filled", p.Filled,
+			"avg_price", p.AveragePrice,
+			"arrival_price", p.ArrivalPrice,
+			"slippage_bps", p.Slippage,
+			"children", p.Children,
+		)
+	}
+	for i := len(working); i < len(r.algos); i++ {
+		r.algos[i] = nil
+	}
+	r.algos = working
+}
+
+// watchOrders returns handlers passing updates of orders on the runner
+// symbols to the strategy, if it watches them, and execution algorithms.
+func (r *runner) watchOrders() (models.Handlers, bool) {
+	w, ok := r.strategy.(strategies.OrderWatcher)
+	return models.Handlers{
+		OrderUpdate: func(msg models.ExchangeMessage, upd models.OrderUpdate) {
+			if !r.owns(msg.Exchange, msg.Account) || !r.trades(msg.Exchange, upd.Symbol) {
+				return
+			}
+			if ok {
+				w.OnOrderUpdate(upd)
+			}
+			for _, a := range r.working() {
+				a.OnOrderUpdate(upd)
+			}
+		},
+	}, ok || r.targeting != nil
+}
+
+// cancelOrders cancels resting orders of the strategy
+// and stops its execution algorithms.
+func (r *runner) cancelOrders(ctx context.Context) {
+	for _, a := range r.working() {
+		if err := a.Cancel(ctx); err != nil {
+			r.log.Error("failed to cancel execution", "err", err)
+		}
+	}
+	r.reapAlgos()
+
+	rs, ok := r.strategy.(strategies.Resting)
+	if !ok {
+		return
+	}
+	n, err := rs.CancelOrders(ctx)
+	if err != nil {
+		r.log.Error("failed to cancel strategy orders", "err", err)
+	}
+	if n > 0 {
+		r.log.Info("canceled strategy orders", "count", n)
+	}
+}
+
+// reloadParams reloads strategy parameters from the config file.
+// All parameters are validated before any strategy is updated and
+// are applied to all strategies or none, other config changes
+// require restart.
+func (e *engine) reloadParams() error {
+	if e.cfg.Path == "" {
+		return errors.New("config was not loaded from file")
+	}
+
+	e.reloadMux.Lock()
+	defer e.reloadMux.Unlock()
+
+	cfg, err := config.Load(e.cfg.Path)
+	if err != nil {
+		return err
+	}
+
+	type update struct {
+		runner  *runner
+		old     interface{}
+		params  interface{}
+		changes []string
+	}
+
+	var updates []update
+	for _, r := range e.runners {
+		var st *config.Strategy
+		for i := range cfg.Strategies {
+			if cfg.Strategies[i].Name == r.name {
+				st = &cfg.Strategies[i]
+			}
+		}
+		if st == nil {
+			r.log.Warn("strategy is not in config anymore, keeping its parameters")
+			continue
+		}
+
+		if st.Type != r.typ {
+			return fmt.Errorf("strategy %s: type can not be changed from %s to %s without restart",
+				r.name, r.typ, st.Type)
+		}
+		params, err := r.params.parse(*st)
+		if err != nil {
+			return fmt.Errorf("strategy %s: %w", r.name, err)
+		}
+		symbols, _ := st.Symbols()
+		if was, now := strings.Join(r.symbols, ","), strings.Join(symbols, ","); was != now {
+			return fmt.Errorf("strategy %s: symbols can not be changed from %s to %s without restart",
+				r.name, was, now)
+		}
+		old := r.params.get()
+		changes, err := config.Diff(old, params)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, update{r, old, params, changes})
+	}
+
+	for i, u := range updates {
+		if err := u.runner.params.set(u.params); err != nil {
+			// Rolling back, so parameters are updated for all strategies or none.
+			for _, done := range updates[:i] {
+				if err := done.runner.params.set(done.old); err != nil {
+					done.runner.log.Error("failed to roll back strategy parameters", "err", err)
+				}
+			}
+			return fmt.Errorf("strategy %s: %w", u.runner.name, err)
+		}
+	}
+
+	for _, u := range updates {
+		if len(u.changes) == 0 {
+			u.runner.log.Info("strategy parameters are not changed")
+			continue
+		}
+		u.runner.log.Info("strategy parameters changed", "changes", strings.Join(u.changes, ", "))
+	}
+
+	return nil
+}
+
+// restore applies state recovered from the journal.
+func (e *engine) restore(state *journal.State) {
+	for _, v := range e.venues {
+		if st, ok := state.Account(v.acc.Exchange(), v.acc.ID()); ok {
+			st.Restore(v.acc)
+		}
+		v.orders.Restore(state.OpenOrders(v.acc.Exchange(), v.acc.ID()))
+	}
+
+	if state.PnL != nil {
+		e.tracker.Restore(*state.PnL)
+	}
+	for _, rec := range state.Fills {
+		if acc := e.accs.GetAccount(rec.Account, rec.Exchange); acc != nil {
+			e.tracker.OnFill(acc, *rec.Fill)
+		}
+	}
+
+	for _, r := range e.runners {
+		if st, ok := state.Strategies[r.name]; ok {
+			if err := r.strategy.Restore(st); err != nil {
+				r.log.Error("failed to restore strategy state", "err", err)
+			}
+		}
+	}
+
+	logger.Info("recovered journal state", "seq", state.LastSeq)
+}
+
+// reconcile replaces local state with exchange one
+// before strategies are allowed to trade.
+func (e *engine) reconcile(ctx context.Context) error {
+	for _, v := range e.venues {
+		if err := v.orders.Reconcile(ctx, v.state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot journals state of the engine. Orders, accounts and PnL are
+// collected under the journal lock, so records appended concurrently
+// are either reflected in the snapshot or replayed after it. Strategy
+// state is collected before taking the lock: strategies place orders,
+// which are journaled, while holding their own locks.
+func (e *engine) snapshot() {
+	strategies := make(map[string]json.RawMessage, len(e.runners))
+	for _, r := range e.runners {
+		st, err := r.strategy.State()
+		if err != nil {
+			r.log.Error("failed to save strategy state", "err", err)
+			continue
+		}
+		strategies[r.name] = st
+	}
+
+	err := e.journal.WriteSnapshotFunc(func() journal.Snapshot {
+		snap := journal.Snapshot{Strategies: strategies}
+		for _, v := range e.venues {
+			snap.Orders = append(snap.Orders, v.orders.OpenOrders()...)
+		}
+		for _, acc := range e.accs.List() {
+			snap.Accounts = append(snap.Accounts, journal.NewAccountState(acc))
+		}
+		pnlSnap := e.tracker.Snapshot()
+		snap.PnL = &pnlSnap
+		return snap
+	})
+	if err != nil {
+		logger.Error("failed to write snapshot", "err", err)
+	}
+}
+
+// placeTarget places market orders moving position of the strategy
+// towards the target or starts execution algorithm working them.
+func (e *engine) placeTarget(ctx context.Context, r *runner, t sizing.Target) {
+	if r.paused.Load() {
+		r.log.Info("strategy is paused, ignoring target", "position", t.Position)
+		return
+	}
+	r.log.Debug("target", "position", t.Position, "price", t.Price)
+
+	limits := r.targeting.Sizing().Limits()
+	limits.MaxPosition, limits.MaxOrderSize = e.cfg.Risk.MaxPosition, e.cfg.Risk.MaxOrderSize
+	sizer := sizing.NewSizer(r.venue.acc, limits)
+	orders := runnerOrders{e, r, r.venue}
+
+	if cfg := r.targeting.Execution(); cfg.Algo != "" && cfg.Algo != execution.AlgoMarket {
+		if len(r.working()) > 0 {
+			r.log.Info("previous order is being executed, ignoring target", "position", t.Position)
+			return
+		}
+		delta, err := sizer.Delta(t)
+		if err != nil || delta.IsZero() {
+			r.log.Debug("target is not traded", "position", t.Position, "err", err)
+			return
+		}
+		side := models.OrderSideBuy
+		if delta.IsNegative() {
+			side = models.OrderSideSell
+		}
+		a, err := execution.New(ctx, orders, cfg, execution.Parent{
+			Symbol: t.Symbol,
+			Side:   side,
+			Size:   delta.Abs(),
+		})
+		if err != nil {
+			r.log.Error("failed to start execution", "side", side, "err", err)
+			return
+		}
+		r.algosMux.Lock()
+		r.algos = append(r.algos, a)
+		r.algosMux.Unlock()
+		r.log.Info("execution started", "algo", cfg.Algo, "side", side, "size", delta.Abs())
+		return
+	}
+
+	batch, err := sizer.Orders(t)
+	if err != nil {
+		r.log.Error("failed to size orders", "position", t.Position, "err", err)
+		return
+	}
+	for _, order := range batch {
+		if _, err := orders.PlaceOrder(ctx, order); err != nil {
+			r.log.Error("failed to place order", "client_order_id", order.ClientOrderID, "side", order.Side, "err", err)
+			return
+		}
+	}
+}
+
+// venueOf returns venue the user data message belongs to.
+func (e *engine) venueOf(msg models.ExchangeMessage) *venue {
+	return e.venues[venueKey{msg.Exchange, msg.Account}]
+}
+
+// onBBO passes BBO to simulated exchanges of all accounts on the exchange.
+func (e *engine) onBBO(msg models.ExchangeMessage, bbo models.BBO) {
+	for k, v := range e.venues {
+		if k.exchange == msg.Exchange && v.paper != nil {
+			v.paper.OnBBO(msg.Symbol, bbo, msg.Timestamp)
+		}
+	}
+}
+
+// orderHandlers apply order updates and fills to OMS and PnL tracker.
+func (e *engine) orderHandlers() models.Handlers {
+	return models.Handlers{
+		OrderUpdate: func(msg models.ExchangeMessage, upd models.OrderUpdate) {
+			orderLog.Info("order update",
+				"exchange", msg.Exchange,
+				"symbol", upd.Symbol,
+				"client_order_id", upd.ClientOrderID,
+				"order_id", upd.ExchangeOrderID,
+				"status", upd.Status,
+				"filled", upd.FilledSize,
+				"avg_price", upd.AveragePrice,
+			)
+			if v := e.venueOf(msg); v != nil {
+				e.tracker.OnOrderUpdate(v.acc, upd)
+				v.orders.OnOrderUpdate(upd)
+			}
+		},
+		Fill: func(msg models.ExchangeMessage, fill models.Fill) {
+			orderLog.Info("fill",
+				"exchange", msg.Exchange,
+				"symbol", fill.Symbol,
+				"client_order_id", fill.ClientOrderID,
+				"order_id", fill.ExchangeOrderID,
+				"side", fill.Side,
+				"size", fill.Size,
+				"price", fill.Price,
+				"fee", fill.Commission,
+				"fee_asset", fill.CommissionAsset,
+			)
+			if v := e.venueOf(msg); v != nil {
+				v.orders.OnFill(fill, func() { e.tracker.OnFill(v.acc, fill) })
+			}
+		},
+	}
+}
+
+// accountHandlers apply balance and position updates to accounts.
+func (e *engine) accountHandlers() models.Handlers {
+	return models.Handlers{
+		BalanceUpdate: func(msg models.ExchangeMessage, upd models.BalanceUpdate) {
+			if err := e.accs.Route(msg); err != nil {
+				logger.Error("failed to apply balance update", "exchange", msg.Exchange, "err", err)
+				return
+			}
+			if err := e.journal.RecordBalance(msg.Exchange, msg.Account, upd); err != nil {
+				logger.Error("failed to journal balance update", "err", err)
+			}
+			if upd.Reason == models.BalanceUpdateReasonFunding {
+				e.tracker.AddFunding(e.accs.GetAccount(msg.Account, msg.Exchange), "", upd.Change)
+			}
+		},
+		PositionUpdate: func(msg models.ExchangeMessage, upd models.PositionUpdate) {
+			if err := e.accs.Route(msg); err != nil {
+				logger.Error("failed to apply position update", "exchange", msg.Exchange, "err", err)
+				return
+			}
+			if err := e.journal.RecordPosition(msg.Exchange, msg.Account, upd); err != nil {
+				logger.Error("failed to journal position update", "err", err)
+			}
+		},
+	}
+}
+
+// strategyPnL returns total PnL of the strategy on all its symbols.
+func (e *engine) strategyPnL(name string) float64 {
+	var total float64
+	for _, p := range e.tracker.Snapshot().Strategies {
+		if p.Strategy == name {
+			total += p.Total().InexactFloat64()
+		}
+	}
+	return total
+}
+
+func (e *engine) logPnL() {
+	snap := e.tracker.Snapshot()
+	for _, p := range snap.Accounts {
+		b := e.accs.GetAccount(p.Account, p.Exchange).GetBalance(e.cfg.Paper.Asset)
+		pnlLog.Info("account",
+			"exchange", p.Exchange,
+			"account", p.Account,
+			"balance", b.Balance,
+			"pnl", p.Total(),
+			"realized", p.Realized,
+			"unrealized", p.Unrealized,
+			"fees", p.Fees,
+			"other_fees", p.OtherFees,
+			"funding", p.Funding,
+		)
+	}
+	for _, p := range snap.Strategies {
+		if p.Strategy == "" {
+			continue
+		}
+		pnlLog.Info("strategy",
+			"exchange", p.Exchange,
+			"strategy", p.Strategy,
+			"symbol", p.Symbol,
+			"position", p.Position,
+			"pnl", p.Total(),
+		)
+	}
+}
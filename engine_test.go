@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"degen/pkg/config"
+	"degen/pkg/journal"
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+const testGridConfig = `{
+	"exchanges": [{"name": "binance", "account": "test"}],
+	"strategies": [{
+		"name": "grid",
+		"type": "grid",
+		"exchange": "binance",
+		"params": {"symbol": "ethusdt", "lower": "1990", "upper": "2040", "levels": 11}
+	}],
+	"risk": {"max_order_size": "1", "max_position": "100", "max_open_orders": 50}
+}`
+
+func TestSnapshotWhileGridPlacesOrders(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(testGridConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := journal.Open(filepath.Join(dir, "test.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rp, err := newReplay(ctx, cfg, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Grid places orders holding its lock while the price moves
+	// across levels, snapshots must not deadlock with it.
+	fed := make(chan struct{})
+	go func() {
+		defer close(fed)
+		for i := 0; i < 5000; i++ {
+			mid := decimal.NewFromInt(1990 + int64(i%50))
+			rp.see(models.NewBBOMessage("binance", "ethusdt", time.Now(), models.BBO{
+				Bid: models.PriceLevel{Price: mid, Size: decimal.NewFromInt(1)},
+				Ask: models.PriceLevel{Price: mid.Add(decimal.NewFromInt(1)), Size: decimal.NewFromInt(1)},
+			}))
+		}
+	}()
+	snapped := make(chan struct{})
+	go func() {
+		defer close(snapped)
+		for {
+			rp.e.snapshot()
+			select {
+			case <-fed:
+				return
+			default:
+			}
+		}
+	}()
+
+	select {
+	case <-snapped:
+	case <-time.After(10 * time.Second):
+		// Not closing the journal, it is locked by the stuck snapshot.
+		t.Fatal("snapshot deadlocked with grid placing orders")
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := journal.Load(filepath.Join(dir, "test.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.Strategies["grid"]; !ok {
+		t.Error("expected grid state in snapshot")
+	}
+}
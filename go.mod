@@ -3,7 +3,6 @@ module degen
 go 1.19
 
 require (
-	github.com/c-pro/rolling v0.0.0-20230719090925-f64bebf524bd
 	github.com/google/uuid v1.3.0
 	github.com/gorilla/websocket v1.4.1
 	github.com/mailru/easyjson v0.7.7
@@ -12,6 +11,5 @@ require (
 )
 
 require (
-	github.com/gammazero/deque v0.2.1 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
 )
@@ -2,144 +2,155 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"runtime"
 	"strings"
-	"sync"
 	"syscall"
-	"time"
 
-	"degen/pkg/accounts"
-	"degen/pkg/connectors/binance"
-	"degen/pkg/models"
-	"degen/pkg/strategies"
-
-	"github.com/shopspring/decimal"
+	"degen/pkg/config"
+	"degen/pkg/logging"
 )
 
-const (
-	theSymbol = "ethusdt"
-	theAsset  = "usdt"
-)
+var logger = logging.New("main")
+
+const usage = `Usage: degen <command> [flags]
+
+Commands:
+  run              trade with configured strategies
+  paper            trade on live market data with simulated orders
+  backtest         replay recorded market data through strategies
+  optimize         search strategy parameters with walk-forward validation
+  dump             record market data for backtests
+  validate-config  check config and exit
+
+Flags:
+`
+
+func setupLogging(cfg config.Logging) (func(), error) {
+	opts := logging.Options{
+		JSON:       cfg.Format == "json",
+		Level:      cfg.Level,
+		Components: cfg.Components,
+	}
+	closeFn := func() {}
+
+	switch cfg.Output {
+	case "", "stderr":
+		opts.Output = os.Stderr
+	case "stdout":
+		opts.Output = os.Stdout
+	default:
+		f, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		opts.Output = f
+		closeFn = func() { f.Close() }
+	}
+
+	logging.Configure(opts)
+	return closeFn, nil
+}
 
 func main() {
-	initialBalance := decimal.Zero
-	once := sync.Once{}
+	fs := flag.NewFlagSet("degen", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", os.Getenv("DEGEN_CONFIG"), "path to JSON config, defaults are used if empty")
+	input := fs.String("input", "market.jsonl", "backtest, optimize: recorded market data file")
+	var opt optimizeOptions
+	fs.StringVar(&opt.strategy, "strategy", "", "optimize: strategy name, required with several strategies")
+	fs.StringVar(&opt.space, "space", "space.json", "optimize: JSON object of parameter values to try")
+	fs.IntVar(&opt.trials, "trials", 0, "optimize: number of random samples of the space, whole grid if 0")
+	fs.Int64Var(&opt.seed, "seed", 1, "optimize: random search seed")
+	fs.IntVar(&opt.segments, "segments", 4, "optimize: number of walk-forward segments")
+	fs.IntVar(&opt.workers, "workers", runtime.NumCPU(), "optimize: number of parallel replays")
+	output := fs.String("output", "", "dump: output file (overrides config)")
+	symbols := fs.String("symbols", "", "dump: comma separated symbols (overrides config)")
+
+	if len(os.Args) < 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	cmd := os.Args[1]
+	_ = fs.Parse(os.Args[2:])
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		logger.Error("failed to load config", "err", err)
+		os.Exit(1)
+	}
+
+	if *output != "" {
+		cfg.Dump.Output = *output
+	}
+	if *symbols != "" {
+		cfg.Dump.Symbols = strings.Split(*symbols, ",")
+	}
+
+	if cmd == "optimize" {
+		// Replays log every order, only errors are kept.
+		cfg.Logging.Level, cfg.Logging.Components = logging.LevelError, nil
+	}
+
+	closeLog, err := setupLogging(cfg.Logging)
+	if err != nil {
+		logger.Error("failed to setup logging", "err", err)
+		os.Exit(1)
+	}
+	defer closeLog()
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	ch := make(chan models.ExchangeMessage, 100)
-	go func() {
-		<-ctx.Done()
-		close(ch)
-	}()
-
-	bnc := binance.NewBinance(
-		ctx,
-		os.Getenv("BINANCE_KEY"),
-		os.Getenv("BINANCE_SECRET"),
-		"https://testnet.binancefuture.com",
-		"wss://stream.binancefuture.com",
-	)
-
-	if bnc == nil {
-		return
+	switch cmd {
+	case "run":
+		err = trade(ctx, cfg, false)
+	case "paper":
+		err = trade(ctx, cfg, true)
+	case "backtest":
+		err = backtest(ctx, cfg, *input)
+	case "optimize":
+		opt.input = *input
+		err = optimizeParams(ctx, cfg, opt)
+	case "dump":
+		err = dump(ctx, cfg)
+	case "validate-config":
+		err = validateConfig(cfg)
+	default:
+		fs.Usage()
+		os.Exit(2)
 	}
 
-	go bnc.Listen(ctx, ch)
-
-	if err := bnc.SubscribeBookTickers(ctx, []string{theSymbol}); err != nil {
-		log.Printf("failed to subscribe: %v\n", err)
-		return
+	if err != nil && !errors.Is(err, context.Canceled) {
+		logger.Error("command failed", "command", cmd, "err", err)
+		closeLog()
+		os.Exit(1)
 	}
+}
 
-	accs := accounts.NewAccounts()
-	acc := accs.AddAccount("monkey", binance.Name)
-
-	monkey := strategies.NewMonkey(ctx, acc)
-	go func() {
-		for {
-			select {
-			case side := <-monkey.Say():
-				log.Printf("MONKEY WANNA %s!\n", strings.ToUpper(string(side)))
-				order := models.Order{
-					CreatedAt: time.Now().UTC(),
-					Symbol:    theSymbol,
-					Size:      decimal.NewFromFloat(0.25),
-					Side:      side,
-					Type:      models.OrderTypeMarket,
-				}
-				res, err := bnc.API.PlaceOrder(ctx, order)
-				if err != nil {
-					log.Printf("monkey has failed to place an order: %v", err)
-					continue
-				}
-
-				if res.Type == models.OrderTypeMarket {
-					log.Printf("monkey has placed a %s order to %s %v %s\n",
-						res.Type,
-						res.Side,
-						res.Size,
-						res.Symbol,
-					)
-				} else {
-					log.Printf("monkey has placed a %s order to %s %v %s at %v\n",
-						res.Type,
-						res.Side,
-						res.Size,
-						res.Symbol,
-						res.Price,
-					)
-				}
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
-
-	go func() {
-		var lastChange time.Time
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(time.Second):
-				b := acc.GetBalance(theAsset)
-				if b.UpdatedAt.After(lastChange) {
-					pnl := b.Balance.Sub(initialBalance)
-					log.Printf("### Current balance is %v; PNL is %v", b.Balance, pnl)
-					lastChange = b.UpdatedAt
-				}
-			}
+// validateConfig prints config summary. Config itself is validated
+// when loaded, credentials are checked here too.
+func validateConfig(cfg *config.Config) error {
+	for _, ex := range cfg.Exchanges {
+		fmt.Printf("exchange %s, account %s, symbols %v\n", ex.Name, ex.Account, cfg.Symbols(ex.Name))
+		if ex.Parent != "" {
+			fmt.Printf("  sub-account of %s\n", ex.Parent)
 		}
-	}()
-
-	for msg := range ch {
-		switch msg.MsgType {
-		case models.MsgTypeBBO:
-			bbo := msg.Payload.(models.BBO)
-			log.Printf("BBO %s:%s", bbo.Bid.Price.String(), bbo.Ask.Price.String())
-			monkey.See(msg)
-		case models.MsgTypeOrderStatus:
-			upd := msg.Payload.(models.OrderUpdate)
-			log.Printf("%s: %s (%v at %v)\n", upd.ExchangeOrderID, upd.Status, upd.FilledSize, upd.AveragePrice)
-			continue
-		case models.MsgTypeBalanceUpdate:
-			upd := msg.Payload.(models.BalanceUpdate)
-			// log.Printf("Balance %s = %v\n", upd.Asset, upd.Balance)
-			acc.UpdateBalance(upd.Asset, upd.Balance, msg.Timestamp)
-			once.Do(func() {
-				initialBalance = upd.Balance
-			})
-			continue
-		case models.MsgTypePositionUpdate:
-			upd := msg.Payload.(models.PositionUpdate)
-			// log.Printf("Position %s = %v\n", upd.Symbol, upd.Amount)
-			acc.UpdatePosition(upd.Symbol, upd.Amount, upd.EntryPrice, msg.Timestamp)
-			continue
-		default:
-			continue
+		if _, err := ex.Credentials.Resolve(); err != nil {
+			fmt.Printf("  warning: %v (only paper trading and backtests will work)\n", err)
 		}
 	}
+	for _, st := range cfg.Strategies {
+		venues, _ := st.Venues()
+		fmt.Printf("strategy %s (%s) on %v: %s\n", st.Name, st.Type, venues, st.Params)
+	}
+	fmt.Println("config is valid")
+	return nil
 }
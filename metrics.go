@@ -0,0 +1,46 @@
+package main
+
+import (
+	"degen/pkg/metrics"
+)
+
+var (
+	balanceGauge = metrics.NewGauge("degen_balance",
+		"Account balance by asset.", "exchange", "account", "asset")
+	positionGauge = metrics.NewGauge("degen_position",
+		"Position amount, negative for short.", "exchange", "account", "symbol")
+	pnlGauge = metrics.NewGauge("degen_pnl",
+		"Account PnL by component: realized, unrealized, fees, funding and total.", "exchange", "account", "kind")
+	strategyPnLGauge = metrics.NewGauge("degen_strategy_pnl",
+		"Total PnL of strategy by symbol.", "exchange", "account", "strategy", "symbol")
+)
+
+// collectMetrics sets account gauges from the current state.
+func (e *engine) collectMetrics() {
+	balanceGauge.Reset()
+	positionGauge.Reset()
+	for _, acc := range e.accs.List() {
+		for asset, b := range acc.Balances() {
+			balanceGauge.Set(b.Balance.InexactFloat64(), acc.Exchange(), acc.ID(), asset)
+		}
+		for symbol, p := range acc.Positions() {
+			if !p.Amount.IsZero() {
+				positionGauge.Set(p.Amount.InexactFloat64(), acc.Exchange(), acc.ID(), symbol)
+			}
+		}
+	}
+
+	snap := e.tracker.Snapshot()
+	for _, p := range snap.Accounts {
+		pnlGauge.Set(p.Realized.InexactFloat64(), p.Exchange, p.Account, "realized")
+		pnlGauge.Set(p.Unrealized.InexactFloat64(), p.Exchange, p.Account, "unrealized")
+		pnlGauge.Set(p.Fees.InexactFloat64(), p.Exchange, p.Account, "fees")
+		pnlGauge.Set(p.Funding.InexactFloat64(), p.Exchange, p.Account, "funding")
+		pnlGauge.Set(p.Total().InexactFloat64(), p.Exchange, p.Account, "total")
+	}
+	for _, p := range snap.Strategies {
+		if p.Strategy != "" {
+			strategyPnLGauge.Set(p.Total().InexactFloat64(), p.Exchange, p.Account, p.Strategy, p.Symbol)
+		}
+	}
+}
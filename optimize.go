@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"degen/pkg/config"
+	"degen/pkg/models"
+	"degen/pkg/optimize"
+)
+
+// optimizeOptions configure optimize command.
+type optimizeOptions struct {
+	input    string
+	strategy string
+	space    string
+	// trials sampled at random from the space, the whole grid if zero.
+	trials   int
+	seed     int64
+	segments int
+	workers  int
+}
+
+// optimizeParams replays market data through the strategy with parameters
+// of the search space in parallel, validating them walk-forward on
+// consecutive segments of the data, and prints them ranked.
+func optimizeParams(ctx context.Context, cfg *config.Config, opts optimizeOptions) error {
+	st, err := optimizedStrategy(cfg, opts.strategy)
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(opts.space)
+	if err != nil {
+		return fmt.Errorf("failed to read search space: %w", err)
+	}
+	space, err := optimize.ParseSpace(raw)
+	if err != nil {
+		return err
+	}
+	candidates := space.Grid()
+	if opts.trials > 0 {
+		candidates = space.Random(opts.trials, opts.seed)
+	}
+
+	segments, err := loadSegments(opts.input, opts.segments)
+	if err != nil {
+		return err
+	}
+
+	eval := func(ctx context.Context, p optimize.Params, segment int) (optimize.Result, error) {
+		params, err := p.Apply(st.Params)
+		if err != nil {
+			return optimize.Result{}, err
+		}
+		trial := *cfg
+		trial.Path = ""
+		trial.Strategies = []config.Strategy{st}
+		trial.Strategies[0].Params = params
+		return replaySegment(ctx, &trial, st.Name, segments[segment])
+	}
+
+	fmt.Printf("optimizing %s: %d candidates on %d segments of %s\n\n", st.Name, len(candidates), len(segments), opts.input)
+	report, err := optimize.Run(ctx, candidates, len(segments), opts.workers, eval)
+	if err != nil {
+		return err
+	}
+	printReport(report)
+	return nil
+}
+
+// optimizedStrategy returns strategy of the name or the only configured one.
+func optimizedStrategy(cfg *config.Config, name string) (config.Strategy, error) {
+	if name == "" && len(cfg.Strategies) == 1 {
+		return cfg.Strategies[0], nil
+	}
+	for _, st := range cfg.Strategies {
+		if st.Name == name {
+			return st, nil
+		}
+	}
+	return config.Strategy{}, fmt.Errorf("strategy %q is not configured, set it with -strategy", name)
+}
+
+// loadSegments reads market data split into n segments of equal duration.
+func loadSegments(input string, n int) ([][]models.ExchangeMessage, error) {
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open market data: %w", err)
+	}
+	defer f.Close()
+
+	var msgs []models.ExchangeMessage
+	if err := scanMarketData(f, func(msg models.ExchangeMessage) error {
+		msgs = append(msgs, msg)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", input, err)
+	}
+	if len(msgs) == 0 || n < 1 {
+		return nil, fmt.Errorf("no market data in %s to split into %d segments", input, n)
+	}
+
+	start, end := msgs[0].Timestamp, msgs[len(msgs)-1].Timestamp
+	step := end.Sub(start) / time.Duration(n)
+	segments := make([][]models.ExchangeMessage, n)
+	for _, msg := range msgs {
+		i := n - 1
+		if step > 0 {
+			if k := int(msg.Timestamp.Sub(start) / step); k < i {
+				i = k
+			}
+		}
+		segments[i] = append(segments[i], msg)
+	}
+	for i, seg := range segments {
+		if len(seg) == 0 {
+			return nil, fmt.Errorf("segment %d of %s has no market data", i, input)
+		}
+	}
+	return segments, nil
+}
+
+// replaySegment replays messages through the strategy of the config
+// starting flat, sampling its PnL every minute for drawdown.
+func replaySegment(ctx context.Context, cfg *config.Config, strategy string, msgs []models.ExchangeMessage) (optimize.Result, error) {
+	// Strategies are stopped with the replay.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rp, err := newReplay(ctx, cfg, nil)
+	if err != nil {
+		return optimize.Result{}, err
+	}
+
+	var (
+		res       optimize.Result
+		peak      float64
+		lastCheck time.Time
+	)
+	check := func() {
+		pnl := rp.e.strategyPnL(strategy)
+		if pnl > peak {
+			peak = pnl
+		}
+		if dd := peak - pnl; dd > res.MaxDrawdown {
+			res.MaxDrawdown = dd
+		}
+		res.PnL = pnl
+	}
+	for _, msg := range msgs {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		rp.see(msg)
+		if msg.Timestamp.Sub(lastCheck) >= time.Minute {
+			lastCheck = msg.Timestamp
+			check()
+		}
+	}
+	check()
+	res.Trades = rp.fills
+	return res, nil
+}
+
+func printReport(report optimize.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "rank\tin-sample\tout-of-sample\tstddev\tprofitable\tefficiency\tmax_dd\tfills\tparams")
+	for i, st := range report.Ranked {
+		if st.Err != nil {
+			fmt.Fprintf(w, "-\t\t\t\t\t\t\t\t%s: %v\n", st.Params, st.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%.4f\t%.4f\t%.4f\t%.0f%%\t%.2f\t%.4f\t%d\t%s\n",
+			i+1,
+			st.InSample,
+			st.OutOfSample,
+			st.StdDev,
+			st.Profitable*100,
+			st.Efficiency,
+			st.MaxDrawdown,
+			st.Trades,
+			st.Params,
+		)
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "fold\tin-sample\tout-of-sample\tparams")
+	for _, f := range report.Folds {
+		fmt.Fprintf(w, "%d\t%.4f\t%.4f\t%s\n", f.Segment, f.InSample, f.OutOfSample, f.Params)
+	}
+	w.Flush()
+	fmt.Printf("walk-forward out-of-sample PnL: %.4f\n", report.WalkForward)
+}
@@ -1,34 +1,217 @@
 package accounts
 
 import (
+	"errors"
+	"fmt"
+	"sort"
 	"sync"
 
 	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrAccountExists   = errors.New("account already exists")
+	ErrAccountNotFound = errors.New("account not found")
 )
 
+// Credentials are API keys of an account. Either Secret (HMAC)
+// or KeyFile (PEM encoded Ed25519 or RSA private key) is expected.
+type Credentials struct {
+	Key     string
+	Secret  string
+	KeyFile string
+}
+
+type key struct {
+	exchange, id string
+}
+
+type entry struct {
+	account *models.Account
+	parent  string
+	creds   Credentials
+}
+
+// Accounts is a registry of accounts keyed by exchange and account ID.
 type Accounts struct {
-	data map[string]*models.Account
+	data map[key]*entry
 	mux  sync.RWMutex
 }
 
 func NewAccounts() *Accounts {
 	return &Accounts{
-		data: make(map[string]*models.Account),
+		data: make(map[key]*entry),
+	}
+}
+
+// AddAccount registers a new account. It is an error to add account
+// with the same ID twice on the same exchange.
+func (a *Accounts) AddAccount(id, exchange string) (*models.Account, error) {
+	return a.add(id, exchange, "", Credentials{})
+}
+
+// AddAccountWithCredentials registers a new account with its API keys.
+func (a *Accounts) AddAccountWithCredentials(
+	id, exchange string,
+	creds Credentials,
+) (*models.Account, error) {
+	return a.add(id, exchange, "", creds)
+}
+
+// AddSubAccount registers a sub-account of existing parent account
+// on the same exchange. Sub-accounts have their own API keys.
+func (a *Accounts) AddSubAccount(
+	parentID, id, exchange string,
+	creds Credentials,
+) (*models.Account, error) {
+	if a.GetAccount(parentID, exchange) == nil {
+		return nil, fmt.Errorf("parent %s/%s: %w", exchange, parentID, ErrAccountNotFound)
 	}
+
+	return a.add(id, exchange, parentID, creds)
 }
 
-func (a *Accounts) AddAccount(id, exchange string) *models.Account {
+func (a *Accounts) add(id, exchange, parent string, creds Credentials) (*models.Account, error) {
 	a.mux.Lock()
 	defer a.mux.Unlock()
 
+	k := key{exchange, id}
+	if _, ok := a.data[k]; ok {
+		return nil, fmt.Errorf("%s/%s: %w", exchange, id, ErrAccountExists)
+	}
+
 	acc := models.NewAccount(id, exchange)
-	a.data[id] = acc
-	return acc
+	a.data[k] = &entry{
+		account: acc,
+		parent:  parent,
+		creds:   creds,
+	}
+	return acc, nil
 }
 
 func (a *Accounts) GetAccount(id, exchange string) *models.Account {
 	a.mux.RLock()
 	defer a.mux.RUnlock()
 
-	return a.data[id]
+	e, ok := a.data[key{exchange, id}]
+	if !ok {
+		return nil
+	}
+	return e.account
+}
+
+// Credentials returns API keys of the account.
+func (a *Accounts) Credentials(id, exchange string) (Credentials, bool) {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+
+	e, ok := a.data[key{exchange, id}]
+	if !ok {
+		return Credentials{}, false
+	}
+	return e.creds, true
+}
+
+// SubAccounts returns sub-accounts of the parent account.
+func (a *Accounts) SubAccounts(parentID, exchange string) []*models.Account {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+
+	var res []*models.Account
+	for k, e := range a.data {
+		if k.exchange == exchange && e.parent == parentID {
+			res = append(res, e.account)
+		}
+	}
+	sortAccounts(res)
+	return res
+}
+
+// List returns all accounts sorted by exchange and ID.
+func (a *Accounts) List() []*models.Account {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+
+	res := make([]*models.Account, 0, len(a.data))
+	for _, e := range a.data {
+		res = append(res, e.account)
+	}
+	sortAccounts(res)
+	return res
+}
+
+func sortAccounts(accs []*models.Account) {
+	sort.Slice(accs, func(i, j int) bool {
+		if accs[i].Exchange() != accs[j].Exchange() {
+			return accs[i].Exchange() < accs[j].Exchange()
+		}
+		return accs[i].ID() < accs[j].ID()
+	})
+}
+
+// Route applies balance and position updates to the account
+// the message belongs to. Other messages are ignored.
+func (a *Accounts) Route(msg models.ExchangeMessage) error {
+	if msg.MsgType != models.MsgTypeBalanceUpdate &&
+		msg.MsgType != models.MsgTypePositionUpdate {
+		return nil
+	}
+
+	acc := a.GetAccount(msg.Account, msg.Exchange)
+	if acc == nil {
+		return fmt.Errorf("accounts.Route %s/%s: %w", msg.Exchange, msg.Account, ErrAccountNotFound)
+	}
+
+	if upd, ok := msg.BalanceUpdate(); ok {
+		acc.UpdateBalance(upd.Asset, upd.Balance, msg.Timestamp)
+	}
+
+	if upd, ok := msg.PositionUpdate(); ok {
+		acc.UpdatePosition(upd.Symbol, upd.Amount, upd.EntryPrice, msg.Timestamp)
+	}
+
+	return nil
+}
+
+// TotalBalance returns sum of asset balances across all accounts and venues.
+func (a *Accounts) TotalBalance(asset string) decimal.Decimal {
+	total := decimal.Zero
+	for _, acc := range a.List() {
+		total = total.Add(acc.GetBalance(asset).Balance)
+	}
+	return total
+}
+
+// Balances returns aggregated balances of all assets.
+func (a *Accounts) Balances() map[string]decimal.Decimal {
+	res := make(map[string]decimal.Decimal)
+	for _, acc := range a.List() {
+		for asset, b := range acc.Balances() {
+			res[asset] = res[asset].Add(b.Balance)
+		}
+	}
+	return res
+}
+
+// NetPosition returns sum of signed positions in symbol across
+// all accounts and venues.
+func (a *Accounts) NetPosition(symbol string) decimal.Decimal {
+	total := decimal.Zero
+	for _, acc := range a.List() {
+		total = total.Add(acc.GetPosition(symbol).Amount)
+	}
+	return total
+}
+
+// NetPositions returns aggregated positions of all symbols.
+func (a *Accounts) NetPositions() map[string]decimal.Decimal {
+	res := make(map[string]decimal.Decimal)
+	for _, acc := range a.List() {
+		for symbol, p := range acc.Positions() {
+			res[symbol] = res[symbol].Add(p.Amount)
+		}
+	}
+	return res
 }
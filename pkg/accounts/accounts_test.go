@@ -0,0 +1,97 @@
+package accounts
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAddAccount(t *testing.T) {
+	accs := NewAccounts()
+	if _, err := accs.AddAccount("main", "binance"); err != nil {
+		t.Fatalf("AddAccount returned error: %v", err)
+	}
+
+	if _, err := accs.AddAccount("main", "binance"); !errors.Is(err, ErrAccountExists) {
+		t.Errorf("expected ErrAccountExists, got %v", err)
+	}
+
+	if _, err := accs.AddAccount("main", "bybit"); err != nil {
+		t.Errorf("expected same ID to be allowed on other exchange, got %v", err)
+	}
+
+	if _, err := accs.AddSubAccount("nope", "sub", "binance", Credentials{}); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("expected ErrAccountNotFound, got %v", err)
+	}
+
+	creds := Credentials{Key: "key", Secret: "secret"}
+	if _, err := accs.AddSubAccount("main", "sub", "binance", creds); err != nil {
+		t.Fatalf("AddSubAccount returned error: %v", err)
+	}
+
+	if got, _ := accs.Credentials("sub", "binance"); got != creds {
+		t.Errorf("expected credentials %+v, got %+v", creds, got)
+	}
+
+	subs := accs.SubAccounts("main", "binance")
+	if len(subs) != 1 || subs[0].ID() != "sub" {
+		t.Errorf("unexpected sub-accounts %v", subs)
+	}
+
+	if accs.GetAccount("main", "kraken") != nil {
+		t.Error("expected no account on unknown exchange")
+	}
+}
+
+func TestRouteAndAggregate(t *testing.T) {
+	accs := NewAccounts()
+	for _, exchange := range []string{"binance", "bybit"} {
+		if _, err := accs.AddAccount("main", exchange); err != nil {
+			t.Fatalf("AddAccount returned error: %v", err)
+		}
+	}
+
+	msgs := []models.ExchangeMessage{
+		models.NewBalanceUpdateMessage("binance", time.Now(), models.BalanceUpdate{
+			Asset: "usdt", Balance: decimal.NewFromInt(100),
+		}),
+		models.NewBalanceUpdateMessage("bybit", time.Now(), models.BalanceUpdate{
+			Asset: "usdt", Balance: decimal.NewFromInt(50),
+		}),
+		models.NewPositionUpdateMessage("binance", time.Now(), models.PositionUpdate{
+			Symbol: "ethusdt", Amount: decimal.NewFromInt(2),
+		}),
+		models.NewPositionUpdateMessage("bybit", time.Now(), models.PositionUpdate{
+			Symbol: "ethusdt", Amount: decimal.NewFromInt(-3),
+		}),
+	}
+
+	for _, msg := range msgs {
+		msg.Account = "main"
+		if err := accs.Route(msg); err != nil {
+			t.Fatalf("Route returned error: %v", err)
+		}
+	}
+
+	msg := msgs[0]
+	msg.Account = "unknown"
+	if err := accs.Route(msg); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("expected ErrAccountNotFound, got %v", err)
+	}
+
+	if b := accs.GetAccount("main", "bybit").GetBalance("usdt").Balance; !b.Equal(decimal.NewFromInt(50)) {
+		t.Errorf("expected bybit balance 50, got %v", b)
+	}
+
+	if total := accs.TotalBalance("usdt"); !total.Equal(decimal.NewFromInt(150)) {
+		t.Errorf("expected total balance 150, got %v", total)
+	}
+
+	if net := accs.NetPositions()["ethusdt"]; !net.Equal(decimal.NewFromInt(-1)) {
+		t.Errorf("expected net position -1, got %v", net)
+	}
+}
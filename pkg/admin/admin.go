@@ -0,0 +1,232 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"degen/pkg/bus"
+	"degen/pkg/latency"
+	"degen/pkg/logging"
+	"degen/pkg/models"
+	"degen/pkg/pnl"
+)
+
+var ErrNotFound = errors.New("not found")
+
+var logger = logging.New("admin")
+
+// Account is a state of the exchange account.
+type Account struct {
+	Exchange  string                     `json:"exchange"`
+	Account   string                     `json:"account"`
+	Balances  map[string]models.Balance  `json:"balances,omitempty"`
+	Positions map[string]models.Position `json:"positions,omitempty"`
+}
+
+// Strategy is a state of the running strategy.
+type Strategy struct {
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Exchange string          `json:"exchange"`
+	Paused   bool            `json:"paused"`
+	Params   json.RawMessage `json:"params"`
+	State    json.RawMessage `json:"state,omitempty"`
+}
+
+// Backend is a running bot inspected and controlled by admin API.
+type Backend interface {
+	Accounts() []Account
+	OpenOrders() []models.Order
+	PnL() pnl.Snapshot
+	Subscriptions() []bus.Stats
+	Latency() []latency.Stats
+	Strategies() []Strategy
+
+	// PauseStrategy pauses or resumes strategy, returning ErrNotFound
+	// if there is no such strategy.
+	PauseStrategy(ctx context.Context, name string, paused bool) error
+	// CancelAll cancels all open orders and returns their number.
+	CancelAll(ctx context.Context) (int, error)
+	// Flatten closes positions in the symbol, on all exchanges
+	// if exchange is empty.
+	Flatten(ctx context.Context, exchange, symbol string) ([]models.Order, error)
+	// Kill pauses all strategies, halts trading and cancels all orders.
+	Kill(ctx context.Context) error
+	// Reload reloads strategy parameters from the config file.
+	Reload() error
+}
+
+// Server is an HTTP server exposing JSON admin API. Every request
+// has to be authorized with "Authorization: Bearer <token>" header.
+type Server struct {
+	backend Backend
+	token   string
+	mux     *http.ServeMux
+}
+
+func New(backend Backend, token string) (*Server, error) {
+	if token == "" {
+		return nil, errors.New("admin.New: token is required")
+	}
+
+	s := &Server{
+		backend: backend,
+		token:   token,
+		mux:     http.NewServeMux(),
+	}
+
+	s.handleGet("/api/balances", func(r *http.Request) (interface{}, error) {
+		accs := backend.Accounts()
+		for i := range accs {
+			accs[i].Positions = nil
+		}
+		return accs, nil
+	})
+	s.handleGet("/api/positions", func(r *http.Request) (interface{}, error) {
+		accs := backend.Accounts()
+		for i := range accs {
+			accs[i].Balances = nil
+		}
+		return accs, nil
+	})
+	s.handleGet("/api/orders", func(r *http.Request) (interface{}, error) {
+		return backend.OpenOrders(), nil
+	})
+	s.handleGet("/api/pnl", func(r *http.Request) (interface{}, error) {
+		return backend.PnL(), nil
+	})
+	s.handleGet("/api/subscriptions", func(r *http.Request) (interface{}, error) {
+		return backend.Subscriptions(), nil
+	})
+	s.handleGet("/api/latency", func(r *http.Request) (interface{}, error) {
+		return backend.Latency(), nil
+	})
+	s.handleGet("/api/strategies", func(r *http.Request) (interface{}, error) {
+		return backend.Strategies(), nil
+	})
+
+	// POST /api/strategies/<name>/pause and /api/strategies/<name>/resume
+	s.handlePost("/api/strategies/", func(r *http.Request) (interface{}, error) {
+		path := strings.TrimPrefix(r.URL.Path, "/api/strategies/")
+		name, action, _ := strings.Cut(path, "/")
+		switch action {
+		case "pause", "resume":
+		default:
+			return nil, ErrNotFound
+		}
+		if err := backend.PauseStrategy(r.Context(), name, action == "pause"); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"name": name, "paused": action == "pause"}, nil
+	})
+	s.handlePost("/api/cancel-all", func(r *http.Request) (interface{}, error) {
+		n, err := backend.CancelAll(r.Context())
+		return map[string]int{"canceled": n}, err
+	})
+	s.handlePost("/api/flatten", func(r *http.Request) (interface{}, error) {
+		symbol := r.URL.Query().Get("symbol")
+		if symbol == "" {
+			return nil, badRequest("symbol is required")
+		}
+		return backend.Flatten(r.Context(), r.URL.Query().Get("exchange"), symbol)
+	})
+	s.handlePost("/api/kill", func(r *http.Request) (interface{}, error) {
+		logger.Warn("kill switch triggered", "remote", r.RemoteAddr)
+		return map[string]bool{"killed": true}, backend.Kill(r.Context())
+	})
+	s.handlePost("/api/reload", func(r *http.Request) (interface{}, error) {
+		return map[string]bool{"reloaded": true}, backend.Reload()
+	})
+
+	return s, nil
+}
+
+type badRequest string
+
+func (e badRequest) Error() string {
+	return string(e)
+}
+
+func (s *Server) handleGet(path string, h func(*http.Request) (interface{}, error)) {
+	s.handle(http.MethodGet, path, h)
+}
+
+func (s *Server) handlePost(path string, h func(*http.Request) (interface{}, error)) {
+	s.handle(http.MethodPost, path, h)
+}
+
+func (s *Server) handle(method, path string, h func(*http.Request) (interface{}, error)) {
+	s.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		res, err := h(r)
+		if err != nil {
+			status := http.StatusInternalServerError
+			var bad badRequest
+			switch {
+			case errors.Is(err, ErrNotFound):
+				status = http.StatusNotFound
+			case errors.As(err, &bad):
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, res)
+	})
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == auth || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves admin API on addr until context is canceled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("admin.ListenAndServe failed to listen: %w", err)
+	}
+
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+
+	logger.Info("admin API is listening", "addr", l.Addr())
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("admin.ListenAndServe: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("failed to write response", "err", err)
+	}
+}
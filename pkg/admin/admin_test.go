@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"degen/pkg/bus"
+	"degen/pkg/latency"
+	"degen/pkg/models"
+	"degen/pkg/pnl"
+
+	"github.com/shopspring/decimal"
+)
+
+type fakeBackend struct {
+	paused map[string]bool
+	killed bool
+}
+
+func (f *fakeBackend) Accounts() []Account {
+	return []Account{{
+		Exchange:  "ex",
+		Account:   "acc",
+		Balances:  map[string]models.Balance{"usdt": {Balance: decimal.NewFromInt(100)}},
+		Positions: map[string]models.Position{"ethusdt": {Amount: decimal.NewFromInt(1)}},
+	}}
+}
+
+func (f *fakeBackend) OpenOrders() []models.Order             { return nil }
+func (f *fakeBackend) PnL() pnl.Snapshot                      { return pnl.Snapshot{} }
+func (f *fakeBackend) Subscriptions() []bus.Stats             { return nil }
+func (f *fakeBackend) Latency() []latency.Stats               { return nil }
+func (f *fakeBackend) Strategies() []Strategy                 { return nil }
+func (f *fakeBackend) Reload() error                          { return nil }
+func (f *fakeBackend) CancelAll(context.Context) (int, error) { return 2, nil }
+
+func (f *fakeBackend) PauseStrategy(_ context.Context, name string, paused bool) error {
+	if name != "monkey" {
+		return ErrNotFound
+	}
+	f.paused[name] = paused
+	return nil
+}
+
+func (f *fakeBackend) Flatten(_ context.Context, exchange, symbol string) ([]models.Order, error) {
+	return []models.Order{{Exchange: exchange, Symbol: symbol}}, nil
+}
+
+func (f *fakeBackend) Kill(context.Context) error {
+	f.killed = true
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	backend := &fakeBackend{paused: make(map[string]bool)}
+	s, err := New(backend, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		if w := do(http.MethodGet, "/api/balances", token); w.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 for token %q, got %d", token, w.Code)
+		}
+	}
+
+	w := do(http.MethodGet, "/api/balances", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var accs []Account
+	if err := json.Unmarshal(w.Body.Bytes(), &accs); err != nil {
+		t.Fatal(err)
+	}
+	if len(accs) != 1 || len(accs[0].Balances) != 1 || accs[0].Positions != nil {
+		t.Errorf("unexpected balances %s", w.Body)
+	}
+
+	if w := do(http.MethodGet, "/api/kill", "secret"); w.Code != http.StatusMethodNotAllowed || backend.killed {
+		t.Errorf("expected kill to require POST, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/kill", "secret"); w.Code != http.StatusOK || !backend.killed {
+		t.Errorf("expected kill switch to be triggered, got %d", w.Code)
+	}
+
+	if w := do(http.MethodPost, "/api/strategies/monkey/pause", "secret"); w.Code != http.StatusOK || !backend.paused["monkey"] {
+		t.Errorf("expected strategy to be paused, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/strategies/gorilla/pause", "secret"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown strategy, got %d", w.Code)
+	}
+
+	if w := do(http.MethodPost, "/api/flatten", "secret"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without symbol, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/flatten?symbol=ethusdt", "secret"); w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+}
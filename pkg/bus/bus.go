@@ -0,0 +1,187 @@
+package bus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"degen/pkg/models"
+)
+
+// OverflowPolicy defines what happens when subscriber's queue is full.
+type OverflowPolicy uint8
+
+const (
+	// PolicyBlock blocks publisher until subscriber reads from its queue.
+	// Use only for consumers which must not miss anything and are fast.
+	PolicyBlock OverflowPolicy = iota
+	// PolicyDropOldest drops the oldest queued message.
+	PolicyDropOldest
+	// PolicyConflate replaces queued BBO with the latest one
+	// for the same exchange and symbol, otherwise drops the oldest message.
+	PolicyConflate
+)
+
+var policyNames = [...]string{
+	PolicyBlock:      "block",
+	PolicyDropOldest: "drop_oldest",
+	PolicyConflate:   "conflate",
+}
+
+func (p OverflowPolicy) String() string {
+	if int(p) < len(policyNames) {
+		return policyNames[p]
+	}
+	return "unknown"
+}
+
+func (p OverflowPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+const defaultQueueSize = 100
+
+// Filter selects messages for subscription.
+// Empty field matches any value.
+type Filter struct {
+	Exchanges []string
+	Symbols   []string
+	MsgTypes  []models.MsgType
+}
+
+func (f *Filter) Match(msg *models.ExchangeMessage) bool {
+	return matchString(f.Exchanges, msg.Exchange) &&
+		matchString(f.Symbols, msg.Symbol) &&
+		matchType(f.MsgTypes, msg.MsgType)
+}
+
+func matchString(values []string, v string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func matchType(values []models.MsgType, v models.MsgType) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, t := range values {
+		if t == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configure a subscription.
+type Options struct {
+	Name      string
+	Filter    Filter
+	QueueSize int
+	Policy    OverflowPolicy
+}
+
+// Bus fans out exchange messages to subscribers,
+// each one having its own queue.
+type Bus struct {
+	// subs are copied on write, so publishers push to a snapshot
+	// of them without holding the lock.
+	subs []*Subscription
+	mux  sync.RWMutex
+}
+
+func New() *Bus {
+	return &Bus{}
+}
+
+// Subscribe creates subscription. Messages are read from Subscription.C().
+func (b *Bus) Subscribe(opts Options) *Subscription {
+	s := newSubscription(opts)
+
+	b.mux.Lock()
+	b.subs = append(b.subs, s)
+	b.mux.Unlock()
+
+	return s
+}
+
+// Unsubscribe removes subscription and closes its channel.
+func (b *Bus) Unsubscribe(s *Subscription) {
+	b.mux.Lock()
+	subs := make([]*Subscription, 0, len(b.subs))
+	for _, sub := range b.subs {
+		if sub != s {
+			subs = append(subs, sub)
+		}
+	}
+	b.subs = subs
+	b.mux.Unlock()
+
+	s.close(false)
+}
+
+// Publish puts message into queues of all matching subscriptions.
+// The lock is not held while pushing, so a subscriber blocking the
+// publisher can still be unsubscribed or closed.
+func (b *Bus) Publish(msg models.ExchangeMessage) {
+	now := time.Now()
+
+	b.mux.RLock()
+	subs := b.subs
+	b.mux.RUnlock()
+
+	for _, s := range subs {
+		if s.filter.Match(&msg) {
+			s.push(msg, now)
+		}
+	}
+}
+
+// Run publishes messages from ch until it is closed or ctx is done,
+// then closes all subscriptions.
+func (b *Bus) Run(ctx context.Context, ch <-chan models.ExchangeMessage) {
+	defer b.Close()
+
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			b.Publish(msg)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Close closes all subscriptions after delivering queued messages.
+func (b *Bus) Close() {
+	b.mux.Lock()
+	subs := b.subs
+	b.subs = nil
+	b.mux.Unlock()
+
+	for _, s := range subs {
+		s.close(true)
+	}
+}
+
+// Stats returns stats of all subscriptions.
+func (b *Bus) Stats() []Stats {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	stats := make([]Stats, 0, len(b.subs))
+	for _, s := range b.subs {
+		stats = append(stats, s.Stats())
+	}
+
+	return stats
+}
@@ -0,0 +1,207 @@
+package bus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func bboMsg(symbol string, bid int64) models.ExchangeMessage {
+	return models.NewBBOMessage("test", symbol, time.Now(), models.BBO{
+		Bid: models.PriceLevel{Price: decimal.NewFromInt(bid)},
+	})
+}
+
+func tradeMsg(symbol string) models.ExchangeMessage {
+	return models.NewTradeMessage("test", symbol, time.Now(), models.Trade{})
+}
+
+func recv(t *testing.T, s *Subscription) models.ExchangeMessage {
+	t.Helper()
+	select {
+	case msg := <-s.C():
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+	return models.ExchangeMessage{}
+}
+
+func TestFanOut(t *testing.T) {
+	b := New()
+	defer b.Close()
+
+	all := b.Subscribe(Options{Name: "all"})
+	eth := b.Subscribe(Options{
+		Name:   "eth",
+		Filter: Filter{Symbols: []string{"ethusdt"}, MsgTypes: []models.MsgType{models.MsgTypeBBO}},
+	})
+
+	b.Publish(tradeMsg("ethusdt"))
+	b.Publish(bboMsg("btcusdt", 1))
+	b.Publish(bboMsg("ethusdt", 2))
+
+	for _, expected := range []models.MsgType{models.MsgTypeTrade, models.MsgTypeBBO, models.MsgTypeBBO} {
+		if msg := recv(t, all); msg.MsgType != expected {
+			t.Errorf("expected %v, got %v", expected, msg.MsgType)
+		}
+	}
+
+	msg := recv(t, eth)
+	if msg.Symbol != "ethusdt" || msg.MsgType != models.MsgTypeBBO {
+		t.Errorf("unexpected message %v %v", msg.Symbol, msg.MsgType)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for eth.Stats().Delivered != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if stats := eth.Stats(); stats.Published != 1 || stats.Delivered != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+// waitQueued waits until pump takes first message and blocks on delivery,
+// so the rest stays in the queue.
+func waitQueued(t *testing.T, s *Subscription, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for s.Stats().Queued != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d queued messages, got %d", n, s.Stats().Queued)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	b := New()
+	defer b.Close()
+
+	s := b.Subscribe(Options{QueueSize: 2, Policy: PolicyDropOldest})
+	b.Publish(bboMsg("ethusdt", 1))
+	waitQueued(t, s, 0)
+
+	for i := int64(2); i <= 5; i++ {
+		b.Publish(bboMsg("ethusdt", i))
+	}
+
+	for _, expected := range []int64{1, 4, 5} {
+		bbo, _ := recv(t, s).BBO()
+		if bbo.Bid.Price.IntPart() != expected {
+			t.Errorf("expected bid %d, got %v", expected, bbo.Bid.Price)
+		}
+	}
+
+	if stats := s.Stats(); stats.Dropped != 2 {
+		t.Errorf("expected 2 dropped messages, got %+v", stats)
+	}
+}
+
+func TestConflate(t *testing.T) {
+	b := New()
+	defer b.Close()
+
+	s := b.Subscribe(Options{QueueSize: 10, Policy: PolicyConflate})
+	b.Publish(bboMsg("ethusdt", 1))
+	waitQueued(t, s, 0)
+
+	b.Publish(bboMsg("ethusdt", 2))
+	b.Publish(bboMsg("btcusdt", 10))
+	b.Publish(tradeMsg("ethusdt"))
+	b.Publish(bboMsg("ethusdt", 3))
+	b.Publish(bboMsg("btcusdt", 11))
+
+	expected := []struct {
+		symbol string
+		typ    models.MsgType
+		bid    int64
+	}{
+		{"ethusdt", models.MsgTypeBBO, 1},
+		{"ethusdt", models.MsgTypeBBO, 3},
+		{"btcusdt", models.MsgTypeBBO, 11},
+		{"ethusdt", models.MsgTypeTrade, 0},
+	}
+
+	for _, e := range expected {
+		msg := recv(t, s)
+		bbo, _ := msg.BBO()
+		if msg.Symbol != e.symbol || msg.MsgType != e.typ || bbo.Bid.Price.IntPart() != e.bid {
+			t.Errorf("expected %s %v %d, got %s %v %v", e.symbol, e.typ, e.bid, msg.Symbol, msg.MsgType, bbo.Bid.Price)
+		}
+	}
+
+	if stats := s.Stats(); stats.Conflated != 2 {
+		t.Errorf("expected 2 conflated messages, got %+v", stats)
+	}
+}
+
+func TestBlockAndRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := New()
+	s := b.Subscribe(Options{QueueSize: 1, Policy: PolicyBlock})
+
+	ch := make(chan models.ExchangeMessage)
+	go b.Run(ctx, ch)
+
+	go func() {
+		for i := int64(0); i < 10; i++ {
+			ch <- bboMsg("ethusdt", i)
+		}
+		close(ch)
+	}()
+
+	var i int64
+	for msg := range s.C() {
+		bbo, _ := msg.BBO()
+		if bbo.Bid.Price.IntPart() != i {
+			t.Errorf("expected bid %d, got %v", i, bbo.Bid.Price)
+		}
+		i++
+	}
+
+	if i != 10 {
+		t.Errorf("expected 10 messages, got %d", i)
+	}
+}
+
+func TestUnsubscribeBlocked(t *testing.T) {
+	b := New()
+	stuck := b.Subscribe(Options{Name: "stuck", QueueSize: 1, Policy: PolicyBlock})
+	other := b.Subscribe(Options{Name: "other", QueueSize: 1, Policy: PolicyBlock})
+
+	go func() {
+		for i := int64(0); i < 5; i++ {
+			b.Publish(bboMsg("ethusdt", i))
+		}
+	}()
+	// Stuck subscriber is not read, so publisher blocks on it.
+	recv(t, other)
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		b.Unsubscribe(stuck)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("unsubscribe blocked by publisher")
+	}
+
+	for i := int64(1); i < 5; i++ {
+		bbo, _ := recv(t, other).BBO()
+		if bbo.Bid.Price.IntPart() != i {
+			t.Errorf("expected bid %d, got %v", i, bbo.Bid.Price)
+		}
+	}
+	b.Close()
+}
@@ -0,0 +1,212 @@
+package bus
+
+import (
+	"sync"
+	"time"
+
+	"degen/pkg/models"
+)
+
+// Stats describe subscriber's queue state and lag.
+// Lag is time between message publishing and its delivery to subscriber.
+type Stats struct {
+	Name      string         `json:"name"`
+	Policy    OverflowPolicy `json:"policy"`
+	Queued    int            `json:"queued"`
+	Published uint64         `json:"published"`
+	Delivered uint64         `json:"delivered"`
+	Dropped   uint64         `json:"dropped"`
+	Conflated uint64         `json:"conflated"`
+	LastLag   time.Duration  `json:"last_lag"`
+	MaxLag    time.Duration  `json:"max_lag"`
+}
+
+type entry struct {
+	msg         models.ExchangeMessage
+	publishedAt time.Time
+}
+
+type bboKey struct {
+	exchange, symbol string
+}
+
+// Subscription is a bounded queue of messages delivered to C().
+// Queue is a ring buffer addressed by ever increasing head and tail
+// sequence numbers.
+type Subscription struct {
+	filter Filter
+	policy OverflowPolicy
+
+	buf        []entry
+	head, tail uint64
+	latestBBO  map[bboKey]uint64
+	closed     bool
+
+	out  chan models.ExchangeMessage
+	done chan struct{}
+
+	stats Stats
+
+	mux  sync.Mutex
+	cond *sync.Cond
+}
+
+func newSubscription(opts Options) *Subscription {
+	size := opts.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	s := &Subscription{
+		filter:    opts.Filter,
+		policy:    opts.Policy,
+		buf:       make([]entry, size),
+		latestBBO: make(map[bboKey]uint64),
+		out:       make(chan models.ExchangeMessage),
+		done:      make(chan struct{}),
+		stats: Stats{
+			Name:   opts.Name,
+			Policy: opts.Policy,
+		},
+	}
+	s.cond = sync.NewCond(&s.mux)
+
+	go s.pump()
+
+	return s
+}
+
+// C returns channel to read messages from.
+// It is closed when subscription is closed.
+func (s *Subscription) C() <-chan models.ExchangeMessage {
+	return s.out
+}
+
+func (s *Subscription) Stats() Stats {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	stats := s.stats
+	stats.Queued = int(s.tail - s.head)
+	return stats
+}
+
+func (s *Subscription) size() uint64 {
+	return uint64(len(s.buf))
+}
+
+func (s *Subscription) push(msg models.ExchangeMessage, now time.Time) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	s.stats.Published++
+
+	conflate := s.policy == PolicyConflate && msg.MsgType == models.MsgTypeBBO
+	key := bboKey{msg.Exchange, msg.Symbol}
+	if conflate {
+		if seq, ok := s.latestBBO[key]; ok && seq >= s.head {
+			// Keeping original publishedAt, so lag shows
+			// how long the slot waits for the subscriber.
+			s.buf[seq%s.size()].msg = msg
+			s.stats.Conflated++
+			return
+		}
+	}
+
+	for s.tail-s.head >= s.size() {
+		if s.policy == PolicyBlock {
+			s.cond.Wait()
+			if s.closed {
+				return
+			}
+			continue
+		}
+
+		s.popLocked()
+		s.stats.Dropped++
+	}
+
+	s.buf[s.tail%s.size()] = entry{msg: msg, publishedAt: now}
+	if conflate {
+		s.latestBBO[key] = s.tail
+	}
+	s.tail++
+	s.cond.Broadcast()
+}
+
+func (s *Subscription) popLocked() entry {
+	seq := s.head
+	e := s.buf[seq%s.size()]
+	s.buf[seq%s.size()] = entry{}
+	s.head++
+
+	if e.msg.MsgType == models.MsgTypeBBO {
+		key := bboKey{e.msg.Exchange, e.msg.Symbol}
+		if latest, ok := s.latestBBO[key]; ok && latest == seq {
+			delete(s.latestBBO, key)
+		}
+	}
+
+	return e
+}
+
+// pump delivers queued messages to the out channel.
+func (s *Subscription) pump() {
+	defer close(s.out)
+
+	for {
+		s.mux.Lock()
+		for s.head == s.tail && !s.closed {
+			s.cond.Wait()
+		}
+		if s.head == s.tail {
+			// Closed and drained.
+			s.mux.Unlock()
+			return
+		}
+		e := s.popLocked()
+		s.cond.Broadcast()
+		s.mux.Unlock()
+
+		e.msg.DispatchedAt = time.Now().UTC()
+		select {
+		case s.out <- e.msg:
+		case <-s.done:
+			return
+		}
+
+		lag := time.Since(e.publishedAt)
+		s.mux.Lock()
+		s.stats.Delivered++
+		s.stats.LastLag = lag
+		if lag > s.stats.MaxLag {
+			s.stats.MaxLag = lag
+		}
+		s.mux.Unlock()
+	}
+}
+
+// close stops accepting new messages and wakes blocked publishers.
+// If drain is true, already queued messages are delivered before C()
+// is closed, otherwise they are dropped.
+func (s *Subscription) close(drain bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+	if !drain {
+		for s.head != s.tail {
+			s.popLocked()
+		}
+		close(s.done)
+	}
+	s.cond.Broadcast()
+}
@@ -0,0 +1,636 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"degen/pkg/accounts"
+	"degen/pkg/execution"
+	"degen/pkg/logging"
+	"degen/pkg/oms"
+	"degen/pkg/paper"
+	"degen/pkg/strategies"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	StrategyMonkey       = "monkey"
+	StrategyMarketMaker  = "market_maker"
+	StrategyGrid         = "grid"
+	StrategyArbitrage    = "arbitrage"
+	StrategyFundingCarry = "funding_carry"
+	StrategyPairs        = "pairs"
+
+	ExchangeBinance = "binance"
+)
+
+var (
+	supportedExchanges  = map[string]bool{ExchangeBinance: true}
+	supportedStrategies = map[string]bool{
+		StrategyMonkey:       true,
+		StrategyMarketMaker:  true,
+		StrategyGrid:         true,
+		StrategyArbitrage:    true,
+		StrategyFundingCarry: true,
+		StrategyPairs:        true,
+	}
+)
+
+type Config struct {
+	// Path is a file config was loaded from, empty for default config.
+	Path string `json:"-"`
+
+	// Journal is a path to the state journal file.
+	Journal    string       `json:"journal"`
+	Exchanges  []Exchange   `json:"exchanges"`
+	Strategies []Strategy   `json:"strategies"`
+	Risk       oms.Limits   `json:"risk"`
+	Paper      paper.Config `json:"paper"`
+	Dump       Dump         `json:"dump"`
+	Logging    Logging      `json:"logging"`
+	Admin      Admin        `json:"admin"`
+	Metrics    Metrics      `json:"metrics"`
+}
+
+// Exchange is an exchange account to trade on. An exchange can have
+// several accounts, each one with its own API keys; market data is
+// received by the first one.
+type Exchange struct {
+	Name string `json:"name"`
+	// Type is a connector of the exchange, the name by default. The same
+	// connector can be configured under several names as separate venues.
+	Type    string `json:"type,omitempty"`
+	Account string `json:"account"`
+	// Parent is an account of the same exchange this one is a sub-account of.
+	Parent      string      `json:"parent,omitempty"`
+	APIURL      string      `json:"api_url"`
+	WSURL       string      `json:"ws_url"`
+	WSAPIURL    string      `json:"ws_api_url"`
+	Credentials Credentials `json:"credentials"`
+}
+
+// Credentials define where API keys are read from, so secrets
+// are not stored in the config file. Either SecretEnv or KeyFile
+// (PEM encoded Ed25519 or RSA private key) is used.
+type Credentials struct {
+	KeyEnv    string `json:"key_env"`
+	SecretEnv string `json:"secret_env"`
+	KeyFile   string `json:"key_file"`
+}
+
+// Strategy is a strategy instance with its parameters.
+type Strategy struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Exchange string `json:"exchange"`
+	// Account on the exchange, the first configured one by default.
+	Account string          `json:"account,omitempty"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// Venue is an account on an exchange. Empty account is
+// the first one configured on the exchange.
+type Venue struct {
+	Exchange string
+	Account  string
+}
+
+func (v Venue) String() string {
+	if v.Account == "" {
+		return v.Exchange
+	}
+	return v.Exchange + "/" + v.Account
+}
+
+// Dump configures market data recording.
+type Dump struct {
+	Symbols []string `json:"symbols"`
+	Output  string   `json:"output"`
+}
+
+type Logging struct {
+	// Output is "stderr" (default), "stdout" or a file path.
+	Output string `json:"output"`
+	// Format is "text" (default) or "json".
+	Format string        `json:"format"`
+	Level  logging.Level `json:"level"`
+	// Components override level per component, e.g. {"binance": "debug"}.
+	Components map[string]logging.Level `json:"components"`
+}
+
+// Admin configures HTTP admin API, disabled if Listen is empty.
+type Admin struct {
+	// Listen is an address to listen on, e.g. "127.0.0.1:8080".
+	Listen string `json:"listen"`
+	// TokenEnv is an environment variable with API token.
+	TokenEnv string `json:"token_env"`
+}
+
+// Metrics configures Prometheus metrics endpoint, disabled if Listen is empty.
+type Metrics struct {
+	Listen string `json:"listen"`
+}
+
+// Token reads admin API token from environment variable.
+func (a Admin) Token() (string, error) {
+	token := os.Getenv(a.TokenEnv)
+	if token == "" {
+		return "", fmt.Errorf("admin API token is not set in %s", a.TokenEnv)
+	}
+	return token, nil
+}
+
+// Default returns config matching previously hardcoded setup:
+// Monkey trading ethusdt on Binance futures testnet.
+func Default() *Config {
+	params, _ := json.Marshal(strategies.DefaultMonkeyParams())
+	cfg := &Config{
+		Exchanges: []Exchange{{Name: ExchangeBinance, Account: "monkey"}},
+		Strategies: []Strategy{{
+			Name:     "monkey",
+			Type:     StrategyMonkey,
+			Exchange: ExchangeBinance,
+			Params:   params,
+		}},
+	}
+	cfg.setDefaults()
+	return cfg
+}
+
+// Load reads config file, applies defaults and environment
+// overrides and validates the result. Empty path means default config.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config.Load failed to read %s: %w", path, err)
+		}
+
+		cfg = &Config{}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("config.Load failed to parse %s: %w", path, err)
+		}
+		cfg.setDefaults()
+		cfg.Path = path
+	}
+
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, fmt.Errorf("config.Load: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config.Load: %w", err)
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) setDefaults() {
+	if c.Journal == "" {
+		c.Journal = "degen.journal"
+	}
+	if c.Paper.Asset == "" {
+		c.Paper.Asset = "usdt"
+	}
+	if c.Paper.Balance.IsZero() {
+		c.Paper.Balance = decimal.NewFromInt(10000)
+	}
+	if c.Dump.Output == "" {
+		c.Dump.Output = "market.jsonl"
+	}
+	if c.Admin.TokenEnv == "" {
+		c.Admin.TokenEnv = "DEGEN_ADMIN_TOKEN"
+	}
+
+	for i := range c.Exchanges {
+		ex := &c.Exchanges[i]
+		if ex.Account == "" {
+			ex.Account = "main"
+		}
+		// Keys of other than the first account are read
+		// from e.g. BINANCE_HEDGE_KEY.
+		prefix := strings.ToUpper(ex.Name)
+		if !c.Primary(*ex) {
+			prefix += "_" + strings.ToUpper(ex.Account)
+		}
+		if ex.Credentials.KeyEnv == "" {
+			ex.Credentials.KeyEnv = prefix + "_KEY"
+		}
+		if ex.Credentials.SecretEnv == "" {
+			ex.Credentials.SecretEnv = prefix + "_SECRET"
+		}
+
+		if ex.Connector() == ExchangeBinance {
+			if ex.APIURL == "" {
+				ex.APIURL = "https://testnet.binancefuture.com"
+			}
+			if ex.WSURL == "" {
+				ex.WSURL = "wss://stream.binancefuture.com"
+			}
+			if ex.WSAPIURL == "" {
+				ex.WSAPIURL = "wss://testnet.binancefuture.com/ws-fapi/v1"
+			}
+		}
+	}
+}
+
+// applyEnv overrides config values with DEGEN_* environment variables.
+// Values of the first account of an exchange are overridden with
+// DEGEN_<EXCHANGE>_<FIELD>, e.g. DEGEN_BINANCE_API_URL. For compatibility
+// <EXCHANGE>_KEY_FILE (e.g. BINANCE_KEY_FILE) sets its key file too.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	if v, ok := lookup("DEGEN_JOURNAL"); ok {
+		c.Journal = v
+	}
+	if v, ok := lookup("DEGEN_LOG_OUTPUT"); ok {
+		c.Logging.Output = v
+	}
+	if v, ok := lookup("DEGEN_LOG_FORMAT"); ok {
+		c.Logging.Format = v
+	}
+	if v, ok := lookup("DEGEN_LOG_LEVEL"); ok {
+		if err := c.Logging.Level.UnmarshalText([]byte(v)); err != nil {
+			return fmt.Errorf("DEGEN_LOG_LEVEL: %w", err)
+		}
+	}
+	if v, ok := lookup("DEGEN_ADMIN_LISTEN"); ok {
+		c.Admin.Listen = v
+	}
+	if v, ok := lookup("DEGEN_METRICS_LISTEN"); ok {
+		c.Metrics.Listen = v
+	}
+	if v, ok := lookup("DEGEN_DUMP_OUTPUT"); ok {
+		c.Dump.Output = v
+	}
+	if v, ok := lookup("DEGEN_PAPER_BALANCE"); ok {
+		balance, err := decimal.NewFromString(v)
+		if err != nil {
+			return fmt.Errorf("DEGEN_PAPER_BALANCE: %w", err)
+		}
+		c.Paper.Balance = balance
+	}
+
+	for i := range c.Exchanges {
+		if !c.Primary(c.Exchanges[i]) {
+			continue
+		}
+		ex := &c.Exchanges[i]
+		name := strings.ToUpper(ex.Name)
+		if v, ok := lookup(name + "_KEY_FILE"); ok {
+			ex.Credentials.KeyFile = v
+		}
+
+		prefix := "DEGEN_" + name + "_"
+		for suffix, field := range map[string]*string{
+			"ACCOUNT":    &ex.Account,
+			"API_URL":    &ex.APIURL,
+			"WS_URL":     &ex.WSURL,
+			"WS_API_URL": &ex.WSAPIURL,
+			"KEY_FILE":   &ex.Credentials.KeyFile,
+		} {
+			if v, ok := lookup(prefix + suffix); ok {
+				*field = v
+			}
+		}
+	}
+
+	return nil
+}
+
+// Validate checks config consistency. Credentials are not resolved,
+// because they are not needed for paper trading and backtests.
+func (c *Config) Validate() error {
+	var errs []string
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if len(c.Exchanges) == 0 {
+		fail("no exchanges configured")
+	}
+
+	exchanges := make(map[string]bool)
+	venues := make(map[Venue]bool)
+	keys := make(map[string]string)
+	for i, ex := range c.Exchanges {
+		v := Venue{ex.Name, ex.Account}
+		switch {
+		case !supportedExchanges[ex.Connector()]:
+			fail("exchanges[%d]: unsupported exchange %q", i, ex.Connector())
+		case venues[v]:
+			fail("exchanges[%d]: duplicate account %s", i, v)
+		case ex.APIURL == "" || ex.WSURL == "":
+			fail("exchanges[%d]: api_url and ws_url are required", i)
+		case ex.Parent != "" && (ex.Parent == ex.Account || !venues[Venue{ex.Name, ex.Parent}]):
+			fail("exchanges[%d]: parent account %q must be configured before %s", i, ex.Parent, v)
+		}
+		// Accounts sharing API keys would be the same account.
+		for _, key := range []string{ex.Credentials.KeyEnv, ex.Credentials.KeyFile} {
+			if key == "" {
+				continue
+			}
+			if other, ok := keys[ex.Name+" "+key]; ok {
+				fail("exchanges[%d]: %s uses API key %s of %s", i, v, key, other)
+			}
+			keys[ex.Name+" "+key] = v.String()
+		}
+		exchanges[ex.Name] = true
+		venues[v] = true
+	}
+
+	names := make(map[string]bool)
+	for i, st := range c.Strategies {
+		switch {
+		case st.Name == "":
+			fail("strategies[%d]: name is required", i)
+		case names[st.Name]:
+			fail("strategies[%d]: duplicate name %q", i, st.Name)
+		case !supportedStrategies[st.Type]:
+			fail("strategies[%d]: unsupported type %q", i, st.Type)
+		}
+		names[st.Name] = true
+
+		if supportedStrategies[st.Type] {
+			if _, err := st.Symbols(); err != nil {
+				fail("strategies[%d]: %v", i, err)
+			} else if all, err := st.Venues(); err != nil {
+				fail("strategies[%d]: %v", i, err)
+			} else {
+				for _, v := range all {
+					if !exchanges[v.Exchange] {
+						fail("strategies[%d]: unknown exchange %q", i, v.Exchange)
+					} else if _, ok := c.Account(v); !ok {
+						fail("strategies[%d]: unknown account %s", i, v)
+					}
+				}
+			}
+		}
+	}
+
+	if c.Risk.MaxOrderSize.IsNegative() || c.Risk.MaxPosition.IsNegative() || c.Risk.MaxOpenOrders < 0 {
+		fail("risk: limits must not be negative")
+	}
+
+	if f := c.Logging.Format; f != "" && f != "text" && f != "json" {
+		fail("logging: unsupported format %q", f)
+	}
+
+	if c.Paper.Balance.IsNegative() || c.Paper.TakerFee.IsNegative() || c.Paper.MakerFee.IsNegative() {
+		fail("paper: balance and fees must not be negative")
+	}
+
+	if len(errs) > 0 {
+		return errors.New("invalid config:\n\t" + strings.Join(errs, "\n\t"))
+	}
+
+	return nil
+}
+
+// Connector returns type of the exchange connector.
+func (ex Exchange) Connector() string {
+	if ex.Type == "" {
+		return ex.Name
+	}
+	return ex.Type
+}
+
+// Account returns config of the venue account.
+func (c *Config) Account(v Venue) (Exchange, bool) {
+	for _, ex := range c.Exchanges {
+		if ex.Name == v.Exchange && (v.Account == "" || ex.Account == v.Account) {
+			return ex, true
+		}
+	}
+	return Exchange{}, false
+}
+
+// Primary tells if the account is the first one of its exchange,
+// which receives market data of the exchange.
+func (c *Config) Primary(ex Exchange) bool {
+	first, ok := c.Account(Venue{Exchange: ex.Name})
+	return ok && first.Account == ex.Account
+}
+
+// Symbols returns sorted symbols traded by strategies on the exchange.
+func (c *Config) Symbols(exchange string) []string {
+	set := make(map[string]bool)
+	for _, st := range c.Strategies {
+		if !st.TradesOn(exchange) {
+			continue
+		}
+		symbols, _ := st.Symbols()
+		for _, s := range symbols {
+			set[s] = true
+		}
+	}
+
+	symbols := make([]string, 0, len(set))
+	for s := range set {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// FundingSymbols returns sorted symbols, funding rates of which
+// are used by strategies on the exchange.
+func (c *Config) FundingSymbols(exchange string) []string {
+	set := make(map[string]bool)
+	for _, st := range c.Strategies {
+		if st.Type != StrategyFundingCarry || st.Exchange != exchange {
+			continue
+		}
+		if p, err := st.FundingCarryParams(); err == nil {
+			set[p.Symbol] = true
+		}
+	}
+
+	symbols := make([]string, 0, len(set))
+	for s := range set {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// TradeSymbols returns sorted symbols, trades of which
+// are used by execution algorithms on the exchange.
+func (c *Config) TradeSymbols(exchange string) []string {
+	set := make(map[string]bool)
+	for _, st := range c.Strategies {
+		if st.Type != StrategyMonkey || st.Exchange != exchange {
+			continue
+		}
+		if p, err := st.MonkeyParams(); err == nil && p.Execution.Algo == execution.AlgoParticipation {
+			set[p.Symbol] = true
+		}
+	}
+
+	symbols := make([]string, 0, len(set))
+	for s := range set {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// MonkeyParams parses and validates monkey strategy parameters.
+func (s Strategy) MonkeyParams() (strategies.MonkeyParams, error) {
+	if s.Type != StrategyMonkey {
+		return strategies.MonkeyParams{}, fmt.Errorf("strategy %q is not a monkey", s.Name)
+	}
+	return strategies.ParseMonkeyParams(s.Params)
+}
+
+// MarketMakerParams parses and validates market maker strategy parameters.
+func (s Strategy) MarketMakerParams() (strategies.MarketMakerParams, error) {
+	if s.Type != StrategyMarketMaker {
+		return strategies.MarketMakerParams{}, fmt.Errorf("strategy %q is not a market maker", s.Name)
+	}
+	return strategies.ParseMarketMakerParams(s.Params)
+}
+
+// GridParams parses and validates grid strategy parameters.
+func (s Strategy) GridParams() (strategies.GridParams, error) {
+	if s.Type != StrategyGrid {
+		return strategies.GridParams{}, fmt.Errorf("strategy %q is not a grid", s.Name)
+	}
+	return strategies.ParseGridParams(s.Params)
+}
+
+// ArbitrageParams parses and validates arbitrage strategy parameters.
+func (s Strategy) ArbitrageParams() (strategies.ArbitrageParams, error) {
+	if s.Type != StrategyArbitrage {
+		return strategies.ArbitrageParams{}, fmt.Errorf("strategy %q is not an arbitrage", s.Name)
+	}
+	return strategies.ParseArbitrageParams(s.Params)
+}
+
+// FundingCarryParams parses and validates funding carry strategy parameters.
+func (s Strategy) FundingCarryParams() (strategies.FundingCarryParams, error) {
+	if s.Type != StrategyFundingCarry {
+		return strategies.FundingCarryParams{}, fmt.Errorf("strategy %q is not a funding carry", s.Name)
+	}
+	return strategies.ParseFundingCarryParams(s.Params)
+}
+
+// PairsParams parses and validates pairs strategy parameters.
+func (s Strategy) PairsParams() (strategies.PairsParams, error) {
+	if s.Type != StrategyPairs {
+		return strategies.PairsParams{}, fmt.Errorf("strategy %q is not a pairs", s.Name)
+	}
+	return strategies.ParsePairsParams(s.Params)
+}
+
+// Venues returns accounts the strategy trades on,
+// the strategy account goes first.
+func (s Strategy) Venues() ([]Venue, error) {
+	exchanges, err := s.Exchanges()
+	venues := []Venue{{s.Exchange, s.Account}}
+	for _, ex := range exchanges[1:] {
+		venues = append(venues, Venue{Exchange: ex})
+	}
+	return venues, err
+}
+
+// Exchanges returns exchanges the strategy trades on,
+// the strategy exchange goes first.
+func (s Strategy) Exchanges() ([]string, error) {
+	var (
+		other string
+		err   error
+	)
+	switch s.Type {
+	case StrategyArbitrage:
+		var p strategies.ArbitrageParams
+		p, err = s.ArbitrageParams()
+		other = p.OtherExchange
+	case StrategyFundingCarry:
+		var p strategies.FundingCarryParams
+		p, err = s.FundingCarryParams()
+		if err == nil && (p.SpotExchange == "" || p.SpotExchange == s.Exchange) {
+			// Spot leg is another contract on the strategy exchange.
+			if p.SpotSymbol == p.Symbol {
+				return []string{s.Exchange}, fmt.Errorf("funding carry spot symbol %s is the perpetual", p.Symbol)
+			}
+			return []string{s.Exchange}, nil
+		}
+		other = p.SpotExchange
+	default:
+		return []string{s.Exchange}, nil
+	}
+
+	if err != nil {
+		return []string{s.Exchange}, err
+	}
+	if other == s.Exchange {
+		return []string{s.Exchange}, fmt.Errorf("%s exchanges must differ, got %s twice", s.Type, other)
+	}
+	return []string{s.Exchange, other}, nil
+}
+
+// TradesOn tells if the strategy trades on the exchange.
+func (s Strategy) TradesOn(exchange string) bool {
+	exchanges, _ := s.Exchanges()
+	for _, ex := range exchanges {
+		if ex == exchange {
+			return true
+		}
+	}
+	return false
+}
+
+// Symbols parses strategy parameters and returns symbols it trades.
+func (s Strategy) Symbols() ([]string, error) {
+	switch s.Type {
+	case StrategyMonkey:
+		p, err := s.MonkeyParams()
+		return []string{p.Symbol}, err
+	case StrategyMarketMaker:
+		p, err := s.MarketMakerParams()
+		return []string{p.Symbol}, err
+	case StrategyGrid:
+		p, err := s.GridParams()
+		return []string{p.Symbol}, err
+	case StrategyArbitrage:
+		p, err := s.ArbitrageParams()
+		return []string{p.Symbol}, err
+	case StrategyFundingCarry:
+		p, err := s.FundingCarryParams()
+		if p.SpotSymbol == p.Symbol {
+			return []string{p.Symbol}, err
+		}
+		return []string{p.Symbol, p.SpotSymbol}, err
+	case StrategyPairs:
+		p, err := s.PairsParams()
+		return []string{p.Symbol, p.HedgeSymbol}, err
+	}
+	return nil, fmt.Errorf("unsupported strategy type %q", s.Type)
+}
+
+// Resolve reads API keys from environment variables or key file.
+func (c Credentials) Resolve() (accounts.Credentials, error) {
+	creds := accounts.Credentials{
+		Key:     os.Getenv(c.KeyEnv),
+		Secret:  os.Getenv(c.SecretEnv),
+		KeyFile: c.KeyFile,
+	}
+
+	if creds.Key == "" {
+		return creds, fmt.Errorf("API key is not set in %s", c.KeyEnv)
+	}
+	if creds.Secret == "" && creds.KeyFile == "" {
+		return creds, fmt.Errorf("neither %s nor key file is set", c.SecretEnv)
+	}
+
+	return creds, nil
+}
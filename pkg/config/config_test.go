@@ -0,0 +1,198 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+		"exchanges": [{"name": "binance", "account": "acc"}],
+		"strategies": [{
+			"name": "m1",
+			"type": "monkey",
+			"exchange": "binance",
+			"params": {"symbol": "btcusdt", "patience": 3}
+		}],
+		"risk": {"max_position": "2"}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("DEGEN_BINANCE_API_URL", "http://localhost")
+	t.Setenv("DEGEN_JOURNAL", "/tmp/test.journal")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Journal != "/tmp/test.journal" || cfg.Exchanges[0].APIURL != "http://localhost" {
+		t.Errorf("env overrides are not applied: %+v", cfg)
+	}
+	if cfg.Exchanges[0].WSURL == "" || cfg.Exchanges[0].Credentials.KeyEnv != "BINANCE_KEY" {
+		t.Errorf("defaults are not applied: %+v", cfg.Exchanges[0])
+	}
+	if !cfg.Risk.MaxPosition.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("unexpected risk limits %+v", cfg.Risk)
+	}
+
+	p, err := cfg.Strategies[0].MonkeyParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unset params keep defaults.
+	if p.Patience != 3 || p.Symbol != "btcusdt" || !p.OrderSize.Equal(decimal.NewFromFloat(0.25)) {
+		t.Errorf("unexpected params %+v", p)
+	}
+
+	if symbols := cfg.Symbols("binance"); len(symbols) != 1 || symbols[0] != "btcusdt" {
+		t.Errorf("unexpected symbols %v", symbols)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Exchanges = append(cfg.Exchanges, Exchange{Name: "ftx"})
+	cfg.Strategies = append(cfg.Strategies,
+		Strategy{Name: "monkey", Type: "monkey", Exchange: "binance"},
+		Strategy{Name: "bad", Type: "monkey", Exchange: "binance", Params: json.RawMessage(`{"order_size": "-1"}`)},
+		Strategy{Name: "arb", Type: "arbitrage", Exchange: "binance", Params: json.RawMessage(`{"other_exchange": "kraken"}`)},
+	)
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+
+	for _, msg := range []string{`unsupported exchange "ftx"`, `duplicate name "monkey"`, "order size -1", `unknown exchange "kraken"`} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected %q in %v", msg, err)
+		}
+	}
+
+	if err := Default().Validate(); err != nil {
+		t.Errorf("default config is invalid: %v", err)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	cfg := Default()
+	cfg.Exchanges = append(cfg.Exchanges, Exchange{Name: "binance", Account: "hedge", Parent: "monkey"})
+	cfg.Strategies = append(cfg.Strategies, Strategy{Name: "hedge", Type: "monkey", Exchange: "binance", Account: "hedge"})
+	cfg.setDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if key := cfg.Exchanges[1].Credentials.KeyEnv; key != "BINANCE_HEDGE_KEY" {
+		t.Errorf("expected sub-account key in BINANCE_HEDGE_KEY, got %s", key)
+	}
+	if ex, ok := cfg.Account(Venue{Exchange: "binance"}); !ok || ex.Account != "monkey" {
+		t.Errorf("expected the first account by default, got %+v", ex)
+	}
+	if !cfg.Primary(cfg.Exchanges[0]) || cfg.Primary(cfg.Exchanges[1]) {
+		t.Error("expected only the first account to be primary")
+	}
+	venues, err := cfg.Strategies[1].Venues()
+	if err != nil || len(venues) != 1 || venues[0].String() != "binance/hedge" {
+		t.Errorf("unexpected strategy venues %v: %v", venues, err)
+	}
+
+	cfg.Exchanges = append(cfg.Exchanges,
+		Exchange{Name: "binance", Account: "hedge", APIURL: "a", WSURL: "w"},
+		Exchange{Name: "binance", Account: "sub", Parent: "nobody", APIURL: "a", WSURL: "w"},
+		Exchange{Name: "binance", Account: "twin", APIURL: "a", WSURL: "w", Credentials: Credentials{KeyEnv: "BINANCE_KEY"}},
+	)
+	cfg.Strategies = append(cfg.Strategies, Strategy{Name: "lost", Type: "monkey", Exchange: "binance", Account: "lost"})
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, msg := range []string{
+		"duplicate account binance/hedge",
+		`parent account "nobody"`,
+		"binance/twin uses API key BINANCE_KEY of binance/monkey",
+		"unknown account binance/lost",
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected %q in %v", msg, err)
+		}
+	}
+}
+
+func TestNamedVenues(t *testing.T) {
+	cfg := Default()
+	cfg.Exchanges = append(cfg.Exchanges, Exchange{Name: "binance_b", Type: "binance"})
+	cfg.Strategies = []Strategy{{
+		Name:     "arb",
+		Type:     "arbitrage",
+		Exchange: "binance",
+		Params:   json.RawMessage(`{"symbol": "ethusdt", "other_exchange": "binance_b"}`),
+	}}
+	cfg.setDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := cfg.Exchanges[1]
+	if b.APIURL == "" || b.Credentials.KeyEnv != "BINANCE_B_KEY" {
+		t.Errorf("defaults of the connector are not applied: %+v", b)
+	}
+	if !cfg.Strategies[0].TradesOn("binance_b") {
+		t.Error("expected arbitrage to trade on binance_b")
+	}
+}
+
+func TestFundingCarryVenues(t *testing.T) {
+	st := Strategy{
+		Name:     "carry",
+		Type:     "funding_carry",
+		Exchange: "binance",
+		Params:   json.RawMessage(`{"symbol": "ethusdt", "spot_symbol": "ethusdt_250328"}`),
+	}
+	if exchanges, err := st.Exchanges(); err != nil || len(exchanges) != 1 {
+		t.Errorf("expected both legs on binance, got %v: %v", exchanges, err)
+	}
+	if symbols, _ := st.Symbols(); len(symbols) != 2 {
+		t.Errorf("expected both legs symbols, got %v", symbols)
+	}
+
+	st.Params = json.RawMessage(`{"symbol": "ethusdt"}`)
+	if _, err := st.Exchanges(); err == nil {
+		t.Error("expected error for spot leg on the perpetual")
+	}
+
+	st.Params = json.RawMessage(`{"symbol": "ethusdt", "spot_exchange": "binance_b"}`)
+	if exchanges, err := st.Exchanges(); err != nil || len(exchanges) != 2 || exchanges[1] != "binance_b" {
+		t.Errorf("expected spot leg on binance_b, got %v: %v", exchanges, err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type params struct {
+		A int             `json:"a"`
+		B decimal.Decimal `json:"b"`
+		C string          `json:"c,omitempty"`
+	}
+
+	changes, err := Diff(
+		params{A: 1, B: decimal.NewFromFloat(0.5)},
+		params{A: 1, B: decimal.NewFromFloat(0.25), C: "x"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{`b: "0.5" -> "0.25"`, `c: <none> -> "x"`}
+	if strings.Join(changes, ";") != strings.Join(expected, ";") {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+}
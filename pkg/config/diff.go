@@ -0,0 +1,58 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Diff returns human readable changes of top level fields of two
+// values marshalled to JSON objects, e.g. `patience: 1 -> 2`.
+func Diff(old, new interface{}) ([]string, error) {
+	a, err := toMap(old)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toMap(new)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+
+	var changes []string
+	for k := range keys {
+		if string(a[k]) != string(b[k]) {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", k, valueOrNone(a[k]), valueOrNone(b[k])))
+		}
+	}
+	sort.Strings(changes)
+
+	return changes, nil
+}
+
+func toMap(v interface{}) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func valueOrNone(v json.RawMessage) string {
+	if v == nil {
+		return "<none>"
+	}
+	return string(v)
+}
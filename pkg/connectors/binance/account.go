@@ -0,0 +1,172 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+type openOrderResp struct {
+	ClientOrderID   string `json:"clientOrderId"`
+	Symbol          string `json:"symbol"`
+	Side            string `json:"side"`
+	Type            string `json:"type"`
+	Quantity        string `json:"origQty"`
+	Price           string `json:"price"`
+	Status          string `json:"status"`
+	ExchangeOrderID int64  `json:"orderId"`
+	FilledQty       string `json:"executedQty"`
+	FilledPrice     string `json:"avgPrice"`
+	TimeInForce     string `json:"timeInForce"`
+	Time            int64  `json:"time"`
+	UpdateTime      int64  `json:"updateTime"`
+}
+
+type accountAssetResp struct {
+	Asset         string `json:"asset"`
+	WalletBalance string `json:"walletBalance"`
+	UpdateTime    int64  `json:"updateTime"`
+}
+
+type accountPositionResp struct {
+	Symbol      string `json:"symbol"`
+	PositionAmt string `json:"positionAmt"`
+	EntryPrice  string `json:"entryPrice"`
+	UpdateTime  int64  `json:"updateTime"`
+}
+
+type accountResp struct {
+	Assets    []accountAssetResp    `json:"assets"`
+	Positions []accountPositionResp `json:"positions"`
+}
+
+// GetOpenOrders returns all open orders of the account.
+func (api *API) GetOpenOrders(ctx context.Context) ([]models.Order, error) {
+	var respData []openOrderResp
+	if err := api.signedGet(ctx, "binance.GetOpenOrders", "/fapi/v1/openOrders", &respData); err != nil {
+		return nil, err
+	}
+
+	orders := make([]models.Order, 0, len(respData))
+	for _, r := range respData {
+		order := models.Order{
+			Exchange:      api.name,
+			ClientOrderID: r.ClientOrderID,
+			CreatedAt:     timestampToTime(r.Time),
+			Symbol:        symbolFromExchange(r.Symbol),
+			Side:          models.OrderSide(strings.ToLower(r.Side)),
+			Type:          models.OrderType(strings.ToLower(r.Type)),
+			TimeInForce:   models.TimeInForce(r.TimeInForce),
+		}
+
+		var err error
+		if order.Size, err = decimal.NewFromString(r.Quantity); err != nil {
+			return nil, fmt.Errorf("binance.GetOpenOrders failed to parse origQty(%q): %w", r.Quantity, err)
+		}
+		if order.Price, err = decimal.NewFromString(r.Price); err != nil {
+			return nil, fmt.Errorf("binance.GetOpenOrders failed to parse price(%q): %w", r.Price, err)
+		}
+		state := placeOrderResp{
+			Status:          r.Status,
+			ExchangeOrderID: r.ExchangeOrderID,
+			FilledQty:       r.FilledQty,
+			FilledPrice:     r.FilledPrice,
+			UpdateTime:      r.UpdateTime,
+		}
+		if err := state.updateOrder(&order); err != nil {
+			return nil, fmt.Errorf("binance.GetOpenOrders %w", err)
+		}
+
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// GetAccountState returns current asset balances and non-zero positions.
+func (api *API) GetAccountState(ctx context.Context) ([]models.BalanceUpdate, []models.PositionUpdate, error) {
+	var respData accountResp
+	if err := api.signedGet(ctx, "binance.GetAccountState", "/fapi/v2/account", &respData); err != nil {
+		return nil, nil, err
+	}
+
+	balances := make([]models.BalanceUpdate, 0, len(respData.Assets))
+	for _, a := range respData.Assets {
+		balance, err := decimal.NewFromString(a.WalletBalance)
+		if err != nil {
+			return nil, nil, fmt.Errorf("binance.GetAccountState failed to parse walletBalance(%q): %w", a.WalletBalance, err)
+		}
+		balances = append(balances, models.BalanceUpdate{
+			Asset:   strings.ToLower(a.Asset),
+			Balance: balance,
+			Reason:  models.BalanceUpdateReasonOther,
+		})
+	}
+
+	var positions []models.PositionUpdate
+	for _, p := range respData.Positions {
+		amount, err := decimal.NewFromString(p.PositionAmt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("binance.GetAccountState failed to parse positionAmt(%q): %w", p.PositionAmt, err)
+		}
+		if amount.IsZero() {
+			continue
+		}
+		entry, err := decimal.NewFromString(p.EntryPrice)
+		if err != nil {
+			return nil, nil, fmt.Errorf("binance.GetAccountState failed to parse entryPrice(%q): %w", p.EntryPrice, err)
+		}
+		positions = append(positions, models.PositionUpdate{
+			Symbol:     symbolFromExchange(p.Symbol),
+			Amount:     amount,
+			EntryPrice: entry,
+		})
+	}
+
+	return balances, positions, nil
+}
+
+// signedGet performs signed GET request without parameters
+// and unmarshals response into dst.
+func (api *API) signedGet(ctx context.Context, name, path string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", api.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("%s failed to create request: %w", name, err)
+	}
+
+	req.URL.RawQuery, err = api.signRequest("", "", time.Now())
+	if err != nil {
+		return fmt.Errorf("%s failed to sign request: %w", name, err)
+	}
+	req.Header.Add("X-MBX-APIKEY", api.key)
+
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s failed perform request: %w", name, err)
+	}
+
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s failed to read response: %w", name, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %w", name, newAPIError(resp.StatusCode, b))
+	}
+
+	if err := json.Unmarshal(b, dst); err != nil {
+		return fmt.Errorf("%s failed to unmarshal response: %w", name, err)
+	}
+
+	return nil
+}
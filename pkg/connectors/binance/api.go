@@ -11,17 +11,26 @@ import (
 )
 
 type API struct {
+	// name is exchange name orders are tagged with.
+	name    string
 	key     string
-	secret  string
+	signer  Signer
 	baseURL string
 
 	client http.Client
 }
 
 func NewAPI(key, secret, baseURL string) *API {
+	return NewAPIWithSigner(key, NewHMACSigner(secret), baseURL)
+}
+
+// NewAPIWithSigner creates API client using given signer
+// (HMAC, Ed25519 or RSA) for signed requests.
+func NewAPIWithSigner(key string, signer Signer, baseURL string) *API {
 	return &API{
+		name:    Name,
 		key:     key,
-		secret:  secret,
+		signer:  signer,
 		baseURL: baseURL,
 		client: http.Client{
 			Timeout: time.Second * 5,
@@ -29,8 +38,8 @@ func NewAPI(key, secret, baseURL string) *API {
 	}
 }
 
-func (api *API) signRequest(queryString, body string, ts time.Time) string {
-	return signRequest(api.secret, queryString, body, ts)
+func (api *API) signRequest(queryString, body string, ts time.Time) (string, error) {
+	return signRequest(api.signer, queryString, body, ts)
 }
 
 type listenKeyResp struct {
@@ -72,3 +81,40 @@ func (api *API) GetListenKey(ctx context.Context) (string, error) {
 
 	return respData.ListenKey, nil
 }
+
+// KeepAliveListenKey extends current listen key validity for 60 minutes.
+func (api *API) KeepAliveListenKey(ctx context.Context) error {
+	return api.listenKeyRequest(ctx, "PUT", "binance.KeepAliveListenKey")
+}
+
+// CloseListenKey closes user data stream.
+func (api *API) CloseListenKey(ctx context.Context) error {
+	return api.listenKeyRequest(ctx, "DELETE", "binance.CloseListenKey")
+}
+
+func (api *API) listenKeyRequest(ctx context.Context, method, name string) error {
+	req, err := http.NewRequestWithContext(ctx, method, api.baseURL+"/fapi/v1/listenKey", nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Add("X-MBX-APIKEY", api.key)
+
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s failed perform request: %w", name, err)
+	}
+
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s failed to read response: %w", name, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %w", name, newAPIError(resp.StatusCode, b))
+	}
+
+	return nil
+}
@@ -1,24 +1,175 @@
 package binance
 
 import (
+	"crypto"
+	"crypto/ed25519"
 	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// Signer calculates request signature with one of API key types supported
+// by Binance: https://www.binance.com/en/support/faq/api-key-types
+type Signer interface {
+	Sign(payload []byte) (string, error)
+}
+
+// HMACSigner signs requests with HMAC-SHA256 secret key.
+type HMACSigner struct {
+	secret []byte
+}
+
+func NewHMACSigner(secret string) *HMACSigner {
+	return &HMACSigner{secret: []byte(secret)}
+}
+
+func (s *HMACSigner) Sign(payload []byte) (string, error) {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write(payload)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Ed25519Signer signs requests with Ed25519 private key.
+// Only this key type can be used for WebSocket API session logon.
+type Ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+func NewEd25519Signer(key ed25519.PrivateKey) *Ed25519Signer {
+	return &Ed25519Signer{key: key}
+}
+
+func (s *Ed25519Signer) Sign(payload []byte) (string, error) {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload)), nil
+}
+
+// RSASigner signs requests with RSA private key (PKCS#1 v1.5 SHA-256).
+type RSASigner struct {
+	key *rsa.PrivateKey
+}
+
+func NewRSASigner(key *rsa.PrivateKey) *RSASigner {
+	return &RSASigner{key: key}
+}
+
+func (s *RSASigner) Sign(payload []byte) (string, error) {
+	hash := sha256.Sum256(payload)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("binance.RSASigner failed to sign: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// ParsePEMSigner creates Ed25519 or RSA signer depending on the type
+// of PEM encoded private key.
+func ParsePEMSigner(data []byte) (Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("binance.ParsePEMSigner: no PEM data found")
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("binance.ParsePEMSigner failed to parse RSA key: %w", err)
+		}
+		return NewRSASigner(key), nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("binance.ParsePEMSigner failed to parse key: %w", err)
+	}
+
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return NewEd25519Signer(k), nil
+	case *rsa.PrivateKey:
+		return NewRSASigner(k), nil
+	default:
+		return nil, fmt.Errorf("binance.ParsePEMSigner: unsupported key type %T", key)
+	}
+}
+
+// LoadPEMSigner reads private key from PEM file.
+func LoadPEMSigner(path string) (Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("binance.LoadPEMSigner failed to read key file: %w", err)
+	}
+
+	return ParsePEMSigner(data)
+}
+
 // signRequests implements this stuff:
 // https://binance-docs.github.io/apidocs/spot/en/#signed-trade-user_data-and-margin-endpoint-security
-func signRequest(secret, queryString, body string, ts time.Time) string {
+func signRequest(signer Signer, queryString, body string, ts time.Time) (string, error) {
 	if len(queryString) > 0 {
 		queryString = queryString + "&timestamp=" + strconv.FormatInt(ts.UnixMilli(), 10)
 	} else {
 		queryString = "timestamp=" + strconv.FormatInt(ts.UnixMilli(), 10)
 	}
-	h := hmac.New(sha256.New, []byte(secret))
-	h.Write([]byte(queryString))
-	h.Write([]byte(body))
 
-	return queryString + "&signature=" + hex.EncodeToString(h.Sum(nil))
+	sig, err := signer.Sign([]byte(queryString + body))
+	if err != nil {
+		return "", err
+	}
+
+	return queryString + "&signature=" + url.QueryEscape(sig), nil
+}
+
+// signParams implements WebSocket API request signing:
+// https://binance-docs.github.io/apidocs/futures/en/#signed-endpoint-security-2
+// apiKey and timestamp are added to params and signature is calculated
+// over all parameters sorted by name.
+func signParams(key string, signer Signer, params map[string]string, ts time.Time) error {
+	params["apiKey"] = key
+	params["timestamp"] = strconv.FormatInt(ts.UnixMilli(), 10)
+	delete(params, "signature")
+
+	sig, err := signer.Sign([]byte(paramsPayload(params)))
+	if err != nil {
+		return err
+	}
+
+	params["signature"] = sig
+	return nil
+}
+
+// paramsPayload returns params as key=value pairs sorted by key
+// and joined by "&".
+func paramsPayload(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(params[k])
+	}
+
+	return sb.String()
 }
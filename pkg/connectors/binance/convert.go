@@ -34,3 +34,14 @@ func orderStatusFromExchange(status string) models.OrderStatus {
 func typeToEx(tp models.OrderType) string {
 	return strings.ToUpper(string(tp))
 }
+
+func balanceReasonFromExchange(reason string) models.BalanceUpdateReason {
+	switch reason {
+	case "ORDER":
+		return models.BalanceUpdateReasonOrder
+	case "FUNDING_FEE":
+		return models.BalanceUpdateReasonFunding
+	default:
+		return models.BalanceUpdateReasonOther
+	}
+}
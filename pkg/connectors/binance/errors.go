@@ -0,0 +1,34 @@
+package binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// APIError is an error response of Binance REST or WebSocket API:
+// https://binance-docs.github.io/apidocs/futures/en/#error-codes
+type APIError struct {
+	Status int    `json:"-"`
+	Code   int    `json:"code"`
+	Msg    string `json:"msg"`
+}
+
+// newAPIError parses error response body, keeping the whole body
+// as a message if it is not a JSON error.
+func newAPIError(status int, body []byte) *APIError {
+	e := &APIError{Status: status}
+	if err := json.Unmarshal(body, e); err != nil || e.Msg == "" {
+		e.Msg = string(body)
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("returned code %d: %d %s", e.Status, e.Code, e.Msg)
+}
+
+// ErrorCode implements connectors.CodedError.
+func (e *APIError) ErrorCode() string {
+	return strconv.Itoa(e.Code)
+}
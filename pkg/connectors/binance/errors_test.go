@@ -0,0 +1,23 @@
+package binance
+
+import (
+	"fmt"
+	"testing"
+
+	"degen/pkg/connectors"
+)
+
+func TestAPIError(t *testing.T) {
+	err := fmt.Errorf("binance.PlaceOrder %w",
+		newAPIError(400, []byte(`{"code":-2019,"msg":"Margin is insufficient."}`)))
+	if code := connectors.ErrorCode(err); code != "-2019" {
+		t.Errorf("expected code -2019, got %q", code)
+	}
+	if err.Error() != "binance.PlaceOrder returned code 400: -2019 Margin is insufficient." {
+		t.Errorf("unexpected error %q", err)
+	}
+
+	if e := newAPIError(502, []byte("Bad Gateway")); e.Code != 0 || e.Msg != "Bad Gateway" {
+		t.Errorf("unexpected error %+v", e)
+	}
+}
@@ -5,18 +5,31 @@ import (
 	"sync"
 
 	"degen/pkg/connectors"
+	"degen/pkg/logging"
 )
 
 const Name = "binance"
 
+var logger = logging.New(Name)
+
 type Binance struct {
+	// ws is used for market data streams and
+	// userWS for user data stream (orders, balances, positions).
 	ws                   *connectors.WS
+	userWS               *connectors.WS
+	wsBaseURL            string
 	API                  *API
+	Orders               connectors.OrderAPI
 	subscribedStreams    []string
 	subscriptionRequests map[uint64][]string
 
-	listenKey   string
-	reconnectCh chan any
+	// name tags messages, Name by default.
+	name            string
+	accountID       string
+	listenKey       string
+	renewing        uint32
+	reconnectCh     chan any
+	userReconnectCh chan any
 
 	mux sync.RWMutex
 }
@@ -25,27 +38,53 @@ func NewBinance(
 	ctx context.Context,
 	key, secret, apiBaseURL, wsBaseURL string,
 ) *Binance {
+	return NewBinanceWithSigner(ctx, key, NewHMACSigner(secret), apiBaseURL, wsBaseURL)
+}
+
+// NewBinanceWithSigner creates connector signing requests with given
+// signer, e.g. Ed25519 or RSA key loaded with LoadPEMSigner.
+func NewBinanceWithSigner(
+	ctx context.Context,
+	key string,
+	signer Signer,
+	apiBaseURL, wsBaseURL string,
+) *Binance {
+	api := NewAPIWithSigner(key, signer, apiBaseURL)
 	b := &Binance{
-		API:                  NewAPI(key, secret, apiBaseURL),
-		ws:                   &connectors.WS{},
+		API:                  api,
+		Orders:               api,
+		ws:                   &connectors.WS{Name: "binance_market"},
+		userWS:               &connectors.WS{Name: "binance_user"},
+		wsBaseURL:            wsBaseURL,
+		name:                 Name,
 		reconnectCh:          make(chan any),
+		userReconnectCh:      make(chan any),
 		subscriptionRequests: make(map[uint64][]string),
 	}
 
 	if key != "" {
 		lkOnce := sync.Once{}
 		lkReady := make(chan any)
-		go b.refreshListenKeyLoop(ctx, &lkOnce, lkReady)
+		go b.listenKeyLoop(ctx, &lkOnce, lkReady)
 		select {
 		case <-lkReady:
 		case <-ctx.Done():
 			return nil
 		}
+
+		userOnce := sync.Once{}
+		userReady := make(chan any)
+		go b.wsReconnectLoop(ctx, b.userWS, b.userEndpoint, nil, b.userReconnectCh, &userOnce, userReady)
+		select {
+		case <-userReady:
+		case <-ctx.Done():
+			return nil
+		}
 	}
 
 	wsOnce := sync.Once{}
 	wsReady := make(chan any)
-	go b.wsReconnectLoop(ctx, wsBaseURL, &wsOnce, wsReady)
+	go b.wsReconnectLoop(ctx, b.ws, b.marketEndpoint, b.resubscribe, b.reconnectCh, &wsOnce, wsReady)
 	select {
 	case <-wsReady:
 	case <-ctx.Done():
@@ -54,3 +93,45 @@ func NewBinance(
 
 	return b
 }
+
+// UseWSAPI switches order management to WebSocket API
+// with fallback to REST API when socket is down.
+func (b *Binance) UseWSAPI(ctx context.Context, wsAPIURL string) *WSAPI {
+	w := NewWSAPI(ctx, b.API, wsAPIURL)
+	b.Orders = w
+	return w
+}
+
+// SetAccountID binds connector to an account, so user data messages
+// are routed to it. Must be called before Listen.
+func (b *Binance) SetAccountID(id string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.accountID = id
+}
+
+// SetName sets exchange name messages and orders are tagged with,
+// so several Binance venues can be told apart. Must be called before Listen.
+func (b *Binance) SetName(name string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.name = name
+	b.API.name = name
+}
+
+// Exchange returns exchange name messages are tagged with.
+func (b *Binance) Exchange() string {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	return b.name
+}
+
+func (b *Binance) AccountID() string {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	return b.accountID
+}
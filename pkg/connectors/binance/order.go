@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"degen/pkg/models"
 
@@ -87,6 +88,44 @@ type placeOrderResp struct {
 	UpdateTime      int64  `json:"updateTime"`
 }
 
+// updateOrder fills order with exchange provided state.
+func (r *placeOrderResp) updateOrder(order *models.Order) error {
+	var err error
+	if r.ExchangeOrderID != 0 {
+		order.ExchangeOrderID = strconv.FormatInt(r.ExchangeOrderID, 10)
+	}
+	order.Status = orderStatusFromExchange(r.Status)
+	order.UpdatedAt = timestampToTime(r.UpdateTime)
+
+	if r.FilledQty != "" {
+		order.FilledSize, err = decimal.NewFromString(r.FilledQty)
+		if err != nil {
+			return fmt.Errorf("failed to parse executedQty(%q): %w", r.FilledQty, err)
+		}
+
+		order.AveragePrice, err = decimal.NewFromString(r.FilledPrice)
+		if err != nil {
+			return fmt.Errorf("failed to parse avgPrice(%q): %w", r.FilledPrice, err)
+		}
+	}
+
+	return nil
+}
+
+// orderIDValues returns parameters identifying existing order.
+// Exchange order ID is preferred over client order ID.
+func orderIDValues(order models.Order) url.Values {
+	values := url.Values{}
+	values.Add("symbol", symbolToExchange(order.Symbol))
+	if order.ExchangeOrderID != "" {
+		values.Add("orderId", order.ExchangeOrderID)
+	} else {
+		values.Add("origClientOrderId", order.ClientOrderID)
+	}
+
+	return values
+}
+
 func (api *API) PlaceOrder(ctx context.Context, order models.Order) (*models.Order, error) {
 	reqOrder, _ := newPlaceOrderReq(order)
 	query := reqOrder.Values().Encode()
@@ -101,7 +140,10 @@ func (api *API) PlaceOrder(ctx context.Context, order models.Order) (*models.Ord
 		return nil, fmt.Errorf("binance.PlaceOrder failed to create request: %w", err)
 	}
 
-	req.URL.RawQuery = api.signRequest(query, "", order.CreatedAt)
+	req.URL.RawQuery, err = api.signRequest(query, "", order.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("binance.PlaceOrder failed to sign request: %w", err)
+	}
 	req.Header.Add("X-MBX-APIKEY", api.key)
 
 	resp, err := api.client.Do(req)
@@ -122,50 +164,52 @@ func (api *API) PlaceOrder(ctx context.Context, order models.Order) (*models.Ord
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("binance.PlaceOrder returned code %d: %s", resp.StatusCode, string(b))
+		return nil, fmt.Errorf("binance.PlaceOrder %w", newAPIError(resp.StatusCode, b))
 	}
 
-	order.ExchangeOrderID = strconv.FormatInt(respData.ExchangeOrderID, 10)
-	order.Status = orderStatusFromExchange(respData.Status)
-	order.UpdatedAt = timestampToTime(respData.UpdateTime)
-
-	if respData.FilledQty != "" {
-		order.FilledSize, err = decimal.NewFromString(respData.FilledQty)
-		if err != nil {
-			return nil, fmt.Errorf("binance.PlaceOrder failed to parse executedQty(%q): %w", respData.FilledQty, err)
-		}
-
-		order.AveragePrice, err = decimal.NewFromString(respData.FilledPrice)
-		if err != nil {
-			return nil, fmt.Errorf("binance.PlaceOrder failed to parse avgPrice(%q): %w", respData.FilledPrice, err)
-		}
+	if err := respData.updateOrder(&order); err != nil {
+		return nil, fmt.Errorf("binance.PlaceOrder %w", err)
 	}
 
 	return &order, nil
 }
 
 func (api *API) CancelOrder(ctx context.Context, order models.Order) (*models.Order, error) {
-	query := fmt.Sprintf("symbol=%s&orderId=%s",
-		symbolToExchange(order.Symbol),
-		order.ExchangeOrderID,
-	)
+	return api.orderRequest(ctx, "DELETE", "binance.CancelOrder", order)
+}
+
+// QueryOrder returns current order state.
+func (api *API) QueryOrder(ctx context.Context, order models.Order) (*models.Order, error) {
+	return api.orderRequest(ctx, "GET", "binance.QueryOrder", order)
+}
+
+// orderRequest performs a request to an existing order endpoint.
+func (api *API) orderRequest(
+	ctx context.Context,
+	method, name string,
+	order models.Order,
+) (*models.Order, error) {
+	query := orderIDValues(order).Encode()
 	path := "/fapi/v1/order"
 	req, err := http.NewRequestWithContext(
 		ctx,
-		"DELETE",
+		method,
 		api.baseURL+path,
 		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("binance.CancelOrder failed to create request: %w", err)
+		return nil, fmt.Errorf("%s failed to create request: %w", name, err)
 	}
 
-	req.URL.RawQuery = api.signRequest(query, "", order.CreatedAt)
+	req.URL.RawQuery, err = api.signRequest(query, "", time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to sign request: %w", name, err)
+	}
 	req.Header.Add("X-MBX-APIKEY", api.key)
 
 	resp, err := api.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("binance.CancelOrder failed perform request: %w", err)
+		return nil, fmt.Errorf("%s failed perform request: %w", name, err)
 	}
 
 	defer resp.Body.Close()
@@ -173,30 +217,19 @@ func (api *API) CancelOrder(ctx context.Context, order models.Order) (*models.Or
 
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("binance.CancelOrder failed to read response: %w", err)
+		return nil, fmt.Errorf("%s failed to read response: %w", name, err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("binance.CancelOrder returned code %d: %s", resp.StatusCode, string(b))
+		return nil, fmt.Errorf("%s %w", name, newAPIError(resp.StatusCode, b))
 	}
 
 	if err := json.Unmarshal(b, &respData); err != nil {
-		return nil, fmt.Errorf("binance.CancelOrder failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("%s failed to unmarshal response: %w", name, err)
 	}
 
-	order.Status = orderStatusFromExchange(respData.Status)
-	order.UpdatedAt = timestampToTime(respData.UpdateTime)
-
-	if respData.FilledQty != "" {
-		order.FilledSize, err = decimal.NewFromString(respData.FilledQty)
-		if err != nil {
-			return nil, fmt.Errorf("binance.PlaceOrder failed to parse executedQty(%q): %w", respData.FilledQty, err)
-		}
-
-		order.AveragePrice, err = decimal.NewFromString(respData.FilledPrice)
-		if err != nil {
-			return nil, fmt.Errorf("binance.PlaceOrder failed to parse avgPrice(%q): %w", respData.FilledPrice, err)
-		}
+	if err := respData.updateOrder(&order); err != nil {
+		return nil, fmt.Errorf("%s %w", name, err)
 	}
 
 	return &order, nil
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,6 +11,7 @@ import (
 	"time"
 
 	"degen/pkg/connectors"
+	"degen/pkg/metrics"
 	"degen/pkg/models"
 
 	"github.com/shopspring/decimal"
@@ -19,12 +19,45 @@ import (
 
 var requestID uint64
 
+var (
+	streamMessages = metrics.NewCounter("degen_binance_messages_total",
+		"Binance stream messages received by event type.", "event")
+	decodeErrors = metrics.NewCounter("degen_binance_decode_errors_total",
+		"Binance stream messages failed to decode by event type.", "event")
+	feedLatency = metrics.NewHistogram("degen_feed_latency_seconds",
+		"Time between exchange event and its receiving.", metrics.DefaultBuckets, "exchange", "event")
+)
+
+// observeFeedLatency records latency of the event with exchange timestamp in ms.
+func observeFeedLatency(exchange, event string, exchangeMS int64, received time.Time) {
+	if exchangeMS > 0 {
+		feedLatency.Observe(received.Sub(timestampToTime(exchangeMS)).Seconds(), exchange, event)
+	}
+}
+
+// stamp sets exchange event and transaction times in ms, falling back
+// to receive time if there is no event time, and pipeline times of msg.
+func stamp(msg models.ExchangeMessage, eventMS, transactMS int64, received time.Time) models.ExchangeMessage {
+	msg.Timestamp = received
+	if eventMS > 0 {
+		msg.Timestamp = timestampToTime(eventMS)
+	}
+	if transactMS > 0 {
+		msg.TransactTime = timestampToTime(transactMS)
+	}
+	msg.ReceivedAt = received
+	msg.DecodedAt = time.Now().UTC()
+	return msg
+}
+
 type BinanceReq struct {
 	Method string `json:"method"`
 	ID     uint64 `json:"id"`
 	Params any    `json:"params,omitempty"`
 }
 
+const listenKeyKeepAlive = 30 * time.Minute
+
 func (bts *Binance) refreshListenKey(ctx context.Context) error {
 	lk, err := bts.API.GetListenKey(ctx)
 	if err != nil {
@@ -38,32 +71,66 @@ func (bts *Binance) refreshListenKey(ctx context.Context) error {
 	return nil
 }
 
-// refreshListenKeyLoop refreshes listen key every 15 minutes
-func (bts *Binance) refreshListenKeyLoop(ctx context.Context, once *sync.Once, ready chan any) {
-	ticker := time.NewTicker(time.Minute * 15)
-	defer ticker.Stop()
+// obtainListenKey retries to get listen key until success or ctx is done.
+func (bts *Binance) obtainListenKey(ctx context.Context) bool {
 	for {
-		if err := bts.refreshListenKey(ctx); err != nil {
-			log.Println(err)
-			select {
-			case <-time.After(time.Second):
-				continue
-			case <-ctx.Done():
-				return
-			}
+		err := bts.refreshListenKey(ctx)
+		if err == nil {
+			return true
 		}
 
-		once.Do(func() { close(ready) })
+		logger.Error("failed to obtain listen key", "err", err)
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// listenKeyLoop obtains listen key and keeps it alive every 30 minutes.
+// If keepalive fails (e.g. key is expired), new key is obtained
+// and user data stream is reconnected.
+func (bts *Binance) listenKeyLoop(ctx context.Context, once *sync.Once, ready chan any) {
+	if !bts.obtainListenKey(ctx) {
+		return
+	}
+
+	once.Do(func() { close(ready) })
 
+	ticker := time.NewTicker(listenKeyKeepAlive)
+	defer ticker.Stop()
+	for {
 		select {
 		case <-ticker.C:
-			continue
+			if err := bts.API.KeepAliveListenKey(ctx); err != nil {
+				logger.Warn("listen key keepalive failed", "err", err)
+				bts.renewListenKey(ctx)
+			}
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// renewListenKey obtains new listen key and reconnects user data stream.
+func (bts *Binance) renewListenKey(ctx context.Context) {
+	if !atomic.CompareAndSwapUint32(&bts.renewing, 0, 1) {
+		// Already in progress.
+		return
+	}
+	defer atomic.StoreUint32(&bts.renewing, 0)
+
+	if !bts.obtainListenKey(ctx) {
+		return
+	}
+
+	// Listen will get an error and trigger reconnect with the new key.
+	if err := bts.userWS.Close(); err != nil {
+		logger.Error("failed to close user data stream", "err", err)
+	}
+}
+
 func (bts *Binance) getListenKey() string {
 	bts.mux.RLock()
 	defer bts.mux.RUnlock()
@@ -71,25 +138,62 @@ func (bts *Binance) getListenKey() string {
 	return bts.listenKey
 }
 
+// Close closes listen key and websocket connections.
+func (bts *Binance) Close(ctx context.Context) error {
+	var err error
+	if bts.getListenKey() != "" {
+		err = bts.API.CloseListenKey(ctx)
+		//nolint:errcheck
+		bts.userWS.Close()
+	}
+
+	//nolint:errcheck
+	bts.ws.Close()
+
+	return err
+}
+
+func (bts *Binance) marketEndpoint() string {
+	return fmt.Sprintf("%s/ws", bts.wsBaseURL)
+}
+
+func (bts *Binance) userEndpoint() string {
+	return fmt.Sprintf("%s/ws/%s", bts.wsBaseURL, bts.getListenKey())
+}
+
+// resubscribe restores market data subscriptions after reconnect.
+func (bts *Binance) resubscribe(ctx context.Context) error {
+	var toSubscribe []string
+	bts.mux.RLock()
+	if len(bts.subscribedStreams) > 0 {
+		toSubscribe = bts.subscribedStreams
+	}
+	bts.mux.RUnlock()
+
+	if len(toSubscribe) > 0 {
+		return bts.subscribeStreams(ctx, toSubscribe)
+	}
+
+	return nil
+}
+
 // wsReconnectLoop connects to websocket and reconnects on error or
 // when something is received via reconnectCh channel
 func (bts *Binance) wsReconnectLoop(
 	ctx context.Context,
-	wsBaseURL string,
+	ws *connectors.WS,
+	endpoint func() string,
+	onConnect func(context.Context) error,
+	reconnectCh chan any,
 	once *sync.Once,
 	ready chan any,
 ) {
 	for {
-		endpoint := fmt.Sprintf("%s/ws", wsBaseURL)
-		lk := bts.getListenKey()
-		if lk != "" {
-			endpoint = fmt.Sprintf("%s/%s", endpoint, lk)
-		}
-		if err := bts.ws.Connect(
+		if err := ws.Connect(
 			ctx,
-			endpoint,
+			endpoint(),
 		); err != nil {
-			log.Printf("binance websocket connect error: %v", err)
+			logger.Error("websocket connect failed", "ws", ws.Name, "err", err)
 			select {
 			case <-ctx.Done():
 				return
@@ -98,16 +202,9 @@ func (bts *Binance) wsReconnectLoop(
 			}
 		}
 
-		var toSubscribe []string
-		bts.mux.RLock()
-		if len(bts.subscribedStreams) > 0 {
-			toSubscribe = bts.subscribedStreams
-		}
-		bts.mux.RUnlock()
-
-		if len(toSubscribe) > 0 {
-			if err := bts.subscribeStreams(ctx, toSubscribe); err != nil {
-				log.Printf("binance websocket subscribe error: %v", err)
+		if onConnect != nil {
+			if err := onConnect(ctx); err != nil {
+				logger.Error("websocket subscribe failed", "ws", ws.Name, "err", err)
 				select {
 				case <-ctx.Done():
 					return
@@ -122,7 +219,7 @@ func (bts *Binance) wsReconnectLoop(
 		select {
 		case <-ctx.Done():
 			return
-		case <-bts.reconnectCh:
+		case <-reconnectCh:
 			continue
 		}
 	}
@@ -166,6 +263,17 @@ func (bts *Binance) SubscribeBookAggTrades(ctx context.Context, symbols []string
 	return bts.subscribeStreams(ctx, streams)
 }
 
+// SubscribeMarkPrices subscribes to mark prices and funding rates
+// of perpetual contracts updated every second.
+func (bts *Binance) SubscribeMarkPrices(ctx context.Context, symbols []string) error {
+	streams := make([]string, len(symbols))
+	for i, s := range symbols {
+		streams[i] = strings.ToLower(s) + "@markPrice@1s"
+	}
+
+	return bts.subscribeStreams(ctx, streams)
+}
+
 func (bts *Binance) subscribeStreams(ctx context.Context, streams []string) error {
 	id, err := SendWSMsg(ctx, bts.ws, "SUBSCRIBE", streams)
 	if err == nil {
@@ -191,6 +299,7 @@ type subscribeResponse struct {
 //easyjson:json
 type bookTicker struct {
 	Event     string          `json:"e"`
+	EventTime int64           `json:"E"`
 	Symbol    string          `json:"s"`
 	BidPrice  decimal.Decimal `json:"b"`
 	BidSize   decimal.Decimal `json:"B"`
@@ -201,8 +310,10 @@ type bookTicker struct {
 
 //easyjson:json
 type orderUpdate struct {
-	Event string `json:"e"`
-	Order struct {
+	Event        string `json:"e"`
+	EventTime    int64  `json:"E"`
+	TransactTime int64  `json:"T"`
+	Order        struct {
 		Symbol          string `json:"s"`
 		ClientOrderID   string `json:"c"`
 		ExchangeOrderID int64  `json:"i"`
@@ -212,18 +323,30 @@ type orderUpdate struct {
 		FilledSize      string `json:"z"`
 		AveragePrice    string `json:"ap"`
 		UpdatedAtMS     int64  `json:"T"`
+		ExecutionType   string `json:"x"`
+
+		// Last fill details, set when ExecutionType is TRADE.
+		LastFilledSize  decimal.Decimal `json:"l"`
+		LastFilledPrice decimal.Decimal `json:"L"`
+		Commission      decimal.Decimal `json:"n"`
+		CommissionAsset string          `json:"N"`
+		RealizedProfit  decimal.Decimal `json:"rp"`
+		IsMaker         bool            `json:"m"`
+		TradeID         int64           `json:"t"`
 	} `json:"o"`
 }
 
 //easyjson:json
 type accountUpdate struct {
-	Event     string `json:"e"`
-	Timestamp int64  `json:"E"`
-	Update    struct {
+	Event        string `json:"e"`
+	Timestamp    int64  `json:"E"`
+	TransactTime int64  `json:"T"`
+	Update       struct {
 		Reason   string `json:"m"`
 		Balances []struct {
 			Asset   string          `json:"a"`
 			Balance decimal.Decimal `json:"wb"`
+			Change  decimal.Decimal `json:"bc"`
 		} `json:"B"`
 		Positions []struct {
 			Symbol     string          `json:"s"`
@@ -247,44 +370,80 @@ type aggTrade struct {
 	IsBuyer   bool            `json:"m"`
 }
 
-func (bts *Binance) Listen(ctx context.Context, ch chan<- models.ExchangeMessage) {
-	rawCh := make(chan []byte, 100)
-	go func() {
-		for {
-			if err := bts.ws.Listen(ctx, rawCh); err != nil {
-				log.Printf("binance.Listen returned: %v\n", err)
-			}
+//easyjson:json
+type markPriceUpdate struct {
+	Event           string          `json:"e"`
+	EventTime       int64           `json:"E"`
+	Symbol          string          `json:"s"`
+	MarkPrice       decimal.Decimal `json:"p"`
+	IndexPrice      decimal.Decimal `json:"i"`
+	FundingRate     decimal.Decimal `json:"r"`
+	NextFundingTime int64           `json:"T"`
+}
 
+// listenWS reads messages from ws into rawCh and requests reconnect
+// via reconnectCh on error.
+func listenWS(
+	ctx context.Context,
+	ws *connectors.WS,
+	rawCh chan<- connectors.Frame,
+	reconnectCh chan<- any,
+) {
+	for {
+		if err := ws.ListenFrames(ctx, rawCh); err != nil {
+			logger.Warn("websocket listen failed", "ws", ws.Name, "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+			// wait for some time before reconnecting
+			logger.Info("websocket reconnecting", "ws", ws.Name)
 			select {
+			case reconnectCh <- "reconnect, please":
 			case <-ctx.Done():
 				return
-			case <-time.After(time.Second):
-				// wait for some time before reconnecting
-				log.Println("binance.Listen reconnecting")
-				bts.reconnectCh <- "reconnect, please"
 			}
 		}
-	}()
+	}
+}
+
+func (bts *Binance) Listen(ctx context.Context, ch chan<- models.ExchangeMessage) {
+	accountID := bts.AccountID()
+	exchange := bts.Exchange()
+	sendUser := func(msg models.ExchangeMessage) {
+		msg.Account = accountID
+		ch <- msg
+	}
+
+	rawCh := make(chan connectors.Frame, 100)
+	go listenWS(ctx, bts.ws, rawCh, bts.reconnectCh)
+	if bts.getListenKey() != "" {
+		go listenWS(ctx, bts.userWS, rawCh, bts.userReconnectCh)
+	}
 
 	for {
 		select {
-		case msg := <-rawCh:
+		case frame := <-rawCh:
+			msg, received := frame.Data, frame.ReceivedAt
 			var r subscribeResponse
 			if err := json.Unmarshal(msg, &r); err != nil {
-				log.Printf("failed to unmarshal msg: %v\n%v\n", err, string(msg))
+				decodeErrors.Inc("unknown")
+				logger.Error("failed to unmarshal message", "err", err, "msg", string(msg))
 				break
 			}
 
 			if r.ID > 0 {
 				if r.Result != nil {
-					log.Printf("message id=%d returned result %q", r.ID, *r.Result)
+					logger.Info("request returned result", "id", r.ID, "result", *r.Result)
 					break
 				}
 
 				bts.mux.RLock()
 				streams, ok := bts.subscriptionRequests[r.ID]
 				if !ok {
-					log.Printf("unsolicted message id=%d returned result %q", r.ID, *r.Result)
+					logger.Warn("unsolicited response", "id", r.ID)
 					bts.mux.RUnlock()
 					break
 				}
@@ -298,36 +457,69 @@ func (bts *Binance) Listen(ctx context.Context, ch chan<- models.ExchangeMessage
 
 			var e dummyEvent
 			if err := json.Unmarshal(msg, &e); err != nil {
-				log.Printf("failed to unmarshal msg: %v\n%v\n", err, string(msg))
+				decodeErrors.Inc("unknown")
+				logger.Error("failed to unmarshal message", "err", err, "msg", string(msg))
 				break
 			}
+			streamMessages.Inc(e.Event)
 
 			switch e.Event {
+			case "listenKeyExpired":
+				logger.Warn("listen key expired")
+				go bts.renewListenKey(ctx)
 			case "ORDER_TRADE_UPDATE":
 				var upd orderUpdate
 				if err := json.Unmarshal(msg, &upd); err != nil {
-					log.Printf("failed to unmarshal ORDER_TRADE_UPDATE: %v %q", err, string(msg))
+					decodeErrors.Inc(e.Event)
+					logger.Error("failed to unmarshal order update", "err", err, "msg", string(msg))
 					break
 				}
 
 				o := upd.Order
+				observeFeedLatency(exchange, e.Event, upd.EventTime, received)
+				transactMS := upd.TransactTime
+				if transactMS == 0 {
+					transactMS = o.UpdatedAtMS
+				}
 				size, err := decimal.NewFromString(o.FilledSize)
 				if err != nil {
-					log.Printf("failed to parse filled size: %v %q", err, string(msg))
+					decodeErrors.Inc(e.Event)
+					logger.Error("failed to parse filled size", "err", err, "msg", string(msg))
 					break
 				}
 				price, err := decimal.NewFromString(o.AveragePrice)
 				if err != nil {
-					log.Printf("failed to parse average price: %v %q", err, string(msg))
+					decodeErrors.Inc(e.Event)
+					logger.Error("failed to parse average price", "err", err, "msg", string(msg))
 					break
 				}
 
-				ch <- models.ExchangeMessage{
-					Exchange:  Name,
-					Symbol:    symbolFromExchange(o.Symbol),
-					Timestamp: time.Now().UTC(),
-					MsgType:   models.MsgTypeOrderStatus,
-					Payload: models.OrderUpdate{
+				if o.ExecutionType == "TRADE" {
+					sendUser(stamp(models.NewFillMessage(
+						exchange,
+						received,
+						models.Fill{
+							ClientOrderID:   o.ClientOrderID,
+							ExchangeOrderID: strconv.FormatInt(o.ExchangeOrderID, 10),
+							TradeID:         strconv.FormatInt(o.TradeID, 10),
+							Symbol:          symbolFromExchange(o.Symbol),
+							Side:            models.OrderSide(strings.ToLower(o.Side)),
+							Size:            o.LastFilledSize,
+							Price:           o.LastFilledPrice,
+							Commission:      o.Commission,
+							CommissionAsset: strings.ToLower(o.CommissionAsset),
+							RealizedProfit:  o.RealizedProfit,
+							IsMaker:         o.IsMaker,
+							Timestamp:       timestampToTime(o.UpdatedAtMS),
+						},
+					), upd.EventTime, transactMS, received))
+				}
+
+				sendUser(stamp(models.NewOrderUpdateMessage(
+					exchange,
+					symbolFromExchange(o.Symbol),
+					received,
+					models.OrderUpdate{
 						ClientOrderID:   o.ClientOrderID,
 						ExchangeOrderID: strconv.FormatInt(o.ExchangeOrderID, 10),
 						UpdatedAt:       timestampToTime(o.UpdatedAtMS),
@@ -337,53 +529,59 @@ func (bts *Binance) Listen(ctx context.Context, ch chan<- models.ExchangeMessage
 						FilledSize:      size,
 						AveragePrice:    price,
 					},
-				}
+				), upd.EventTime, transactMS, received))
 			case "ACCOUNT_UPDATE":
 				var upd accountUpdate
 				if err := json.Unmarshal(msg, &upd); err != nil {
-					log.Printf("failed to unmarshal account update: %v %q", err, string(msg))
+					decodeErrors.Inc(e.Event)
+					logger.Error("failed to unmarshal account update", "err", err, "msg", string(msg))
 					break
 				}
 
+				observeFeedLatency(exchange, e.Event, upd.Timestamp, received)
 				for _, b := range upd.Update.Balances {
-					ch <- models.ExchangeMessage{
-						Exchange:  Name,
-						Timestamp: timestampToTime(upd.Timestamp),
-						MsgType:   models.MsgTypeBalanceUpdate,
-						Payload: models.BalanceUpdate{
+					sendUser(stamp(models.NewBalanceUpdateMessage(
+						exchange,
+						timestampToTime(upd.Timestamp),
+						models.BalanceUpdate{
 							Asset:   strings.ToLower(b.Asset),
 							Balance: b.Balance,
+							Change:  b.Change,
+							Reason:  balanceReasonFromExchange(upd.Update.Reason),
 						},
-					}
+					), upd.Timestamp, upd.TransactTime, received))
 				}
 
 				for _, p := range upd.Update.Positions {
-					ch <- models.ExchangeMessage{
-						Exchange:  Name,
-						Timestamp: timestampToTime(upd.Timestamp),
-						MsgType:   models.MsgTypePositionUpdate,
-						Payload: models.PositionUpdate{
+					sendUser(stamp(models.NewPositionUpdateMessage(
+						exchange,
+						timestampToTime(upd.Timestamp),
+						models.PositionUpdate{
 							Symbol:     strings.ToLower(p.Symbol),
 							Amount:     p.Amount,
 							EntryPrice: p.EntryPrice,
 						},
-					}
+					), upd.Timestamp, upd.TransactTime, received))
 				}
 			case "bookTicker":
 				var ticker bookTicker
 				if err := json.Unmarshal(msg, &ticker); err != nil {
-					log.Printf("failed to unmarshal bookTicker: %v %q", err, string(msg))
+					decodeErrors.Inc(e.Event)
+					logger.Error("failed to unmarshal book ticker", "err", err, "msg", string(msg))
 					break
 				}
 
 				if ticker.Symbol != "" {
-					ts := time.Now().UTC()
-					ch <- models.ExchangeMessage{
-						Exchange:  Name,
-						Symbol:    symbolFromExchange(ticker.Symbol),
-						Timestamp: ts,
-						MsgType:   models.MsgTypeBBO,
-						Payload: models.BBO{
+					eventMS := ticker.EventTime
+					if eventMS == 0 {
+						eventMS = ticker.Timestamp
+					}
+					observeFeedLatency(exchange, e.Event, eventMS, received)
+					ch <- stamp(models.NewBBOMessage(
+						exchange,
+						symbolFromExchange(ticker.Symbol),
+						received,
+						models.BBO{
 							Bid: models.PriceLevel{
 								Price: ticker.BidPrice,
 								Size:  ticker.BidSize,
@@ -394,35 +592,58 @@ func (bts *Binance) Listen(ctx context.Context, ch chan<- models.ExchangeMessage
 							},
 							Timestamp: timestampToTime(ticker.Timestamp),
 						},
-					}
+					), eventMS, ticker.Timestamp, received)
 				}
 			case "aggTrade":
 				var trade aggTrade
 				if err := json.Unmarshal(msg, &trade); err != nil {
-					log.Printf("failed to unmarshal aggTrade: %v %q", err, string(msg))
+					decodeErrors.Inc(e.Event)
+					logger.Error("failed to unmarshal aggregated trade", "err", err, "msg", string(msg))
 					break
 				}
 
 				if trade.Symbol != "" {
+					observeFeedLatency(exchange, e.Event, trade.Timestamp, received)
 					side := models.OrderSideSell
 					if trade.IsBuyer {
 						side = models.OrderSideBuy
 					}
-					ch <- models.ExchangeMessage{
-						Exchange:  Name,
-						Symbol:    symbolFromExchange(trade.Symbol),
-						Timestamp: time.Now().UTC(),
-						MsgType:   models.MsgTypeTrade,
-						Payload: models.Trade{
+					ch <- stamp(models.NewTradeMessage(
+						exchange,
+						symbolFromExchange(trade.Symbol),
+						received,
+						models.Trade{
 							Price:     trade.Price,
 							Size:      trade.Quantity,
-							Timestamp: timestampToTime(trade.Timestamp),
+							Timestamp: timestampToTime(trade.TradeTime),
 							Side:      side,
 						},
-					}
+					), trade.Timestamp, trade.TradeTime, received)
+				}
+			case "markPriceUpdate":
+				var upd markPriceUpdate
+				if err := json.Unmarshal(msg, &upd); err != nil {
+					decodeErrors.Inc(e.Event)
+					logger.Error("failed to unmarshal mark price update", "err", err, "msg", string(msg))
+					break
+				}
+
+				if upd.Symbol != "" {
+					observeFeedLatency(exchange, e.Event, upd.EventTime, received)
+					ch <- stamp(models.NewFundingMessage(
+						exchange,
+						symbolFromExchange(upd.Symbol),
+						received,
+						models.Funding{
+							MarkPrice:       upd.MarkPrice,
+							IndexPrice:      upd.IndexPrice,
+							Rate:            upd.FundingRate,
+							NextFundingTime: timestampToTime(upd.NextFundingTime),
+						},
+					), upd.EventTime, 0, received)
 				}
 			default:
-				log.Printf("unknown event type: %q\n%q", e.Event, string(msg))
+				logger.Warn("unknown event type", "event", e.Event, "msg", string(msg))
 			}
 		case <-ctx.Done():
 			return
@@ -124,6 +124,10 @@ func easyjson72cd9c75DecodeDegenPkgConnectorsBinance1(in *jlexer.Lexer, out *ord
 		switch key {
 		case "e":
 			out.Event = string(in.String())
+		case "E":
+			out.EventTime = int64(in.Int64())
+		case "T":
+			out.TransactTime = int64(in.Int64())
 		case "o":
 			easyjson72cd9c75Decode(in, &out.Order)
 		default:
@@ -145,6 +149,16 @@ func easyjson72cd9c75EncodeDegenPkgConnectorsBinance1(out *jwriter.Writer, in or
 		out.RawString(prefix[1:])
 		out.String(string(in.Event))
 	}
+	{
+		const prefix string = ",\"E\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.EventTime))
+	}
+	{
+		const prefix string = ",\"T\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.TransactTime))
+	}
 	{
 		const prefix string = ",\"o\":"
 		out.RawString(prefix)
@@ -177,15 +191,23 @@ func (v *orderUpdate) UnmarshalEasyJSON(l *jlexer.Lexer) {
 	easyjson72cd9c75DecodeDegenPkgConnectorsBinance1(l, v)
 }
 func easyjson72cd9c75Decode(in *jlexer.Lexer, out *struct {
-	Symbol          string `json:"s"`
-	ClientOrderID   string `json:"c"`
-	ExchangeOrderID int64  `json:"i"`
-	Side            string `json:"S"`
-	Type            string `json:"o"`
-	Status          string `json:"X"`
-	FilledSize      string `json:"z"`
-	AveragePrice    string `json:"ap"`
-	UpdatedAtMS     int64  `json:"T"`
+	Symbol          string          `json:"s"`
+	ClientOrderID   string          `json:"c"`
+	ExchangeOrderID int64           `json:"i"`
+	Side            string          `json:"S"`
+	Type            string          `json:"o"`
+	Status          string          `json:"X"`
+	FilledSize      string          `json:"z"`
+	AveragePrice    string          `json:"ap"`
+	UpdatedAtMS     int64           `json:"T"`
+	ExecutionType   string          `json:"x"`
+	LastFilledSize  decimal.Decimal `json:"l"`
+	LastFilledPrice decimal.Decimal `json:"L"`
+	Commission      decimal.Decimal `json:"n"`
+	CommissionAsset string          `json:"N"`
+	RealizedProfit  decimal.Decimal `json:"rp"`
+	IsMaker         bool            `json:"m"`
+	TradeID         int64           `json:"t"`
 }) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
@@ -223,6 +245,30 @@ func easyjson72cd9c75Decode(in *jlexer.Lexer, out *struct {
 			out.AveragePrice = string(in.String())
 		case "T":
 			out.UpdatedAtMS = int64(in.Int64())
+		case "x":
+			out.ExecutionType = string(in.String())
+		case "l":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.LastFilledSize).UnmarshalJSON(data))
+			}
+		case "L":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.LastFilledPrice).UnmarshalJSON(data))
+			}
+		case "n":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Commission).UnmarshalJSON(data))
+			}
+		case "N":
+			out.CommissionAsset = string(in.String())
+		case "rp":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.RealizedProfit).UnmarshalJSON(data))
+			}
+		case "m":
+			out.IsMaker = bool(in.Bool())
+		case "t":
+			out.TradeID = int64(in.Int64())
 		default:
 			in.SkipRecursive()
 		}
@@ -234,15 +280,23 @@ func easyjson72cd9c75Decode(in *jlexer.Lexer, out *struct {
 	}
 }
 func easyjson72cd9c75Encode(out *jwriter.Writer, in struct {
-	Symbol          string `json:"s"`
-	ClientOrderID   string `json:"c"`
-	ExchangeOrderID int64  `json:"i"`
-	Side            string `json:"S"`
-	Type            string `json:"o"`
-	Status          string `json:"X"`
-	FilledSize      string `json:"z"`
-	AveragePrice    string `json:"ap"`
-	UpdatedAtMS     int64  `json:"T"`
+	Symbol          string          `json:"s"`
+	ClientOrderID   string          `json:"c"`
+	ExchangeOrderID int64           `json:"i"`
+	Side            string          `json:"S"`
+	Type            string          `json:"o"`
+	Status          string          `json:"X"`
+	FilledSize      string          `json:"z"`
+	AveragePrice    string          `json:"ap"`
+	UpdatedAtMS     int64           `json:"T"`
+	ExecutionType   string          `json:"x"`
+	LastFilledSize  decimal.Decimal `json:"l"`
+	LastFilledPrice decimal.Decimal `json:"L"`
+	Commission      decimal.Decimal `json:"n"`
+	CommissionAsset string          `json:"N"`
+	RealizedProfit  decimal.Decimal `json:"rp"`
+	IsMaker         bool            `json:"m"`
+	TradeID         int64           `json:"t"`
 }) {
 	out.RawByte('{')
 	first := true
@@ -292,9 +346,49 @@ func easyjson72cd9c75Encode(out *jwriter.Writer, in struct {
 		out.RawString(prefix)
 		out.Int64(int64(in.UpdatedAtMS))
 	}
+	{
+		const prefix string = ",\"x\":"
+		out.RawString(prefix)
+		out.String(string(in.ExecutionType))
+	}
+	{
+		const prefix string = ",\"l\":"
+		out.RawString(prefix)
+		out.Raw((in.LastFilledSize).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"L\":"
+		out.RawString(prefix)
+		out.Raw((in.LastFilledPrice).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"n\":"
+		out.RawString(prefix)
+		out.Raw((in.Commission).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"N\":"
+		out.RawString(prefix)
+		out.String(string(in.CommissionAsset))
+	}
+	{
+		const prefix string = ",\"rp\":"
+		out.RawString(prefix)
+		out.Raw((in.RealizedProfit).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"m\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.IsMaker))
+	}
+	{
+		const prefix string = ",\"t\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.TradeID))
+	}
 	out.RawByte('}')
 }
-func easyjson72cd9c75DecodeDegenPkgConnectorsBinance2(in *jlexer.Lexer, out *dummyEvent) {
+func easyjson72cd9c75DecodeDegenPkgConnectorsBinance2(in *jlexer.Lexer, out *markPriceUpdate) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -315,6 +409,24 @@ func easyjson72cd9c75DecodeDegenPkgConnectorsBinance2(in *jlexer.Lexer, out *dum
 		switch key {
 		case "e":
 			out.Event = string(in.String())
+		case "E":
+			out.EventTime = int64(in.Int64())
+		case "s":
+			out.Symbol = string(in.String())
+		case "p":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.MarkPrice).UnmarshalJSON(data))
+			}
+		case "i":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.IndexPrice).UnmarshalJSON(data))
+			}
+		case "r":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.FundingRate).UnmarshalJSON(data))
+			}
+		case "T":
+			out.NextFundingTime = int64(in.Int64())
 		default:
 			in.SkipRecursive()
 		}
@@ -325,7 +437,7 @@ func easyjson72cd9c75DecodeDegenPkgConnectorsBinance2(in *jlexer.Lexer, out *dum
 		in.Consumed()
 	}
 }
-func easyjson72cd9c75EncodeDegenPkgConnectorsBinance2(out *jwriter.Writer, in dummyEvent) {
+func easyjson72cd9c75EncodeDegenPkgConnectorsBinance2(out *jwriter.Writer, in markPriceUpdate) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -334,33 +446,63 @@ func easyjson72cd9c75EncodeDegenPkgConnectorsBinance2(out *jwriter.Writer, in du
 		out.RawString(prefix[1:])
 		out.String(string(in.Event))
 	}
+	{
+		const prefix string = ",\"E\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.EventTime))
+	}
+	{
+		const prefix string = ",\"s\":"
+		out.RawString(prefix)
+		out.String(string(in.Symbol))
+	}
+	{
+		const prefix string = ",\"p\":"
+		out.RawString(prefix)
+		out.Raw((in.MarkPrice).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"i\":"
+		out.RawString(prefix)
+		out.Raw((in.IndexPrice).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"r\":"
+		out.RawString(prefix)
+		out.Raw((in.FundingRate).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"T\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.NextFundingTime))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v dummyEvent) MarshalJSON() ([]byte, error) {
+func (v markPriceUpdate) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
 	easyjson72cd9c75EncodeDegenPkgConnectorsBinance2(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v dummyEvent) MarshalEasyJSON(w *jwriter.Writer) {
+func (v markPriceUpdate) MarshalEasyJSON(w *jwriter.Writer) {
 	easyjson72cd9c75EncodeDegenPkgConnectorsBinance2(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *dummyEvent) UnmarshalJSON(data []byte) error {
+func (v *markPriceUpdate) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
 	easyjson72cd9c75DecodeDegenPkgConnectorsBinance2(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *dummyEvent) UnmarshalEasyJSON(l *jlexer.Lexer) {
+func (v *markPriceUpdate) UnmarshalEasyJSON(l *jlexer.Lexer) {
 	easyjson72cd9c75DecodeDegenPkgConnectorsBinance2(l, v)
 }
-func easyjson72cd9c75DecodeDegenPkgConnectorsBinance3(in *jlexer.Lexer, out *bookTicker) {
+func easyjson72cd9c75DecodeDegenPkgConnectorsBinance3(in *jlexer.Lexer, out *dummyEvent) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -381,6 +523,74 @@ func easyjson72cd9c75DecodeDegenPkgConnectorsBinance3(in *jlexer.Lexer, out *boo
 		switch key {
 		case "e":
 			out.Event = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson72cd9c75EncodeDegenPkgConnectorsBinance3(out *jwriter.Writer, in dummyEvent) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"e\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Event))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v dummyEvent) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson72cd9c75EncodeDegenPkgConnectorsBinance3(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v dummyEvent) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson72cd9c75EncodeDegenPkgConnectorsBinance3(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *dummyEvent) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson72cd9c75DecodeDegenPkgConnectorsBinance3(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *dummyEvent) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson72cd9c75DecodeDegenPkgConnectorsBinance3(l, v)
+}
+func easyjson72cd9c75DecodeDegenPkgConnectorsBinance4(in *jlexer.Lexer, out *bookTicker) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "e":
+			out.Event = string(in.String())
+		case "E":
+			out.EventTime = int64(in.Int64())
 		case "s":
 			out.Symbol = string(in.String())
 		case "b":
@@ -411,7 +621,7 @@ func easyjson72cd9c75DecodeDegenPkgConnectorsBinance3(in *jlexer.Lexer, out *boo
 		in.Consumed()
 	}
 }
-func easyjson72cd9c75EncodeDegenPkgConnectorsBinance3(out *jwriter.Writer, in bookTicker) {
+func easyjson72cd9c75EncodeDegenPkgConnectorsBinance4(out *jwriter.Writer, in bookTicker) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -420,6 +630,11 @@ func easyjson72cd9c75EncodeDegenPkgConnectorsBinance3(out *jwriter.Writer, in bo
 		out.RawString(prefix[1:])
 		out.String(string(in.Event))
 	}
+	{
+		const prefix string = ",\"E\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.EventTime))
+	}
 	{
 		const prefix string = ",\"s\":"
 		out.RawString(prefix)
@@ -456,27 +671,27 @@ func easyjson72cd9c75EncodeDegenPkgConnectorsBinance3(out *jwriter.Writer, in bo
 // MarshalJSON supports json.Marshaler interface
 func (v bookTicker) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson72cd9c75EncodeDegenPkgConnectorsBinance3(&w, v)
+	easyjson72cd9c75EncodeDegenPkgConnectorsBinance4(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v bookTicker) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson72cd9c75EncodeDegenPkgConnectorsBinance3(w, v)
+	easyjson72cd9c75EncodeDegenPkgConnectorsBinance4(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *bookTicker) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson72cd9c75DecodeDegenPkgConnectorsBinance3(&r, v)
+	easyjson72cd9c75DecodeDegenPkgConnectorsBinance4(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *bookTicker) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson72cd9c75DecodeDegenPkgConnectorsBinance3(l, v)
+	easyjson72cd9c75DecodeDegenPkgConnectorsBinance4(l, v)
 }
-func easyjson72cd9c75DecodeDegenPkgConnectorsBinance4(in *jlexer.Lexer, out *accountUpdate) {
+func easyjson72cd9c75DecodeDegenPkgConnectorsBinance5(in *jlexer.Lexer, out *aggTrade) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -499,6 +714,141 @@ func easyjson72cd9c75DecodeDegenPkgConnectorsBinance4(in *jlexer.Lexer, out *acc
 			out.Event = string(in.String())
 		case "E":
 			out.Timestamp = int64(in.Int64())
+		case "s":
+			out.Symbol = string(in.String())
+		case "a":
+			out.TradeID = int64(in.Int64())
+		case "p":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Price).UnmarshalJSON(data))
+			}
+		case "q":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Quantity).UnmarshalJSON(data))
+			}
+		case "f":
+			out.FirstID = int64(in.Int64())
+		case "l":
+			out.LastID = int64(in.Int64())
+		case "T":
+			out.TradeTime = int64(in.Int64())
+		case "m":
+			out.IsBuyer = bool(in.Bool())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson72cd9c75EncodeDegenPkgConnectorsBinance5(out *jwriter.Writer, in aggTrade) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"e\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Event))
+	}
+	{
+		const prefix string = ",\"E\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.Timestamp))
+	}
+	{
+		const prefix string = ",\"s\":"
+		out.RawString(prefix)
+		out.String(string(in.Symbol))
+	}
+	{
+		const prefix string = ",\"a\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.TradeID))
+	}
+	{
+		const prefix string = ",\"p\":"
+		out.RawString(prefix)
+		out.Raw((in.Price).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"q\":"
+		out.RawString(prefix)
+		out.Raw((in.Quantity).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"f\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.FirstID))
+	}
+	{
+		const prefix string = ",\"l\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.LastID))
+	}
+	{
+		const prefix string = ",\"T\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.TradeTime))
+	}
+	{
+		const prefix string = ",\"m\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.IsBuyer))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v aggTrade) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson72cd9c75EncodeDegenPkgConnectorsBinance5(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v aggTrade) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson72cd9c75EncodeDegenPkgConnectorsBinance5(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *aggTrade) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson72cd9c75DecodeDegenPkgConnectorsBinance5(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *aggTrade) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson72cd9c75DecodeDegenPkgConnectorsBinance5(l, v)
+}
+func easyjson72cd9c75DecodeDegenPkgConnectorsBinance6(in *jlexer.Lexer, out *accountUpdate) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "e":
+			out.Event = string(in.String())
+		case "E":
+			out.Timestamp = int64(in.Int64())
+		case "T":
+			out.TransactTime = int64(in.Int64())
 		case "a":
 			easyjson72cd9c75Decode1(in, &out.Update)
 		default:
@@ -511,7 +861,7 @@ func easyjson72cd9c75DecodeDegenPkgConnectorsBinance4(in *jlexer.Lexer, out *acc
 		in.Consumed()
 	}
 }
-func easyjson72cd9c75EncodeDegenPkgConnectorsBinance4(out *jwriter.Writer, in accountUpdate) {
+func easyjson72cd9c75EncodeDegenPkgConnectorsBinance6(out *jwriter.Writer, in accountUpdate) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -525,6 +875,11 @@ func easyjson72cd9c75EncodeDegenPkgConnectorsBinance4(out *jwriter.Writer, in ac
 		out.RawString(prefix)
 		out.Int64(int64(in.Timestamp))
 	}
+	{
+		const prefix string = ",\"T\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.TransactTime))
+	}
 	{
 		const prefix string = ",\"a\":"
 		out.RawString(prefix)
@@ -536,31 +891,32 @@ func easyjson72cd9c75EncodeDegenPkgConnectorsBinance4(out *jwriter.Writer, in ac
 // MarshalJSON supports json.Marshaler interface
 func (v accountUpdate) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson72cd9c75EncodeDegenPkgConnectorsBinance4(&w, v)
+	easyjson72cd9c75EncodeDegenPkgConnectorsBinance6(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v accountUpdate) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson72cd9c75EncodeDegenPkgConnectorsBinance4(w, v)
+	easyjson72cd9c75EncodeDegenPkgConnectorsBinance6(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *accountUpdate) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson72cd9c75DecodeDegenPkgConnectorsBinance4(&r, v)
+	easyjson72cd9c75DecodeDegenPkgConnectorsBinance6(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *accountUpdate) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson72cd9c75DecodeDegenPkgConnectorsBinance4(l, v)
+	easyjson72cd9c75DecodeDegenPkgConnectorsBinance6(l, v)
 }
 func easyjson72cd9c75Decode1(in *jlexer.Lexer, out *struct {
 	Reason   string `json:"m"`
 	Balances []struct {
 		Asset   string          `json:"a"`
 		Balance decimal.Decimal `json:"wb"`
+		Change  decimal.Decimal `json:"bc"`
 	} `json:"B"`
 	Positions []struct {
 		Symbol     string          `json:"s"`
@@ -599,11 +955,13 @@ func easyjson72cd9c75Decode1(in *jlexer.Lexer, out *struct {
 						out.Balances = make([]struct {
 							Asset   string          `json:"a"`
 							Balance decimal.Decimal `json:"wb"`
-						}, 0, 2)
+							Change  decimal.Decimal `json:"bc"`
+						}, 0, 1)
 					} else {
 						out.Balances = []struct {
 							Asset   string          `json:"a"`
 							Balance decimal.Decimal `json:"wb"`
+							Change  decimal.Decimal `json:"bc"`
 						}{}
 					}
 				} else {
@@ -613,6 +971,7 @@ func easyjson72cd9c75Decode1(in *jlexer.Lexer, out *struct {
 					var v1 struct {
 						Asset   string          `json:"a"`
 						Balance decimal.Decimal `json:"wb"`
+						Change  decimal.Decimal `json:"bc"`
 					}
 					easyjson72cd9c75Decode2(in, &v1)
 					out.Balances = append(out.Balances, v1)
@@ -670,6 +1029,7 @@ func easyjson72cd9c75Encode1(out *jwriter.Writer, in struct {
 	Balances []struct {
 		Asset   string          `json:"a"`
 		Balance decimal.Decimal `json:"wb"`
+		Change  decimal.Decimal `json:"bc"`
 	} `json:"B"`
 	Positions []struct {
 		Symbol     string          `json:"s"`
@@ -790,6 +1150,7 @@ func easyjson72cd9c75Encode3(out *jwriter.Writer, in struct {
 func easyjson72cd9c75Decode2(in *jlexer.Lexer, out *struct {
 	Asset   string          `json:"a"`
 	Balance decimal.Decimal `json:"wb"`
+	Change  decimal.Decimal `json:"bc"`
 }) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
@@ -815,6 +1176,10 @@ func easyjson72cd9c75Decode2(in *jlexer.Lexer, out *struct {
 			if data := in.Raw(); in.Ok() {
 				in.AddError((out.Balance).UnmarshalJSON(data))
 			}
+		case "bc":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Change).UnmarshalJSON(data))
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -828,6 +1193,7 @@ func easyjson72cd9c75Decode2(in *jlexer.Lexer, out *struct {
 func easyjson72cd9c75Encode2(out *jwriter.Writer, in struct {
 	Asset   string          `json:"a"`
 	Balance decimal.Decimal `json:"wb"`
+	Change  decimal.Decimal `json:"bc"`
 }) {
 	out.RawByte('{')
 	first := true
@@ -842,5 +1208,10 @@ func easyjson72cd9c75Encode2(out *jwriter.Writer, in struct {
 		out.RawString(prefix)
 		out.Raw((in.Balance).MarshalJSON())
 	}
+	{
+		const prefix string = ",\"bc\":"
+		out.RawString(prefix)
+		out.Raw((in.Change).MarshalJSON())
+	}
 	out.RawByte('}')
 }
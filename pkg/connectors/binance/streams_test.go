@@ -0,0 +1,57 @@
+package binance
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Example from https://binance-docs.github.io/apidocs/futures/en/#event-order-update
+const testOrderTradeUpdate = `{"e":"ORDER_TRADE_UPDATE","E":1568879465651,"T":1568879465650,"o":{` +
+	`"s":"BTCUSDT","c":"TEST","S":"SELL","o":"TRAILING_STOP_MARKET","f":"GTC","q":"0.001","p":"0",` +
+	`"ap":"0","sp":"7103.04","x":"TRADE","X":"PARTIALLY_FILLED","i":8886774,"l":"0.0005","z":"0.0005",` +
+	`"L":"7100.5","N":"USDT","n":"0.0014201","T":1568879465650,"t":42,"b":"0","a":"9.91","m":true,` +
+	`"R":false,"wt":"CONTRACT_PRICE","ot":"TRAILING_STOP_MARKET","ps":"LONG","cp":false,"AP":"7476.89",` +
+	`"cr":"5.0","rp":"0.5"}}`
+
+func TestOrderUpdateDecode(t *testing.T) {
+	var upd orderUpdate
+	if err := json.Unmarshal([]byte(testOrderTradeUpdate), &upd); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	o := upd.Order
+	if o.ExecutionType != "TRADE" || o.TradeID != 42 || !o.IsMaker || o.CommissionAsset != "USDT" {
+		t.Errorf("unexpected fill details %+v", o)
+	}
+
+	checks := map[string]struct{ got, expected string }{
+		"last filled size":  {o.LastFilledSize.String(), "0.0005"},
+		"last filled price": {o.LastFilledPrice.String(), "7100.5"},
+		"commission":        {o.Commission.String(), "0.0014201"},
+		"realized profit":   {o.RealizedProfit.String(), "0.5"},
+	}
+
+	for name, c := range checks {
+		if c.got != c.expected {
+			t.Errorf("expected %s %s, got %s", name, c.expected, c.got)
+		}
+	}
+}
+
+// Example from https://binance-docs.github.io/apidocs/futures/en/#mark-price-stream
+const testMarkPriceUpdate = `{"e":"markPriceUpdate","E":1562305380000,"s":"BTCUSDT","p":"11794.15000000",` +
+	`"i":"11784.62659091","P":"11784.25641265","r":"0.00038167","T":1562306400000}`
+
+func TestMarkPriceUpdateDecode(t *testing.T) {
+	var upd markPriceUpdate
+	if err := json.Unmarshal([]byte(testMarkPriceUpdate), &upd); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	if upd.Symbol != "BTCUSDT" || upd.NextFundingTime != 1562306400000 {
+		t.Errorf("unexpected update %+v", upd)
+	}
+	if upd.FundingRate.String() != "0.00038167" || upd.MarkPrice.String() != "11794.15" {
+		t.Errorf("unexpected funding rate %v or mark price %v", upd.FundingRate, upd.MarkPrice)
+	}
+}
@@ -0,0 +1,360 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"degen/pkg/connectors"
+	"degen/pkg/models"
+)
+
+const (
+	wsAPIMethodPlace  = "order.place"
+	wsAPIMethodCancel = "order.cancel"
+	wsAPIMethodStatus = "order.status"
+	wsAPIMethodLogon  = "session.logon"
+
+	wsAPITimeout = 5 * time.Second
+)
+
+// errWSAPIUnavailable is returned when request was not sent
+// to the exchange, so it is safe to retry it via REST API.
+var errWSAPIUnavailable = errors.New("binance websocket API is not connected")
+
+type wsAPIReq struct {
+	ID     uint64            `json:"id"`
+	Method string            `json:"method"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+type wsAPIResp struct {
+	ID     uint64          `json:"id"`
+	Status int             `json:"status"`
+	Result json.RawMessage `json:"result"`
+	Error  *APIError       `json:"error"`
+}
+
+// LatencyFn is called after every order request with request method,
+// round-trip time and request error if any.
+type LatencyFn func(method string, rtt time.Duration, err error)
+
+// WSAPI manages orders via Binance futures WebSocket API:
+// https://binance-docs.github.io/apidocs/futures/en/#websocket-api-general-info
+// It falls back to REST API when the socket is down.
+type WSAPI struct {
+	api     *API
+	url     string
+	ws      *connectors.WS
+	up      uint32
+	session uint32
+	pending map[uint64]chan wsAPIResp
+
+	onLatency LatencyFn
+
+	mux sync.Mutex
+}
+
+var _ connectors.OrderAPI = &WSAPI{}
+
+func NewWSAPI(ctx context.Context, api *API, wsAPIURL string) *WSAPI {
+	w := &WSAPI{
+		api:       api,
+		url:       wsAPIURL,
+		ws:        &connectors.WS{Name: "binance_wsapi"},
+		pending:   make(map[uint64]chan wsAPIResp),
+		onLatency: logLatency,
+	}
+
+	go w.connectLoop(ctx)
+
+	return w
+}
+
+func logLatency(method string, rtt time.Duration, err error) {
+	if err != nil {
+		logger.Warn("websocket API request failed", "method", method, "rtt", rtt, "err", err)
+		return
+	}
+	logger.Debug("websocket API request", "method", method, "rtt", rtt)
+}
+
+// OnLatency sets a function to report request round-trip latency to.
+func (w *WSAPI) OnLatency(fn LatencyFn) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.onLatency = fn
+}
+
+func (w *WSAPI) reportLatency(method string, rtt time.Duration, err error) {
+	w.mux.Lock()
+	fn := w.onLatency
+	w.mux.Unlock()
+
+	if fn != nil {
+		fn(method, rtt, err)
+	}
+}
+
+// Connected returns true when websocket API connection is up.
+func (w *WSAPI) Connected() bool {
+	return atomic.LoadUint32(&w.up) == 1
+}
+
+// connectLoop keeps websocket API connection up until ctx is done.
+func (w *WSAPI) connectLoop(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		//nolint:errcheck
+		w.ws.Close()
+	}()
+
+	rawCh := make(chan []byte, 100)
+	go w.dispatch(ctx, rawCh)
+
+	for {
+		if err := w.ws.Connect(ctx, w.url); err != nil {
+			logger.Error("websocket API connect failed", "err", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+				continue
+			}
+		}
+
+		listenErr := make(chan error, 1)
+		go func() {
+			listenErr <- w.ws.Listen(ctx, rawCh)
+		}()
+
+		w.startSession(ctx)
+		atomic.StoreUint32(&w.up, 1)
+
+		err := <-listenErr
+		atomic.StoreUint32(&w.up, 0)
+		atomic.StoreUint32(&w.session, 0)
+		w.failPending()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+			logger.Warn("websocket API reconnecting", "err", err)
+		}
+	}
+}
+
+// startSession authenticates websocket API session if API key is Ed25519,
+// so following requests do not need to be signed. Requests are signed
+// one by one if logon is not possible.
+func (w *WSAPI) startSession(ctx context.Context) {
+	if _, ok := w.api.signer.(*Ed25519Signer); !ok {
+		return
+	}
+
+	params := make(map[string]string, 3)
+	if err := signParams(w.api.key, w.api.signer, params, time.Now()); err != nil {
+		logger.Error("websocket API failed to sign session logon", "err", err)
+		return
+	}
+
+	if _, err := w.send(ctx, wsAPIMethodLogon, params); err != nil {
+		logger.Error("websocket API session logon failed", "err", err)
+		return
+	}
+
+	atomic.StoreUint32(&w.session, 1)
+}
+
+// dispatch routes responses to waiting requests by request ID.
+func (w *WSAPI) dispatch(ctx context.Context, rawCh <-chan []byte) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-rawCh:
+			var resp wsAPIResp
+			if err := json.Unmarshal(msg, &resp); err != nil {
+				logger.Error("failed to unmarshal websocket API response", "err", err, "msg", string(msg))
+				break
+			}
+
+			w.mux.Lock()
+			ch, ok := w.pending[resp.ID]
+			delete(w.pending, resp.ID)
+			w.mux.Unlock()
+
+			if !ok {
+				logger.Warn("unsolicited websocket API response", "id", resp.ID, "msg", string(msg))
+				break
+			}
+
+			ch <- resp
+		}
+	}
+}
+
+// failPending closes channels of requests still waiting for response
+// when connection is lost.
+func (w *WSAPI) failPending() {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	for id, ch := range w.pending {
+		close(ch)
+		delete(w.pending, id)
+	}
+}
+
+// do sends signed request and waits for the response.
+func (w *WSAPI) do(
+	ctx context.Context,
+	method string,
+	values url.Values,
+	ts time.Time,
+) (json.RawMessage, error) {
+	if !w.Connected() {
+		return nil, errWSAPIUnavailable
+	}
+
+	params := make(map[string]string, len(values)+3)
+	for k := range values {
+		if v := values.Get(k); v != "" {
+			params[k] = v
+		}
+	}
+
+	if atomic.LoadUint32(&w.session) == 1 {
+		params["timestamp"] = strconv.FormatInt(ts.UnixMilli(), 10)
+	} else if err := signParams(w.api.key, w.api.signer, params, ts); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	return w.send(ctx, method, params)
+}
+
+// send writes request to the socket and waits for the response
+// with the same ID.
+func (w *WSAPI) send(
+	ctx context.Context,
+	method string,
+	params map[string]string,
+) (json.RawMessage, error) {
+	id := atomic.AddUint64(&requestID, 1)
+	b, err := json.Marshal(wsAPIReq{
+		ID:     id,
+		Method: method,
+		Params: params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	ch := make(chan wsAPIResp, 1)
+	w.mux.Lock()
+	w.pending[id] = ch
+	w.mux.Unlock()
+
+	defer func() {
+		w.mux.Lock()
+		delete(w.pending, id)
+		w.mux.Unlock()
+	}()
+
+	if err := w.ws.Write(ctx, b); err != nil {
+		return nil, fmt.Errorf("%w: %v", errWSAPIUnavailable, err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, errors.New("connection lost while waiting for response")
+		}
+		if resp.Error != nil {
+			resp.Error.Status = resp.Status
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-time.After(wsAPITimeout):
+		return nil, errors.New("timeout waiting for response")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// orderRequest performs websocket API order request falling back to REST
+// API if websocket is not available.
+func (w *WSAPI) orderRequest(
+	ctx context.Context,
+	method string,
+	values url.Values,
+	ts time.Time,
+	order models.Order,
+	fallback func(context.Context, models.Order) (*models.Order, error),
+) (*models.Order, error) {
+	start := time.Now()
+	res, err := w.do(ctx, method, values, ts)
+	if errors.Is(err, errWSAPIUnavailable) {
+		o, err := fallback(ctx, order)
+		w.reportLatency("rest:"+method, time.Since(start), err)
+		return o, err
+	}
+	w.reportLatency(method, time.Since(start), err)
+	if err != nil {
+		return nil, fmt.Errorf("binance.WSAPI %s: %w", method, err)
+	}
+
+	var respData placeOrderResp
+	if err := json.Unmarshal(res, &respData); err != nil {
+		return nil, fmt.Errorf("binance.WSAPI %s failed to unmarshal response: %w\n%s", method, err, string(res))
+	}
+
+	if err := respData.updateOrder(&order); err != nil {
+		return nil, fmt.Errorf("binance.WSAPI %s %w", method, err)
+	}
+
+	return &order, nil
+}
+
+func (w *WSAPI) PlaceOrder(ctx context.Context, order models.Order) (*models.Order, error) {
+	reqOrder, _ := newPlaceOrderReq(order)
+	return w.orderRequest(
+		ctx,
+		wsAPIMethodPlace,
+		reqOrder.Values(),
+		order.CreatedAt,
+		order,
+		w.api.PlaceOrder,
+	)
+}
+
+func (w *WSAPI) CancelOrder(ctx context.Context, order models.Order) (*models.Order, error) {
+	return w.orderRequest(
+		ctx,
+		wsAPIMethodCancel,
+		orderIDValues(order),
+		time.Now(),
+		order,
+		w.api.CancelOrder,
+	)
+}
+
+// QueryOrder returns current order state.
+func (w *WSAPI) QueryOrder(ctx context.Context, order models.Order) (*models.Order, error) {
+	return w.orderRequest(
+		ctx,
+		wsAPIMethodStatus,
+		orderIDValues(order),
+		time.Now(),
+		order,
+		w.api.QueryOrder,
+	)
+}
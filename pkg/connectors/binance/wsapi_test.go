@@ -0,0 +1,136 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+const testOrderResult = `{"orderId":42,"symbol":"DOGEUSDT","status":"NEW",` +
+	`"executedQty":"0","avgPrice":"0.00000","updateTime":1499827319559}`
+
+func wsAPIServer(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer c.Close()
+		for {
+			_, msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var req wsAPIReq
+			if err := json.Unmarshal(msg, &req); err != nil {
+				t.Errorf("failed to unmarshal request: %v", err)
+				return
+			}
+
+			if req.Params["signature"] == "" || req.Params["apiKey"] != "key" {
+				t.Errorf("request is not signed: %s", string(msg))
+			}
+
+			resp := fmt.Sprintf(`{"id":%d,"status":200,"result":%s}`, req.ID, testOrderResult)
+			if err := c.WriteMessage(websocket.TextMessage, []byte(resp)); err != nil {
+				t.Errorf("write: %v", err)
+				return
+			}
+		}
+	}))
+}
+
+func testOrder() models.Order {
+	return models.Order{
+		ClientOrderID: "test",
+		CreatedAt:     time.Now().UTC(),
+		Symbol:        "dogeusdt",
+		Size:          decimal.NewFromInt(200),
+		Side:          models.OrderSideBuy,
+		Type:          models.OrderTypeMarket,
+	}
+}
+
+func TestWSAPIPlaceOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := wsAPIServer(t)
+	defer srv.Close()
+
+	w := NewWSAPI(ctx, NewAPI("key", "secret", "http://127.0.0.1:1"), "ws"+strings.TrimPrefix(srv.URL, "http"))
+	methods := make(chan string, 1)
+	w.OnLatency(func(method string, rtt time.Duration, err error) {
+		methods <- method
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for !w.Connected() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for websocket API connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	res, err := w.PlaceOrder(ctx, testOrder())
+	if err != nil {
+		t.Fatalf("PlaceOrder returned error: %v", err)
+	}
+
+	if res.ExchangeOrderID != "42" {
+		t.Errorf("Expected exchange order ID 42 but got %q", res.ExchangeOrderID)
+	}
+
+	if res.Status != models.OrderStatusPlaced {
+		t.Errorf("Expected status %s but got %s", models.OrderStatusPlaced, res.Status)
+	}
+
+	if m := <-methods; m != wsAPIMethodPlace {
+		t.Errorf("Expected latency reported for %s but got %s", wsAPIMethodPlace, m)
+	}
+}
+
+func TestWSAPIFallback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v1/order" || r.Method != "POST" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprint(w, testOrderResult)
+	}))
+	defer rest.Close()
+
+	w := NewWSAPI(ctx, NewAPI("key", "secret", rest.URL), "ws://127.0.0.1:1")
+	methods := make(chan string, 1)
+	w.OnLatency(func(method string, rtt time.Duration, err error) {
+		methods <- method
+	})
+
+	res, err := w.PlaceOrder(ctx, testOrder())
+	if err != nil {
+		t.Fatalf("PlaceOrder returned error: %v", err)
+	}
+
+	if res.ExchangeOrderID != "42" {
+		t.Errorf("Expected exchange order ID 42 but got %q", res.ExchangeOrderID)
+	}
+
+	if m := <-methods; m != "rest:"+wsAPIMethodPlace {
+		t.Errorf("Expected latency reported for REST fallback but got %s", m)
+	}
+}
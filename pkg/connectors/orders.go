@@ -0,0 +1,38 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+
+	"degen/pkg/models"
+)
+
+// OrderAPI is implemented by everything able to manage orders on exchange:
+// REST and WebSocket connectors, simulators and wrappers around them.
+type OrderAPI interface {
+	PlaceOrder(ctx context.Context, order models.Order) (*models.Order, error)
+	CancelOrder(ctx context.Context, order models.Order) (*models.Order, error)
+	QueryOrder(ctx context.Context, order models.Order) (*models.Order, error)
+}
+
+// CodedError is an exchange error with exchange specific error code.
+type CodedError interface {
+	error
+	ErrorCode() string
+}
+
+// ErrorCode returns exchange error code of err or "unknown".
+func ErrorCode(err error) string {
+	var ce CodedError
+	if errors.As(err, &ce) {
+		return ce.ErrorCode()
+	}
+	return "unknown"
+}
+
+// AccountAPI is implemented by connectors able to fetch account state,
+// which is used to reconcile local state with exchange.
+type AccountAPI interface {
+	GetOpenOrders(ctx context.Context) ([]models.Order, error)
+	GetAccountState(ctx context.Context) ([]models.BalanceUpdate, []models.PositionUpdate, error)
+}
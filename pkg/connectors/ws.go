@@ -7,18 +7,50 @@ import (
 	"sync"
 	"time"
 
+	"degen/pkg/metrics"
+
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 )
 
+var (
+	wsConnects = metrics.NewCounter("degen_ws_connects_total",
+		"WebSocket connection attempts by result.", "ws", "result")
+	wsReconnects = metrics.NewCounter("degen_ws_reconnects_total",
+		"WebSocket connections replacing previous ones.", "ws")
+	wsMessages = metrics.NewCounter("degen_ws_messages_total",
+		"WebSocket messages received.", "ws")
+	wsBytes = metrics.NewCounter("degen_ws_received_bytes_total",
+		"WebSocket bytes received.", "ws")
+	wsReadErrors = metrics.NewCounter("degen_ws_read_errors_total",
+		"WebSocket read errors, each closing the connection.", "ws")
+)
+
+// Frame is a message read from websocket with its receive time.
+type Frame struct {
+	Data       []byte
+	ReceivedAt time.Time
+}
+
 type WS struct {
+	// Name labels connection metrics.
+	Name string
+
 	conn *websocket.Conn
 	mux  sync.Mutex
 }
 
+func (ws *WS) name() string {
+	if ws.Name == "" {
+		return "ws"
+	}
+	return ws.Name
+}
+
 func (ws *WS) Connect(ctx context.Context, url string) error {
 	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
+		wsConnects.Inc(ws.name(), "error")
 		if resp != nil {
 			body, errR := io.ReadAll(resp.Body)
 			if errR != nil {
@@ -31,24 +63,56 @@ func (ws *WS) Connect(ctx context.Context, url string) error {
 		return err
 	}
 
+	wsConnects.Inc(ws.name(), "ok")
+	ws.mux.Lock()
+	if ws.conn != nil {
+		wsReconnects.Inc(ws.name())
+	}
 	ws.conn = conn
+	ws.mux.Unlock()
 	return nil
 }
 
 func (ws *WS) Listen(ctx context.Context, ch chan<- []byte) error {
+	return ws.listen(ctx, func(msg []byte, _ time.Time) {
+		ch <- msg
+	})
+}
+
+// ListenFrames is Listen passing receive time along with messages,
+// taken right after a message is read from the wire.
+func (ws *WS) ListenFrames(ctx context.Context, ch chan<- Frame) error {
+	return ws.listen(ctx, func(msg []byte, receivedAt time.Time) {
+		ch <- Frame{Data: msg, ReceivedAt: receivedAt}
+	})
+}
+
+func (ws *WS) listen(ctx context.Context, send func(msg []byte, receivedAt time.Time)) error {
+	ws.mux.Lock()
+	conn := ws.conn
+	ws.mux.Unlock()
+
+	if conn == nil {
+		return errors.New("websocket is not connected")
+	}
+
 	for {
-		typ, msg, err := ws.conn.ReadMessage()
+		typ, msg, err := conn.ReadMessage()
+		receivedAt := time.Now().UTC()
 		if err != nil {
+			wsReadErrors.Inc(ws.name())
 			return fmt.Errorf("websocket.Read error: %v", err)
 		}
+		wsMessages.Inc(ws.name())
+		wsBytes.Add(float64(len(msg)), ws.name())
 
 		if typ == websocket.PingMessage {
 			//nolint:errcheck
-			ws.conn.WriteMessage(websocket.PongMessage, msg)
+			conn.WriteMessage(websocket.PongMessage, msg)
 			continue
 		}
 
-		ch <- msg
+		send(msg, receivedAt)
 
 		select {
 		case <-ctx.Done():
@@ -62,5 +126,18 @@ func (ws *WS) Listen(ctx context.Context, ch chan<- []byte) error {
 func (ws *WS) Write(ctx context.Context, msg []byte) error {
 	ws.mux.Lock()
 	defer ws.mux.Unlock()
+	if ws.conn == nil {
+		return errors.New("websocket is not connected")
+	}
 	return ws.conn.WriteMessage(websocket.TextMessage, msg)
 }
+
+// Close closes underlying connection, so Listen returns with error.
+func (ws *WS) Close() error {
+	ws.mux.Lock()
+	defer ws.mux.Unlock()
+	if ws.conn == nil {
+		return nil
+	}
+	return ws.conn.Close()
+}
@@ -0,0 +1,195 @@
+package execution
+
+import (
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// TWAP splits parent order into Slices market orders sent evenly over
+// Duration, the first one on start. Sizes of slices not filled are
+// added to the following ones.
+type TWAP struct {
+	*worker
+	sent int
+}
+
+func (t *TWAP) See(msg models.ExchangeMessage) {
+	t.mux.Lock()
+	if !t.seeLocked(msg) || t.canceled {
+		t.mux.Unlock()
+		return
+	}
+
+	interval := time.Duration(t.cfg.Duration / float64(t.cfg.Slices) * float64(time.Second))
+	due := t.cfg.Slices
+	if interval > 0 {
+		if n := int(msg.Timestamp.Sub(t.started)/interval) + 1; n < due {
+			due = n
+		}
+	}
+
+	var orders []models.Order
+	if t.sent < due {
+		t.sent = due
+		size := t.remainingLocked()
+		// Slices left including this one share the remaining size.
+		if left := t.cfg.Slices - due + 1; left > 1 {
+			size = t.roundLocked(size.Div(decimal.NewFromInt(int64(left))))
+		}
+		if size.IsPositive() {
+			orders = append(orders, t.childLocked(models.OrderTypeMarket, "", size, decimal.Zero))
+		}
+		t.finished = t.sent == t.cfg.Slices
+	}
+	t.mux.Unlock()
+
+	t.place(orders...)
+}
+
+// Participation sends market orders to keep its filled size at Rate of
+// volume traded since start, each order is at least Clip or the rest
+// of the parent size. It has to be fed with trades.
+type Participation struct {
+	*worker
+	volume decimal.Decimal
+}
+
+func (p *Participation) See(msg models.ExchangeMessage) {
+	p.mux.Lock()
+	if p.canceled {
+		p.mux.Unlock()
+		return
+	}
+	p.seeLocked(msg)
+	trade, ok := msg.Trade()
+	if !ok || msg.Symbol != p.parent.Symbol || p.started.IsZero() {
+		p.mux.Unlock()
+		return
+	}
+	p.volume = p.volume.Add(trade.Size)
+
+	var orders []models.Order
+	remaining := p.remainingLocked()
+	target := p.volume.Mul(decimal.NewFromFloat(p.cfg.Rate))
+	size := p.roundLocked(decimal.Min(target.Sub(p.committedLocked()), remaining))
+	if size.IsPositive() && (size.GreaterThanOrEqual(p.cfg.Clip) || size.Equal(remaining)) {
+		orders = append(orders, p.childLocked(models.OrderTypeMarket, "", size, decimal.Zero))
+	}
+	p.mux.Unlock()
+
+	p.place(orders...)
+}
+
+// Iceberg keeps a single limit order of Clip size at parent price,
+// showing only a part of the parent size, and places the next one
+// when it is finished. Without parent price, the touch on start is used.
+type Iceberg struct {
+	*worker
+	price decimal.Decimal
+}
+
+func (i *Iceberg) See(msg models.ExchangeMessage) {
+	i.mux.Lock()
+	if !i.seeLocked(msg) {
+		i.mux.Unlock()
+		return
+	}
+	orders := i.nextLocked()
+	i.mux.Unlock()
+
+	i.place(orders...)
+}
+
+func (i *Iceberg) OnOrderUpdate(upd models.OrderUpdate) {
+	i.worker.OnOrderUpdate(upd)
+
+	i.mux.Lock()
+	orders := i.nextLocked()
+	i.mux.Unlock()
+
+	i.place(orders...)
+}
+
+// nextLocked returns the next clip if no clip is open.
+// Caller must hold the lock.
+func (i *Iceberg) nextLocked() []models.Order {
+	if i.canceled || i.started.IsZero() || len(i.openLocked()) > 0 {
+		return nil
+	}
+	if i.price.IsZero() {
+		i.price = i.parent.Price
+		if i.price.IsZero() {
+			i.price = touch(i.parent.Side, i.bbo)
+		}
+	}
+
+	size := i.roundLocked(decimal.Min(i.cfg.Clip, i.remainingLocked()))
+	if !size.IsPositive() {
+		return nil
+	}
+	return []models.Order{i.childLocked(models.OrderTypeLimit, models.TimeInForceGTC, size, i.price)}
+}
+
+// Chase keeps a post-only limit order at the touch, the best bid for
+// buy or ask for sell, and replaces it when the touch moves. It does not
+// follow the touch beyond parent price, if it is set.
+type Chase struct {
+	*worker
+}
+
+func (c *Chase) See(msg models.ExchangeMessage) {
+	c.mux.Lock()
+	if !c.seeLocked(msg) || c.canceled {
+		c.mux.Unlock()
+		return
+	}
+
+	price := touch(c.parent.Side, c.bbo)
+	if limit := c.parent.Price; limit.IsPositive() {
+		if c.parent.Side == models.OrderSideBuy {
+			price = decimal.Min(price, limit)
+		} else {
+			price = decimal.Max(price, limit)
+		}
+	}
+
+	var stale []models.Order
+	for _, ch := range c.openLocked() {
+		if !ch.order.Price.Equal(price) {
+			stale = append(stale, ch.order)
+		}
+	}
+	open := len(c.openLocked())
+	c.mux.Unlock()
+
+	if len(stale) > 0 {
+		// Replacement is placed once the stale order is canceled,
+		// so the parent size is never exceeded.
+		if err := c.cancel(c.ctx, stale); err != nil {
+			logger.Warn("failed to cancel chased order", "symbol", c.parent.Symbol, "err", err)
+			return
+		}
+	} else if open > 0 {
+		return
+	}
+
+	c.mux.Lock()
+	var orders []models.Order
+	if size := c.roundLocked(c.remainingLocked()); size.IsPositive() && len(c.openLocked()) == 0 {
+		orders = append(orders, c.childLocked(models.OrderTypeLimit, models.TimeInForceGTX, size, price))
+	}
+	c.mux.Unlock()
+
+	c.place(orders...)
+}
+
+// touch returns the best price on the side of the order.
+func touch(side models.OrderSide, bbo models.BBO) decimal.Decimal {
+	if side == models.OrderSideSell {
+		return bbo.Ask.Price
+	}
+	return bbo.Bid.Price
+}
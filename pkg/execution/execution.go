@@ -0,0 +1,385 @@
+// Package execution works parent orders as child orders with execution
+// algorithms: TWAP, participation in traded volume, iceberg and chasing
+// the touch with post-only limits. Algorithms are driven by market data
+// timestamps, so they behave the same in backtests and live trading.
+package execution
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"degen/pkg/logging"
+	"degen/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var logger = logging.New("execution")
+
+const (
+	// AlgoMarket sends the whole size as a single market order.
+	AlgoMarket        = "market"
+	AlgoTWAP          = "twap"
+	AlgoParticipation = "participation"
+	AlgoIceberg       = "iceberg"
+	AlgoChase         = "chase"
+)
+
+// Orders is an order API algorithms place child orders with.
+type Orders interface {
+	PlaceOrder(ctx context.Context, order models.Order) (*models.Order, error)
+	CancelOrder(ctx context.Context, order models.Order) (*models.Order, error)
+}
+
+// Parent is an order worked by an algorithm.
+type Parent struct {
+	Symbol string
+	Side   models.OrderSide
+	Size   decimal.Decimal
+	// Price is a limit price of iceberg and the worst price chase
+	// follows the touch to, zero means no limit.
+	Price decimal.Decimal
+}
+
+// Config selects an algorithm and its parameters.
+type Config struct {
+	Algo string `json:"algo"`
+	// Duration in seconds TWAP is spread over in Slices orders.
+	Duration float64 `json:"duration,omitempty"`
+	Slices   int     `json:"slices,omitempty"`
+	// Rate is a share of traded volume participation algo takes.
+	Rate float64 `json:"rate,omitempty"`
+	// Clip is a visible size of iceberg and a minimal size
+	// of participation child orders.
+	Clip decimal.Decimal `json:"clip,omitempty"`
+	// StepSize child order sizes are rounded down to.
+	StepSize decimal.Decimal `json:"step_size,omitempty"`
+}
+
+func (c Config) Validate() error {
+	switch c.Algo {
+	case "", AlgoMarket, AlgoChase:
+	case AlgoTWAP:
+		if c.Duration <= 0 || c.Slices < 1 {
+			return fmt.Errorf("twap duration %v and slices %d must be positive", c.Duration, c.Slices)
+		}
+	case AlgoParticipation:
+		if c.Rate <= 0 || c.Rate > 1 {
+			return fmt.Errorf("participation rate %v is out of (0, 1] range", c.Rate)
+		}
+	case AlgoIceberg:
+		if !c.Clip.IsPositive() {
+			return fmt.Errorf("iceberg clip %v is not positive", c.Clip)
+		}
+	default:
+		return fmt.Errorf("unsupported execution algo %q", c.Algo)
+	}
+	if c.StepSize.IsNegative() || c.Clip.IsNegative() {
+		return fmt.Errorf("execution step size %v and clip %v must not be negative", c.StepSize, c.Clip)
+	}
+	return nil
+}
+
+// Progress reports execution of a parent order.
+type Progress struct {
+	Algo         string           `json:"algo"`
+	Symbol       string           `json:"symbol"`
+	Side         models.OrderSide `json:"side"`
+	Size         decimal.Decimal  `json:"size"`
+	Filled       decimal.Decimal  `json:"filled"`
+	AveragePrice decimal.Decimal  `json:"average_price"`
+	// ArrivalPrice is mid price when the algorithm started.
+	ArrivalPrice decimal.Decimal `json:"arrival_price"`
+	// Slippage of average price vs arrival price in basis points,
+	// positive when it is worse.
+	Slippage float64 `json:"slippage_bps"`
+	Children int     `json:"children"`
+	Done     bool    `json:"done"`
+}
+
+// Algo works a parent order. It has to be fed with market data of the
+// symbol and updates of its orders. Algorithms start on the first BBO.
+type Algo interface {
+	See(msg models.ExchangeMessage)
+	OnOrderUpdate(upd models.OrderUpdate)
+	// Cancel stops the algorithm and cancels its open orders.
+	Cancel(ctx context.Context) error
+	Progress() Progress
+	Done() bool
+}
+
+// New creates algorithm of the config working the parent order.
+func New(ctx context.Context, orders Orders, cfg Config, parent Parent) (Algo, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("execution.New: %w", err)
+	}
+	if !parent.Size.IsPositive() {
+		return nil, fmt.Errorf("execution.New: parent size %v is not positive", parent.Size)
+	}
+
+	w := newWorker(ctx, orders, cfg, parent)
+	switch cfg.Algo {
+	case AlgoTWAP:
+		return &TWAP{worker: w}, nil
+	case AlgoParticipation:
+		return &Participation{worker: w}, nil
+	case AlgoIceberg:
+		return &Iceberg{worker: w}, nil
+	case AlgoChase:
+		return &Chase{worker: w}, nil
+	}
+	// Market order is TWAP of a single slice.
+	w.cfg.Slices, w.cfg.Duration = 1, 1
+	return &TWAP{worker: w}, nil
+}
+
+// child is an order placed by an algorithm.
+type child struct {
+	order  models.Order
+	filled decimal.Decimal
+	avg    decimal.Decimal
+	done   bool
+}
+
+// worker keeps child orders and progress of an algorithm.
+type worker struct {
+	ctx    context.Context
+	orders Orders
+	cfg    Config
+	parent Parent
+
+	// started is time of the first BBO, arrival is its mid.
+	started  time.Time
+	arrival  decimal.Decimal
+	bbo      models.BBO
+	children map[string]*child
+	count    int
+	canceled bool
+	// finished is set by algorithms sending no more orders.
+	finished bool
+
+	mux sync.Mutex
+}
+
+func newWorker(ctx context.Context, orders Orders, cfg Config, parent Parent) *worker {
+	if cfg.Algo == "" {
+		cfg.Algo = AlgoMarket
+	}
+	return &worker{
+		ctx:      ctx,
+		orders:   orders,
+		cfg:      cfg,
+		parent:   parent,
+		children: make(map[string]*child),
+	}
+}
+
+// seeLocked records market data and tells if BBO of the
+// parent symbol was updated. Caller must hold the lock.
+func (w *worker) seeLocked(msg models.ExchangeMessage) bool {
+	bbo, ok := msg.BBO()
+	if !ok || msg.Symbol != w.parent.Symbol || !bbo.Bid.Price.IsPositive() || !bbo.Ask.Price.IsPositive() {
+		return false
+	}
+	w.bbo = bbo
+	if w.started.IsZero() {
+		w.started = msg.Timestamp
+		w.arrival = bbo.Bid.Price.Add(bbo.Ask.Price).Div(decimal.NewFromInt(2))
+	}
+	return true
+}
+
+// committedLocked returns size of open orders and fills.
+func (w *worker) committedLocked() decimal.Decimal {
+	res := decimal.Zero
+	for _, c := range w.children {
+		if c.done {
+			res = res.Add(c.filled)
+		} else {
+			res = res.Add(c.order.Size)
+		}
+	}
+	return res
+}
+
+// remainingLocked returns size not committed to child orders yet.
+func (w *worker) remainingLocked() decimal.Decimal {
+	return w.parent.Size.Sub(w.committedLocked())
+}
+
+// roundLocked rounds size down to step size, if it is set.
+func (w *worker) roundLocked(size decimal.Decimal) decimal.Decimal {
+	if !w.cfg.StepSize.IsPositive() {
+		return size
+	}
+	return size.Div(w.cfg.StepSize).Floor().Mul(w.cfg.StepSize)
+}
+
+func (w *worker) openLocked() []*child {
+	var res []*child
+	for _, c := range w.children {
+		if !c.done {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// childLocked registers a child order before it is placed, so its
+// updates are not missed. Caller must hold the lock.
+func (w *worker) childLocked(typ models.OrderType, tif models.TimeInForce, size, price decimal.Decimal) models.Order {
+	o := models.Order{
+		ClientOrderID: uuid.New().String(),
+		CreatedAt:     time.Now().UTC(),
+		Symbol:        w.parent.Symbol,
+		Side:          w.parent.Side,
+		Type:          typ,
+		TimeInForce:   tif,
+		Size:          size,
+		Price:         price,
+	}
+	w.children[o.ClientOrderID] = &child{order: o}
+	w.count++
+	return o
+}
+
+// place places child orders. It is called without the lock,
+// because simulated exchanges report fills synchronously.
+func (w *worker) place(orders ...models.Order) {
+	for _, o := range orders {
+		res, err := w.orders.PlaceOrder(w.ctx, o)
+
+		w.mux.Lock()
+		c := w.children[o.ClientOrderID]
+		switch {
+		case err != nil:
+			logger.Warn("failed to place child order",
+				"algo", w.cfg.Algo,
+				"symbol", o.Symbol,
+				"side", o.Side,
+				"size", o.Size,
+				"price", o.Price,
+				"err", err,
+			)
+			c.done = true
+		case !c.done:
+			c.order.ExchangeOrderID = res.ExchangeOrderID
+			if res.Status.IsFinal() {
+				c.filled, c.avg, c.done = res.FilledSize, res.AveragePrice, true
+			}
+		}
+		w.mux.Unlock()
+	}
+}
+
+// cancel cancels child orders without the lock,
+// returning the first error.
+func (w *worker) cancel(ctx context.Context, orders []models.Order) error {
+	var firstErr error
+	for _, o := range orders {
+		res, err := w.orders.CancelOrder(ctx, o)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to cancel child order %s: %w", o.ClientOrderID, err)
+			}
+			continue
+		}
+
+		w.mux.Lock()
+		if c := w.children[o.ClientOrderID]; !c.done {
+			if res.FilledSize.GreaterThan(c.filled) {
+				c.filled, c.avg = res.FilledSize, res.AveragePrice
+			}
+			c.done = true
+		}
+		w.mux.Unlock()
+	}
+	return firstErr
+}
+
+func (w *worker) OnOrderUpdate(upd models.OrderUpdate) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	c, ok := w.children[upd.ClientOrderID]
+	if !ok || c.done {
+		return
+	}
+	if upd.FilledSize.GreaterThanOrEqual(c.filled) {
+		c.filled, c.avg = upd.FilledSize, upd.AveragePrice
+	}
+	c.done = upd.Status.IsFinal()
+}
+
+func (w *worker) Cancel(ctx context.Context) error {
+	w.mux.Lock()
+	w.canceled = true
+	var orders []models.Order
+	for _, c := range w.openLocked() {
+		orders = append(orders, c.order)
+	}
+	w.mux.Unlock()
+
+	if err := w.cancel(ctx, orders); err != nil {
+		return fmt.Errorf("execution.Cancel: %w", err)
+	}
+	return nil
+}
+
+func (w *worker) Done() bool {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	return w.doneLocked()
+}
+
+func (w *worker) doneLocked() bool {
+	if len(w.openLocked()) > 0 {
+		return false
+	}
+	return w.canceled || w.finished || w.filledLocked().GreaterThanOrEqual(w.parent.Size)
+}
+
+func (w *worker) filledLocked() decimal.Decimal {
+	res := decimal.Zero
+	for _, c := range w.children {
+		res = res.Add(c.filled)
+	}
+	return res
+}
+
+func (w *worker) Progress() Progress {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	p := Progress{
+		Algo:         w.cfg.Algo,
+		Symbol:       w.parent.Symbol,
+		Side:         w.parent.Side,
+		Size:         w.parent.Size,
+		Filled:       w.filledLocked(),
+		ArrivalPrice: w.arrival,
+		Children:     w.count,
+		Done:         w.doneLocked(),
+	}
+	if !p.Filled.IsPositive() {
+		return p
+	}
+
+	notional := decimal.Zero
+	for _, c := range w.children {
+		notional = notional.Add(c.filled.Mul(c.avg))
+	}
+	p.AveragePrice = notional.Div(p.Filled)
+	if w.arrival.IsPositive() {
+		slippage := p.AveragePrice.Sub(w.arrival).Div(w.arrival).InexactFloat64() * 1e4
+		if w.parent.Side == models.OrderSideSell {
+			slippage = -slippage
+		}
+		p.Slippage = slippage
+	}
+	return p
+}
@@ -0,0 +1,184 @@
+package execution
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// fakeOrders fills market orders at the touch and keeps limit orders open.
+type fakeOrders struct {
+	bbo      models.BBO
+	open     map[string]models.Order
+	placed   []models.Order
+	canceled int
+}
+
+func newFakeOrders() *fakeOrders {
+	return &fakeOrders{open: make(map[string]models.Order)}
+}
+
+func (f *fakeOrders) PlaceOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	f.placed = append(f.placed, order)
+	if order.Type == models.OrderTypeMarket {
+		order.Status, order.FilledSize, order.AveragePrice = models.OrderStatusFilled, order.Size, f.bbo.Ask.Price
+		if order.Side == models.OrderSideSell {
+			order.AveragePrice = f.bbo.Bid.Price
+		}
+		return &order, nil
+	}
+	order.Status = models.OrderStatusPlaced
+	f.open[order.ClientOrderID] = order
+	return &order, nil
+}
+
+func (f *fakeOrders) CancelOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	delete(f.open, order.ClientOrderID)
+	f.canceled++
+	order.Status = models.OrderStatusCanceled
+	return &order, nil
+}
+
+// fill fills open limit orders reporting them to the algorithm.
+func (f *fakeOrders) fill(a Algo) {
+	for id, o := range f.open {
+		delete(f.open, id)
+		a.OnOrderUpdate(models.OrderUpdate{
+			ClientOrderID: id,
+			Status:        models.OrderStatusFilled,
+			FilledSize:    o.Size,
+			AveragePrice:  o.Price,
+		})
+	}
+}
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func bbo(orders *fakeOrders, sec int, bid float64) models.ExchangeMessage {
+	orders.bbo = models.BBO{
+		Bid: models.PriceLevel{Price: decimal.NewFromFloat(bid), Size: decimal.NewFromInt(1)},
+		Ask: models.PriceLevel{Price: decimal.NewFromFloat(bid + 1), Size: decimal.NewFromInt(1)},
+	}
+	return models.NewBBOMessage("ex", "ethusdt", start.Add(time.Duration(sec)*time.Second), orders.bbo)
+}
+
+func TestTWAP(t *testing.T) {
+	orders := newFakeOrders()
+	parent := Parent{Symbol: "ethusdt", Side: models.OrderSideBuy, Size: decimal.NewFromInt(1)}
+	a, err := New(context.Background(), orders, Config{Algo: AlgoTWAP, Duration: 60, Slices: 4}, parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sec := range []int{0, 5, 14, 15, 50, 100} {
+		a.See(bbo(orders, sec, 1999+float64(sec)))
+	}
+
+	// Slices are due at 0, 15, 30 and 45 seconds, the late ones are merged.
+	sizes := []string{"0.25", "0.25", "0.5"}
+	if len(orders.placed) != len(sizes) {
+		t.Fatalf("expected %d slices, got %d", len(sizes), len(orders.placed))
+	}
+	for i, o := range orders.placed {
+		if o.Size.String() != sizes[i] || o.Type != models.OrderTypeMarket {
+			t.Errorf("unexpected slice %+v", o)
+		}
+	}
+
+	p := a.Progress()
+	// Filled at asks 2000, 2015 and 2050 vs arrival mid 1999.5.
+	if !p.Done || !p.Filled.Equal(parent.Size) || !p.AveragePrice.Equal(decimal.NewFromFloat(2028.75)) {
+		t.Errorf("unexpected progress %+v", p)
+	}
+	if p.Slippage < 146 || p.Slippage > 147 {
+		t.Errorf("expected slippage about 146.3 bps, got %v", p.Slippage)
+	}
+}
+
+func TestParticipation(t *testing.T) {
+	orders := newFakeOrders()
+	parent := Parent{Symbol: "ethusdt", Side: models.OrderSideSell, Size: decimal.NewFromInt(1)}
+	cfg := Config{Algo: AlgoParticipation, Rate: 0.1, Clip: decimal.NewFromFloat(0.2)}
+	a, err := New(context.Background(), orders, cfg, parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.See(bbo(orders, 0, 2000))
+	trade := func(size int64) {
+		a.See(models.NewTradeMessage("ex", "ethusdt", start, models.Trade{Size: decimal.NewFromInt(size)}))
+	}
+	trade(1)
+	if len(orders.placed) != 0 {
+		t.Fatal("expected no order under clip size")
+	}
+	trade(2)
+	if len(orders.placed) != 1 || !orders.placed[0].Size.Equal(decimal.NewFromFloat(0.3)) {
+		t.Fatalf("expected order of 10%% volume, got %+v", orders.placed)
+	}
+	trade(100)
+	if p := a.Progress(); !p.Done || !p.Filled.Equal(parent.Size) || len(orders.placed) != 2 {
+		t.Errorf("expected parent to be filled without overshooting, got %+v", p)
+	}
+}
+
+func TestIceberg(t *testing.T) {
+	orders := newFakeOrders()
+	parent := Parent{Symbol: "ethusdt", Side: models.OrderSideBuy, Size: decimal.NewFromInt(1), Price: decimal.NewFromInt(1990)}
+	a, err := New(context.Background(), orders, Config{Algo: AlgoIceberg, Clip: decimal.NewFromFloat(0.4)}, parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		a.See(bbo(orders, i, 2000))
+		if len(orders.open) != 1 {
+			t.Fatalf("expected single visible clip, got %d", len(orders.open))
+		}
+		orders.fill(a)
+	}
+
+	sizes := []string{"0.4", "0.4", "0.2"}
+	for i, o := range orders.placed {
+		if o.Size.String() != sizes[i] || !o.Price.Equal(parent.Price) {
+			t.Errorf("unexpected clip %+v", o)
+		}
+	}
+	if !a.Done() {
+		t.Error("expected iceberg to be done")
+	}
+}
+
+func TestChase(t *testing.T) {
+	orders := newFakeOrders()
+	parent := Parent{Symbol: "ethusdt", Side: models.OrderSideBuy, Size: decimal.NewFromInt(1), Price: decimal.NewFromInt(2002)}
+	a, err := New(context.Background(), orders, Config{Algo: AlgoChase}, parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.See(bbo(orders, 0, 2000))
+	a.See(bbo(orders, 1, 2000))
+	if len(orders.placed) != 1 || orders.placed[0].TimeInForce != models.TimeInForceGTX {
+		t.Fatalf("expected single post-only order, got %+v", orders.placed)
+	}
+
+	// Touch moving up is chased up to the limit price.
+	a.See(bbo(orders, 2, 2001))
+	a.See(bbo(orders, 3, 2005))
+	if len(orders.placed) != 3 || orders.canceled != 2 || len(orders.open) != 1 {
+		t.Fatalf("expected order replaced twice, got %d placed, %d canceled", len(orders.placed), orders.canceled)
+	}
+	if last := orders.placed[2]; !last.Price.Equal(parent.Price) || !last.Size.Equal(parent.Size) {
+		t.Errorf("expected order at limit price, got %+v", last)
+	}
+
+	orders.fill(a)
+	if p := a.Progress(); !p.Done || !p.AveragePrice.Equal(parent.Price) {
+		t.Errorf("unexpected progress %+v", p)
+	}
+}
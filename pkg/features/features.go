@@ -0,0 +1,445 @@
+// Package features computes rolling window aggregations of market data
+// into feature rows. Symbols, windows, fields and aggregations are set
+// by Config, columns are ordered as configured, so the header is stable
+// and every column name describes how its value was computed.
+// Windows are driven by message timestamps, so replays of recorded
+// market data produce the same rows as live data.
+package features
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"degen/pkg/indicators"
+	"degen/pkg/models"
+)
+
+// SchemaVersion is incremented when names or meaning of columns change.
+const SchemaVersion = 1
+
+// Fields are values windows are built from.
+const (
+	FieldBidPrice   = "bid_price"
+	FieldBidSize    = "bid_size"
+	FieldAskPrice   = "ask_price"
+	FieldAskSize    = "ask_size"
+	FieldMidPrice   = "mid_price"
+	FieldSpread     = "spread"
+	FieldImbalance  = "imbalance"
+	FieldBuyVolume  = "buy_volume"
+	FieldSellVolume = "sell_volume"
+	FieldBuyPrice   = "buy_price"
+	FieldSellPrice  = "sell_price"
+	// FieldTradeSize is a size of every trade, its count is a trade count.
+	FieldTradeSize = "trade_size"
+)
+
+var bboFields = map[string]func(models.BBO) float64{
+	FieldBidPrice:  func(b models.BBO) float64 { return b.Bid.Price.InexactFloat64() },
+	FieldBidSize:   func(b models.BBO) float64 { return b.Bid.Size.InexactFloat64() },
+	FieldAskPrice:  func(b models.BBO) float64 { return b.Ask.Price.InexactFloat64() },
+	FieldAskSize:   func(b models.BBO) float64 { return b.Ask.Size.InexactFloat64() },
+	FieldMidPrice:  indicators.Mid,
+	FieldSpread:    func(b models.BBO) float64 { return b.Ask.Price.Sub(b.Bid.Price).InexactFloat64() },
+	FieldImbalance: indicators.Imbalance,
+}
+
+// tradeFields return trade value and if the trade is counted.
+var tradeFields = map[string]func(models.Trade) (float64, bool){
+	FieldBuyVolume: func(t models.Trade) (float64, bool) {
+		return t.Size.InexactFloat64(), t.Side == models.OrderSideBuy
+	},
+	FieldSellVolume: func(t models.Trade) (float64, bool) {
+		return t.Size.InexactFloat64(), t.Side != models.OrderSideBuy
+	},
+	FieldBuyPrice: func(t models.Trade) (float64, bool) {
+		return t.Price.InexactFloat64(), t.Side == models.OrderSideBuy
+	},
+	FieldSellPrice: func(t models.Trade) (float64, bool) {
+		return t.Price.InexactFloat64(), t.Side != models.OrderSideBuy
+	},
+	FieldTradeSize: func(t models.Trade) (float64, bool) { return t.Size.InexactFloat64(), true },
+}
+
+// aggregations of window values, empty windows are NaN except count and sum.
+// Percentiles are named pN, e.g. p50 or p99.
+var aggregations = map[string]func(values []float64) float64{
+	"min": func(v []float64) float64 {
+		res := math.NaN()
+		for i, x := range v {
+			if i == 0 || x < res {
+				res = x
+			}
+		}
+		return res
+	},
+	"max": func(v []float64) float64 {
+		res := math.NaN()
+		for i, x := range v {
+			if i == 0 || x > res {
+				res = x
+			}
+		}
+		return res
+	},
+	"first": func(v []float64) float64 {
+		if len(v) == 0 {
+			return math.NaN()
+		}
+		return v[0]
+	},
+	"last": func(v []float64) float64 {
+		if len(v) == 0 {
+			return math.NaN()
+		}
+		return v[len(v)-1]
+	},
+	// mid is an average of the first and the last values.
+	"mid": func(v []float64) float64 {
+		if len(v) == 0 {
+			return math.NaN()
+		}
+		return (v[0] + v[len(v)-1]) / 2
+	},
+	"avg":    mean,
+	"sum":    sum,
+	"count":  func(v []float64) float64 { return float64(len(v)) },
+	"stddev": stddev,
+}
+
+func sum(v []float64) float64 {
+	var res float64
+	for _, x := range v {
+		res += x
+	}
+	return res
+}
+
+func mean(v []float64) float64 {
+	if len(v) == 0 {
+		return math.NaN()
+	}
+	return sum(v) / float64(len(v))
+}
+
+// stddev is a population standard deviation.
+func stddev(v []float64) float64 {
+	if len(v) == 0 {
+		return math.NaN()
+	}
+	m := mean(v)
+	var sq float64
+	for _, x := range v {
+		sq += (x - m) * (x - m)
+	}
+	return math.Sqrt(sq / float64(len(v)))
+}
+
+// percentile returns linearly interpolated p-th percentile.
+func percentile(p float64) func([]float64) float64 {
+	return func(v []float64) float64 {
+		if len(v) == 0 {
+			return math.NaN()
+		}
+		sorted := append([]float64(nil), v...)
+		sort.Float64s(sorted)
+		pos := p / 100 * float64(len(sorted)-1)
+		i := int(pos)
+		if i >= len(sorted)-1 {
+			return sorted[len(sorted)-1]
+		}
+		return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+	}
+}
+
+func aggregation(name string) (func([]float64) float64, error) {
+	if fn, ok := aggregations[name]; ok {
+		return fn, nil
+	}
+	if strings.HasPrefix(name, "p") {
+		if p, err := strconv.ParseFloat(name[1:], 64); err == nil && p >= 0 && p <= 100 {
+			return percentile(p), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported aggregation %q", name)
+}
+
+// Config of features.
+type Config struct {
+	Symbols []string `json:"symbols"`
+	// Windows are durations like 1s or 5m.
+	Windows      []string `json:"windows"`
+	Fields       []string `json:"fields"`
+	Aggregations []string `json:"aggregations"`
+	// Interval of rows, 1s by default.
+	Interval string `json:"interval,omitempty"`
+	// MaxSize is a maximal number of values in a window, 86400 by default.
+	MaxSize int `json:"max_size,omitempty"`
+}
+
+// DefaultConfig returns features the dumper used to have hardcoded.
+func DefaultConfig() Config {
+	return Config{
+		Symbols: []string{"ethusdt", "btcusdt", "dogeusdt", "solusdt", "bnbusdt"},
+		Windows: []string{"1s", "5s", "30s", "1m", "5m", "10m"},
+		Fields: []string{
+			FieldBidPrice, FieldBidSize, FieldAskPrice, FieldAskSize,
+			FieldBuyVolume, FieldSellVolume, FieldBuyPrice, FieldSellPrice,
+		},
+		Aggregations: []string{"min", "max", "first", "last", "mid", "avg", "sum", "count"},
+		Interval:     "1s",
+		MaxSize:      86400,
+	}
+}
+
+// LoadConfig reads config from JSON file over the defaults.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("features.LoadConfig failed to read: %w", err)
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("features.LoadConfig failed to parse %s: %w", path, err)
+	}
+	return cfg, cfg.Validate()
+}
+
+// RowInterval returns interval of rows.
+func (c Config) RowInterval() time.Duration {
+	d, err := time.ParseDuration(c.Interval)
+	if err != nil || d <= 0 {
+		return time.Second
+	}
+	return d
+}
+
+func (c Config) Validate() error {
+	if len(c.Symbols) == 0 || len(c.Windows) == 0 || len(c.Fields) == 0 || len(c.Aggregations) == 0 {
+		return errors.New("features symbols, windows, fields and aggregations must not be empty")
+	}
+	for _, w := range c.Windows {
+		if d, err := time.ParseDuration(w); err != nil || d <= 0 {
+			return fmt.Errorf("features window %q is not a positive duration", w)
+		}
+	}
+	for _, f := range c.Fields {
+		if bboFields[f] == nil && tradeFields[f] == nil {
+			return fmt.Errorf("unsupported feature field %q", f)
+		}
+	}
+	for _, a := range c.Aggregations {
+		if _, err := aggregation(a); err != nil {
+			return fmt.Errorf("features: %w", err)
+		}
+	}
+	if c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err != nil || d <= 0 {
+			return fmt.Errorf("features interval %q is not a positive duration", c.Interval)
+		}
+	}
+	if c.MaxSize < 0 {
+		return fmt.Errorf("features max size %d is negative", c.MaxSize)
+	}
+	if err := unique("symbol", c.Symbols); err != nil {
+		return err
+	}
+	if err := unique("window", c.Windows); err != nil {
+		return err
+	}
+	if err := unique("field", c.Fields); err != nil {
+		return err
+	}
+	return unique("aggregation", c.Aggregations)
+}
+
+func unique(what string, values []string) error {
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		if seen[v] {
+			return fmt.Errorf("duplicate feature %s %q", what, v)
+		}
+		seen[v] = true
+	}
+	return nil
+}
+
+// Column describes a feature column.
+type Column struct {
+	Name        string `json:"name"`
+	Symbol      string `json:"symbol,omitempty"`
+	Window      string `json:"window,omitempty"`
+	Field       string `json:"field,omitempty"`
+	Aggregation string `json:"aggregation,omitempty"`
+}
+
+// Schema describes feature rows.
+type Schema struct {
+	Version int      `json:"version"`
+	Config  Config   `json:"config"`
+	Columns []Column `json:"columns"`
+}
+
+// Features aggregates market data of configured symbols into rows.
+// It is safe for concurrent use.
+type Features struct {
+	cfg     Config
+	schema  Schema
+	windows []*window
+	// bySymbol are windows of symbol fields.
+	bySymbol map[string][]*window
+	aggs     []func([]float64) float64
+
+	mux sync.Mutex
+}
+
+// New returns features of the config.
+func New(cfg Config) (*Features, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("features.New: %w", err)
+	}
+	maxSize := cfg.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultConfig().MaxSize
+	}
+
+	f := &Features{
+		cfg:      cfg,
+		schema:   Schema{Version: SchemaVersion, Config: cfg},
+		bySymbol: make(map[string][]*window),
+	}
+	f.schema.Columns = append(f.schema.Columns, Column{Name: "timestamp"})
+	for _, a := range cfg.Aggregations {
+		fn, _ := aggregation(a)
+		f.aggs = append(f.aggs, fn)
+	}
+	for _, s := range cfg.Symbols {
+		symbol := strings.ToLower(s)
+		for _, w := range cfg.Windows {
+			d, _ := time.ParseDuration(w)
+			for _, field := range cfg.Fields {
+				win := &window{field: field, duration: d, maxSize: maxSize}
+				f.windows = append(f.windows, win)
+				f.bySymbol[symbol] = append(f.bySymbol[symbol], win)
+				for _, a := range cfg.Aggregations {
+					f.schema.Columns = append(f.schema.Columns, Column{
+						Name:        strings.Join([]string{s, w, field, a}, "-"),
+						Symbol:      s,
+						Window:      w,
+						Field:       field,
+						Aggregation: a,
+					})
+				}
+			}
+		}
+	}
+	return f, nil
+}
+
+// Schema returns description of rows.
+func (f *Features) Schema() Schema {
+	return f.schema
+}
+
+// Header returns column names of rows.
+func (f *Features) Header() []string {
+	res := make([]string, len(f.schema.Columns))
+	for i, c := range f.schema.Columns {
+		res[i] = c.Name
+	}
+	return res
+}
+
+// Add adds BBO or trade values to windows of the message symbol.
+func (f *Features) Add(msg models.ExchangeMessage) {
+	windows := f.bySymbol[strings.ToLower(msg.Symbol)]
+	if len(windows) == 0 {
+		return
+	}
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	switch msg.MsgType {
+	case models.MsgTypeBBO:
+		bbo, _ := msg.BBO()
+		for _, w := range windows {
+			if fn := bboFields[w.field]; fn != nil {
+				w.add(msg.Timestamp, fn(bbo))
+			}
+		}
+	case models.MsgTypeTrade:
+		trade, _ := msg.Trade()
+		for _, w := range windows {
+			if fn := tradeFields[w.field]; fn != nil {
+				if v, ok := fn(trade); ok {
+					w.add(msg.Timestamp, v)
+				}
+			}
+		}
+	}
+}
+
+// Row returns features at ts, the first column is
+// the timestamp in Unix milliseconds.
+func (f *Features) Row(ts time.Time) []string {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	row := make([]string, 0, len(f.schema.Columns))
+	row = append(row, strconv.FormatInt(ts.UnixMilli(), 10))
+	for _, w := range f.windows {
+		values := w.values(ts)
+		for _, fn := range f.aggs {
+			row = append(row, strconv.FormatFloat(fn(values), 'f', -1, 64))
+		}
+	}
+	return row
+}
+
+// window keeps values of a field added within its duration,
+// values before head are evicted.
+type window struct {
+	field    string
+	duration time.Duration
+	maxSize  int
+	ts       []time.Time
+	vals     []float64
+	head     int
+}
+
+func (w *window) add(ts time.Time, v float64) {
+	w.ts = append(w.ts, ts)
+	w.vals = append(w.vals, v)
+	if n := len(w.vals) - w.head - w.maxSize; n > 0 {
+		w.drop(n)
+	}
+}
+
+// values evicts values older than the duration at ts and returns the rest.
+func (w *window) values(ts time.Time) []float64 {
+	n := 0
+	for w.head+n < len(w.ts) && ts.Sub(w.ts[w.head+n]) > w.duration {
+		n++
+	}
+	w.drop(n)
+	return w.vals[w.head:]
+}
+
+// drop evicts n values, compacting storage when most of it is evicted.
+func (w *window) drop(n int) {
+	w.head += n
+	if w.head > len(w.vals)/2 && w.head > 1024 {
+		w.ts = append(w.ts[:0], w.ts[w.head:]...)
+		w.vals = append(w.vals[:0], w.vals[w.head:]...)
+		w.head = 0
+	}
+}
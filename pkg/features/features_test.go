@@ -0,0 +1,125 @@
+package features
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func bbo(ts time.Time, bid, bidSize, ask, askSize float64) models.ExchangeMessage {
+	return models.NewBBOMessage("test", "ETHUSDT", ts, models.BBO{
+		Bid: models.PriceLevel{Price: decimal.NewFromFloat(bid), Size: decimal.NewFromFloat(bidSize)},
+		Ask: models.PriceLevel{Price: decimal.NewFromFloat(ask), Size: decimal.NewFromFloat(askSize)},
+	})
+}
+
+func trade(ts time.Time, side models.OrderSide, size float64) models.ExchangeMessage {
+	return models.NewTradeMessage("test", "ethusdt", ts, models.Trade{
+		Side:  side,
+		Size:  decimal.NewFromFloat(size),
+		Price: decimal.NewFromInt(100),
+	})
+}
+
+func TestFeatures(t *testing.T) {
+	f, err := New(Config{
+		Symbols:      []string{"ethusdt"},
+		Windows:      []string{"1s", "1m"},
+		Fields:       []string{FieldSpread, FieldImbalance, FieldTradeSize},
+		Aggregations: []string{"count", "avg", "stddev", "p50"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := f.Header()
+	if len(header) != 25 || header[0] != "timestamp" || header[1] != "ethusdt-1s-spread-count" ||
+		header[24] != "ethusdt-1m-trade_size-p50" {
+		t.Fatalf("unexpected header %v", header)
+	}
+	if s := f.Schema(); s.Version != SchemaVersion || len(s.Columns) != len(header) || s.Columns[5].Field != FieldImbalance {
+		t.Errorf("unexpected schema %+v", s)
+	}
+
+	t0 := time.UnixMilli(1700000000000)
+	empty := f.Row(t0)
+	if empty[1] != "0" || empty[2] != "NaN" {
+		t.Errorf("unexpected empty row %v", empty)
+	}
+
+	f.Add(bbo(t0, 100, 3, 101, 1))
+	f.Add(bbo(t0.Add(2*time.Second), 100, 1, 102, 1))
+	f.Add(trade(t0.Add(2*time.Second), models.OrderSideBuy, 1))
+	f.Add(trade(t0.Add(2500*time.Millisecond), models.OrderSideSell, 3))
+	// Other symbols are ignored.
+	f.Add(models.NewTradeMessage("test", "btcusdt", t0, models.Trade{Size: decimal.NewFromInt(1)}))
+
+	ts := t0.Add(3 * time.Second)
+	row := f.Row(ts)
+	if len(row) != len(header) {
+		t.Fatalf("expected %d columns, got %d", len(header), len(row))
+	}
+	got := make(map[string]string, len(row))
+	for i, v := range row {
+		got[header[i]] = v
+	}
+
+	want := map[string]float64{
+		"timestamp": float64(ts.UnixMilli()),
+		// The first BBO is out of 1s window.
+		"ethusdt-1s-spread-count":      1,
+		"ethusdt-1s-spread-avg":        2,
+		"ethusdt-1s-imbalance-avg":     0,
+		"ethusdt-1s-trade_size-count":  2,
+		"ethusdt-1s-trade_size-stddev": 1,
+		"ethusdt-1m-spread-count":      2,
+		"ethusdt-1m-spread-avg":        1.5,
+		"ethusdt-1m-spread-stddev":     0.5,
+		"ethusdt-1m-spread-p50":        1.5,
+		"ethusdt-1m-imbalance-avg":     0.25,
+		"ethusdt-1m-trade_size-count":  2,
+		"ethusdt-1m-trade_size-p50":    2,
+	}
+	for name, v := range want {
+		if got[name] != strconv.FormatFloat(v, 'f', -1, 64) {
+			t.Errorf("%s: expected %v, got %s", name, v, got[name])
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	p, err := aggregation("p90")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := p([]float64{5, 1, 4, 2, 3}); v != 4.6 {
+		t.Errorf("expected 4.6, got %v", v)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []func(c *Config){
+		func(c *Config) { c.Windows = []string{"1s", "1s"} },
+		func(c *Config) { c.Windows = []string{"0s"} },
+		func(c *Config) { c.Fields = []string{"volume"} },
+		func(c *Config) { c.Aggregations = []string{"p101"} },
+		func(c *Config) { c.Symbols = nil },
+		func(c *Config) { c.Interval = "soon" },
+	}
+	for i, tt := range tests {
+		c := DefaultConfig()
+		tt(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("%d: expected error for %+v", i, c)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package indicators
+
+import (
+	"time"
+
+	"degen/pkg/models"
+)
+
+type Candle struct {
+	Start  time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
+
+// Candles aggregates prices into candles of the interval. Candles are
+// aligned to the interval and driven by message timestamps, not by the
+// local clock, so replays build the same candles as live trading.
+// Intervals without prices have no candles.
+type Candles struct {
+	interval time.Duration
+	current  Candle
+	open     bool
+}
+
+func NewCandles(interval time.Duration) *Candles {
+	return &Candles{interval: interval}
+}
+
+// AddTrade adds trade price and size at ts.
+// It returns the previous candle when a new one is started.
+func (c *Candles) AddTrade(ts time.Time, price, size float64) (Candle, bool) {
+	closed, ok := c.roll(ts, price)
+	c.current.Volume += size
+	return closed, ok
+}
+
+// AddPrice adds price without volume at ts, e.g. BBO mid.
+// It returns the previous candle when a new one is started.
+func (c *Candles) AddPrice(ts time.Time, price float64) (Candle, bool) {
+	return c.roll(ts, price)
+}
+
+// Add adds BBO mid or trade from market data message.
+func (c *Candles) Add(msg models.ExchangeMessage) (Candle, bool) {
+	if bbo, ok := msg.BBO(); ok {
+		return c.AddPrice(msg.Timestamp, Mid(bbo))
+	}
+	if t, ok := msg.Trade(); ok {
+		return c.AddTrade(msg.Timestamp, t.Price.InexactFloat64(), t.Size.InexactFloat64())
+	}
+	return Candle{}, false
+}
+
+func (c *Candles) roll(ts time.Time, price float64) (Candle, bool) {
+	start := ts.Truncate(c.interval)
+	if c.open && start.After(c.current.Start) {
+		closed := c.current
+		c.current = Candle{Start: start, Open: price, High: price, Low: price, Close: price}
+		return closed, true
+	}
+
+	if !c.open {
+		c.current = Candle{Start: start, Open: price, High: price, Low: price, Close: price}
+		c.open = true
+		return Candle{}, false
+	}
+	// Late prices are added to the current candle.
+	if price > c.current.High {
+		c.current.High = price
+	}
+	if price < c.current.Low {
+		c.current.Low = price
+	}
+	c.current.Close = price
+	return Candle{}, false
+}
+
+// Current returns candle being built.
+func (c *Candles) Current() (Candle, bool) {
+	return c.current, c.open
+}
@@ -0,0 +1,387 @@
+// Package indicators provides technical indicators updated incrementally
+// in O(1) per value. Indicators depend only on values they are fed with,
+// so live trading and replays of the same data give identical results.
+package indicators
+
+import (
+	"math"
+
+	"degen/pkg/models"
+)
+
+// Indicator is updated with values one by one, e.g. prices.
+type Indicator interface {
+	Update(v float64)
+	// Value is the current value, it is meaningful once Ready.
+	Value() float64
+	Ready() bool
+}
+
+// window is a ring buffer of the last n values.
+type window struct {
+	values []float64
+	next   int
+	count  int
+}
+
+func newWindow(n int) window {
+	if n < 1 {
+		n = 1
+	}
+	return window{values: make([]float64, n)}
+}
+
+// push adds value and returns the evicted one, if the window was full.
+func (w *window) push(v float64) (old float64, full bool) {
+	old, full = w.values[w.next], w.count == len(w.values)
+	w.values[w.next] = v
+	w.next = (w.next + 1) % len(w.values)
+	if !full {
+		w.count++
+	}
+	return old, full
+}
+
+func (w *window) full() bool {
+	return w.count == len(w.values)
+}
+
+// SMA is a simple moving average over n values.
+type SMA struct {
+	w   window
+	sum float64
+}
+
+func NewSMA(n int) *SMA {
+	return &SMA{w: newWindow(n)}
+}
+
+func (s *SMA) Update(v float64) {
+	if old, full := s.w.push(v); full {
+		s.sum -= old
+	}
+	s.sum += v
+}
+
+func (s *SMA) Value() float64 {
+	if s.w.count == 0 {
+		return 0
+	}
+	return s.sum / float64(s.w.count)
+}
+
+func (s *SMA) Ready() bool {
+	return s.w.full()
+}
+
+// EMA is an exponential moving average with smoothing 2/(n+1),
+// seeded with the average of the first n values.
+type EMA struct {
+	n     int
+	alpha float64
+	value float64
+	count int
+}
+
+func NewEMA(n int) *EMA {
+	if n < 1 {
+		n = 1
+	}
+	return &EMA{n: n, alpha: 2 / float64(n+1)}
+}
+
+func (e *EMA) Update(v float64) {
+	e.count++
+	if e.count <= e.n {
+		e.value += (v - e.value) / float64(e.count)
+		return
+	}
+	e.value += e.alpha * (v - e.value)
+}
+
+func (e *EMA) Value() float64 {
+	return e.value
+}
+
+func (e *EMA) Ready() bool {
+	return e.count >= e.n
+}
+
+// wilder is Wilder's moving average with smoothing 1/n,
+// seeded with the average of the first n values.
+type wilder struct {
+	n     int
+	value float64
+	count int
+}
+
+func (w *wilder) update(v float64) {
+	w.count++
+	if w.count <= w.n {
+		w.value += (v - w.value) / float64(w.count)
+		return
+	}
+	w.value += (v - w.value) / float64(w.n)
+}
+
+// RSI is a relative strength index over n changes with Wilder's
+// smoothing, from 0 to 100.
+type RSI struct {
+	gain, loss wilder
+	prev       float64
+	seen       bool
+}
+
+func NewRSI(n int) *RSI {
+	if n < 1 {
+		n = 1
+	}
+	return &RSI{gain: wilder{n: n}, loss: wilder{n: n}}
+}
+
+func (r *RSI) Update(v float64) {
+	if !r.seen {
+		r.prev, r.seen = v, true
+		return
+	}
+	change := v - r.prev
+	r.prev = v
+	r.gain.update(math.Max(change, 0))
+	r.loss.update(math.Max(-change, 0))
+}
+
+func (r *RSI) Value() float64 {
+	if r.loss.value == 0 {
+		if r.gain.value == 0 {
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+r.gain.value/r.loss.value)
+}
+
+func (r *RSI) Ready() bool {
+	return r.gain.count >= r.gain.n
+}
+
+// MACD is a difference of fast and slow EMA, signal line is EMA
+// of the difference.
+type MACD struct {
+	fast, slow, signal *EMA
+}
+
+// NewMACD creates MACD, common periods are 12, 26 and 9.
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: NewEMA(fast), slow: NewEMA(slow), signal: NewEMA(signal)}
+}
+
+func (m *MACD) Update(v float64) {
+	m.fast.Update(v)
+	m.slow.Update(v)
+	if m.slow.Ready() {
+		m.signal.Update(m.Value())
+	}
+}
+
+// Value returns MACD line.
+func (m *MACD) Value() float64 {
+	return m.fast.Value() - m.slow.Value()
+}
+
+func (m *MACD) Signal() float64 {
+	return m.signal.Value()
+}
+
+// Histogram returns difference of MACD and signal lines.
+func (m *MACD) Histogram() float64 {
+	return m.Value() - m.Signal()
+}
+
+func (m *MACD) Ready() bool {
+	return m.signal.Ready()
+}
+
+// Bollinger bands are moving average of n values and bands
+// k standard deviations around it.
+type Bollinger struct {
+	w          window
+	k          float64
+	sum, sumSq float64
+}
+
+func NewBollinger(n int, k float64) *Bollinger {
+	return &Bollinger{w: newWindow(n), k: k}
+}
+
+func (b *Bollinger) Update(v float64) {
+	if old, full := b.w.push(v); full {
+		b.sum -= old
+		b.sumSq -= old * old
+	}
+	b.sum += v
+	b.sumSq += v * v
+}
+
+// Value returns the middle band.
+func (b *Bollinger) Value() float64 {
+	if b.w.count == 0 {
+		return 0
+	}
+	return b.sum / float64(b.w.count)
+}
+
+// StdDev returns population standard deviation of the window.
+func (b *Bollinger) StdDev() float64 {
+	if b.w.count == 0 {
+		return 0
+	}
+	mean := b.Value()
+	// Running sums may get slightly negative variance due to rounding.
+	return math.Sqrt(math.Max(b.sumSq/float64(b.w.count)-mean*mean, 0))
+}
+
+func (b *Bollinger) Upper() float64 {
+	return b.Value() + b.k*b.StdDev()
+}
+
+func (b *Bollinger) Lower() float64 {
+	return b.Value() - b.k*b.StdDev()
+}
+
+// PercentB returns position of v relative to the bands: 0 at
+// the lower band and 1 at the upper one.
+func (b *Bollinger) PercentB(v float64) float64 {
+	width := b.Upper() - b.Lower()
+	if width == 0 {
+		return 0.5
+	}
+	return (v - b.Lower()) / width
+}
+
+func (b *Bollinger) Ready() bool {
+	return b.w.full()
+}
+
+// RealizedVol is a standard deviation of log returns over n returns.
+// It is not annualized, multiply it by square root of number of
+// updates per year for that.
+type RealizedVol struct {
+	w          window
+	sum, sumSq float64
+	prev       float64
+}
+
+func NewRealizedVol(n int) *RealizedVol {
+	return &RealizedVol{w: newWindow(n)}
+}
+
+func (r *RealizedVol) Update(v float64) {
+	if v <= 0 {
+		return
+	}
+	if r.prev == 0 {
+		r.prev = v
+		return
+	}
+	ret := math.Log(v / r.prev)
+	r.prev = v
+	if old, full := r.w.push(ret); full {
+		r.sum -= old
+		r.sumSq -= old * old
+	}
+	r.sum += ret
+	r.sumSq += ret * ret
+}
+
+func (r *RealizedVol) Value() float64 {
+	n := float64(r.w.count)
+	if n < 2 {
+		return 0
+	}
+	mean := r.sum / n
+	return math.Sqrt(math.Max((r.sumSq-n*mean*mean)/(n-1), 0))
+}
+
+func (r *RealizedVol) Ready() bool {
+	return r.w.full()
+}
+
+// ATR is an average true range over n candles with Wilder's smoothing.
+type ATR struct {
+	avg       wilder
+	prevClose float64
+}
+
+func NewATR(n int) *ATR {
+	if n < 1 {
+		n = 1
+	}
+	return &ATR{avg: wilder{n: n}}
+}
+
+func (a *ATR) UpdateCandle(c Candle) {
+	tr := c.High - c.Low
+	if a.prevClose != 0 {
+		tr = math.Max(tr, math.Max(math.Abs(c.High-a.prevClose), math.Abs(c.Low-a.prevClose)))
+	}
+	a.prevClose = c.Close
+	a.avg.update(tr)
+}
+
+func (a *ATR) Value() float64 {
+	return a.avg.value
+}
+
+func (a *ATR) Ready() bool {
+	return a.avg.count >= a.avg.n
+}
+
+// VWAP is a volume weighted average price of trades
+// since creation or the last Reset.
+type VWAP struct {
+	notional, volume float64
+}
+
+func NewVWAP() *VWAP {
+	return &VWAP{}
+}
+
+func (v *VWAP) UpdateTrade(price, size float64) {
+	v.notional += price * size
+	v.volume += size
+}
+
+func (v *VWAP) Value() float64 {
+	if v.volume == 0 {
+		return 0
+	}
+	return v.notional / v.volume
+}
+
+func (v *VWAP) Volume() float64 {
+	return v.volume
+}
+
+func (v *VWAP) Ready() bool {
+	return v.volume > 0
+}
+
+// Reset starts a new session.
+func (v *VWAP) Reset() {
+	v.notional, v.volume = 0, 0
+}
+
+// Mid returns middle price of BBO.
+func Mid(bbo models.BBO) float64 {
+	return (bbo.Bid.Price.InexactFloat64() + bbo.Ask.Price.InexactFloat64()) / 2
+}
+
+// Imbalance returns order book imbalance of BBO from -1, when there
+// are only asks, to 1, when there are only bids.
+func Imbalance(bbo models.BBO) float64 {
+	bid, ask := bbo.Bid.Size.InexactFloat64(), bbo.Ask.Size.InexactFloat64()
+	if bid+ask == 0 {
+		return 0
+	}
+	return (bid - ask) / (bid + ask)
+}
@@ -0,0 +1,164 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func prices() []float64 {
+	res := make([]float64, 200)
+	for i := range res {
+		res[i] = 100 + 10*math.Sin(float64(i)/7) + float64(i%5)
+	}
+	return res
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestMovingAverages(t *testing.T) {
+	values := prices()
+	sma, bb, vol := NewSMA(20), NewBollinger(20, 2), NewRealizedVol(20)
+	for i, v := range values {
+		sma.Update(v)
+		bb.Update(v)
+		vol.Update(v)
+		if i < 20 {
+			continue
+		}
+
+		// Naive computations over the window.
+		var sum, sumSq, retSum float64
+		rets := make([]float64, 20)
+		for j := i - 19; j <= i; j++ {
+			sum += values[j]
+			rets[j-i+19] = math.Log(values[j] / values[j-1])
+			retSum += rets[j-i+19]
+		}
+		mean := sum / 20
+		for j := i - 19; j <= i; j++ {
+			sumSq += (values[j] - mean) * (values[j] - mean)
+		}
+		var retVar float64
+		for _, r := range rets {
+			retVar += (r - retSum/20) * (r - retSum/20)
+		}
+
+		if !near(sma.Value(), mean) || !near(bb.Value(), mean) {
+			t.Fatalf("%d: expected average %v, got %v and %v", i, mean, sma.Value(), bb.Value())
+		}
+		if std := math.Sqrt(sumSq / 20); !near(bb.Upper(), mean+2*std) || !near(bb.Lower(), mean-2*std) {
+			t.Fatalf("%d: expected bands around %v by %v, got %v %v", i, mean, 2*std, bb.Lower(), bb.Upper())
+		}
+		if want := math.Sqrt(retVar / 19); math.Abs(vol.Value()-want) > 1e-9 {
+			t.Fatalf("%d: expected volatility %v, got %v", i, want, vol.Value())
+		}
+	}
+	if !sma.Ready() || !bb.Ready() || !vol.Ready() {
+		t.Error("expected indicators to be ready")
+	}
+}
+
+func TestEMA(t *testing.T) {
+	e := NewEMA(3)
+	for _, v := range []float64{1, 2, 3} {
+		e.Update(v)
+	}
+	if !e.Ready() || e.Value() != 2 {
+		t.Fatalf("expected EMA seeded with average 2, got %v", e.Value())
+	}
+	e.Update(6)
+	if e.Value() != 4 {
+		t.Errorf("expected EMA 4, got %v", e.Value())
+	}
+
+	m := NewMACD(12, 26, 9)
+	for i := 0; i < 40; i++ {
+		m.Update(float64(i))
+	}
+	// Rising prices keep fast EMA over slow one.
+	if !m.Ready() || m.Value() <= 0 || !near(m.Histogram(), m.Value()-m.Signal()) {
+		t.Errorf("unexpected MACD %v, signal %v", m.Value(), m.Signal())
+	}
+}
+
+func TestRSI(t *testing.T) {
+	r := NewRSI(14)
+	for i := 0; i <= 14; i++ {
+		r.Update(float64(i))
+	}
+	if !r.Ready() || r.Value() != 100 {
+		t.Errorf("expected RSI 100 on rising prices, got %v", r.Value())
+	}
+
+	r = NewRSI(2)
+	for _, v := range []float64{10, 11, 10, 12} {
+		r.Update(v)
+	}
+	// Seed: gain 0.5, loss 0.5, then gain (0.5+2)/2, loss 0.5/2.
+	if want := 100 - 100/(1+1.25/0.25); !near(r.Value(), want) {
+		t.Errorf("expected RSI %v, got %v", want, r.Value())
+	}
+}
+
+func TestATRAndVWAP(t *testing.T) {
+	a := NewATR(2)
+	a.UpdateCandle(Candle{High: 12, Low: 10, Close: 11})
+	a.UpdateCandle(Candle{High: 15, Low: 13, Close: 14})
+	// True ranges are 2 and 4 including gap from the previous close.
+	if !a.Ready() || a.Value() != 3 {
+		t.Errorf("expected ATR 3, got %v", a.Value())
+	}
+
+	v := NewVWAP()
+	v.UpdateTrade(100, 1)
+	v.UpdateTrade(110, 3)
+	if v.Value() != 107.5 || v.Volume() != 4 {
+		t.Errorf("expected VWAP 107.5, got %v", v.Value())
+	}
+	v.Reset()
+	if v.Ready() {
+		t.Error("expected VWAP to be reset")
+	}
+
+	bbo := models.BBO{
+		Bid: models.PriceLevel{Price: decimal.NewFromInt(99), Size: decimal.NewFromInt(3)},
+		Ask: models.PriceLevel{Price: decimal.NewFromInt(101), Size: decimal.NewFromInt(1)},
+	}
+	if Mid(bbo) != 100 || Imbalance(bbo) != 0.5 {
+		t.Errorf("unexpected mid %v or imbalance %v", Mid(bbo), Imbalance(bbo))
+	}
+}
+
+func TestCandles(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCandles(time.Minute)
+
+	trades := []struct {
+		sec   int
+		price float64
+	}{{5, 10}, {20, 12}, {40, 9}, {59, 11}, {61, 13}, {200, 14}}
+	var closed []Candle
+	for _, tr := range trades {
+		if candle, ok := c.AddTrade(start.Add(time.Duration(tr.sec)*time.Second), tr.price, 1); ok {
+			closed = append(closed, candle)
+		}
+	}
+
+	if len(closed) != 2 {
+		t.Fatalf("expected 2 closed candles, got %+v", closed)
+	}
+	want := Candle{Start: start, Open: 10, High: 12, Low: 9, Close: 11, Volume: 4}
+	if closed[0] != want {
+		t.Errorf("expected %+v, got %+v", want, closed[0])
+	}
+	if cur, ok := c.Current(); !ok || !cur.Start.Equal(start.Add(3*time.Minute)) || cur.Close != 14 {
+		t.Errorf("unexpected current candle %+v", cur)
+	}
+}
@@ -0,0 +1,347 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"degen/pkg/models"
+	"degen/pkg/pnl"
+)
+
+type RecordType string
+
+const (
+	RecordOrderIntent RecordType = "order_intent"
+	RecordOrderAck    RecordType = "order_ack"
+	RecordOrderReject RecordType = "order_reject"
+	RecordOrderUpdate RecordType = "order_update"
+	RecordFill        RecordType = "fill"
+	RecordBalance     RecordType = "balance"
+	RecordPosition    RecordType = "position"
+	RecordSnapshot    RecordType = "snapshot"
+)
+
+// Record is a single journal line. Only payload matching the type is set.
+type Record struct {
+	Seq      uint64     `json:"seq"`
+	Time     time.Time  `json:"time"`
+	Type     RecordType `json:"type"`
+	Exchange string     `json:"exchange,omitempty"`
+	Account  string     `json:"account,omitempty"`
+	Error    string     `json:"error,omitempty"`
+
+	Order       *models.Order          `json:"order,omitempty"`
+	OrderUpdate *models.OrderUpdate    `json:"order_update,omitempty"`
+	Fill        *models.Fill           `json:"fill,omitempty"`
+	Balance     *models.BalanceUpdate  `json:"balance,omitempty"`
+	Position    *models.PositionUpdate `json:"position,omitempty"`
+	Snapshot    *Snapshot              `json:"snapshot,omitempty"`
+}
+
+// AccountState is a copy of account balances and positions.
+type AccountState struct {
+	Exchange  string                     `json:"exchange"`
+	ID        string                     `json:"id"`
+	Balances  map[string]models.Balance  `json:"balances"`
+	Positions map[string]models.Position `json:"positions"`
+}
+
+// Snapshot is a full state, so records before it are not needed for recovery.
+type Snapshot struct {
+	Accounts   []AccountState             `json:"accounts"`
+	Orders     []models.Order             `json:"orders"`
+	PnL        *pnl.Snapshot              `json:"pnl,omitempty"`
+	Strategies map[string]json.RawMessage `json:"strategies,omitempty"`
+}
+
+// DefaultMaxSize is journal size after which it is compacted
+// on the next snapshot.
+const DefaultMaxSize = 64 << 20
+
+// Journal is an append-only file of JSON records, one per line.
+// All methods are safe to call on nil Journal, doing nothing.
+type Journal struct {
+	path    string
+	f       *os.File
+	seq     uint64
+	size    int64
+	maxSize int64
+
+	mux sync.Mutex
+}
+
+// Open opens or creates journal file. Sequence numbers continue
+// from the last record in the file. Truncated last line left by
+// a crash is cut off, so new records start on a line of their own.
+func Open(path string) (*Journal, error) {
+	var seq uint64
+	size, err := read(path, func(rec Record) error {
+		seq = rec.Seq
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("journal.Open failed to open %s: %w", path, err)
+	}
+
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("journal.Open failed to truncate %s: %w", path, err)
+	}
+
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("journal.Open failed to stat %s: %w", path, err)
+	}
+
+	return &Journal{
+		path:    path,
+		f:       f,
+		seq:     seq,
+		size:    st.Size(),
+		maxSize: DefaultMaxSize,
+	}, nil
+}
+
+// SetMaxSize sets journal size after which it is compacted on snapshot.
+func (j *Journal) SetMaxSize(size int64) {
+	if j == nil {
+		return
+	}
+
+	j.mux.Lock()
+	j.maxSize = size
+	j.mux.Unlock()
+}
+
+// Append writes record to the journal setting its sequence number and time.
+// Record is written with a single write call, so it survives process crash.
+func (j *Journal) Append(rec Record) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	return j.appendLocked(rec)
+}
+
+func (j *Journal) appendLocked(rec Record) error {
+	if j.f == nil {
+		return errors.New("journal is closed")
+	}
+
+	j.seq++
+	rec.Seq = j.seq
+	if rec.Time.IsZero() {
+		rec.Time = time.Now().UTC()
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("journal.Append failed to marshal record: %w", err)
+	}
+	b = append(b, '\n')
+
+	n, err := j.f.Write(b)
+	j.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("journal.Append failed to write record: %w", err)
+	}
+
+	return nil
+}
+
+// RecordOrder journals order intent, acknowledgement or rejection.
+func (j *Journal) RecordOrder(tp RecordType, order models.Order, orderErr error) error {
+	rec := Record{
+		Type:     tp,
+		Exchange: order.Exchange,
+		Account:  order.Account,
+		Order:    &order,
+	}
+	if orderErr != nil {
+		rec.Error = orderErr.Error()
+	}
+	return j.Append(rec)
+}
+
+func (j *Journal) RecordOrderUpdate(exchange, account string, upd models.OrderUpdate) error {
+	return j.Append(Record{
+		Type:        RecordOrderUpdate,
+		Exchange:    exchange,
+		Account:     account,
+		OrderUpdate: &upd,
+	})
+}
+
+// RecordFill journals fill and calls apply, which updates state derived
+// from fills, e.g. PnL, so that a concurrent snapshot either includes
+// the fill or precedes its record. apply may be nil and must not use
+// the journal nor wait for locks held by callers of the journal.
+func (j *Journal) RecordFill(exchange, account string, fill models.Fill, apply func()) error {
+	if apply == nil {
+		apply = func() {}
+	}
+	if j == nil {
+		apply()
+		return nil
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	defer apply()
+	return j.appendLocked(Record{
+		Type:     RecordFill,
+		Exchange: exchange,
+		Account:  account,
+		Fill:     &fill,
+	})
+}
+
+func (j *Journal) RecordBalance(exchange, account string, upd models.BalanceUpdate) error {
+	return j.Append(Record{
+		Type:     RecordBalance,
+		Exchange: exchange,
+		Account:  account,
+		Balance:  &upd,
+	})
+}
+
+func (j *Journal) RecordPosition(exchange, account string, upd models.PositionUpdate) error {
+	return j.Append(Record{
+		Type:     RecordPosition,
+		Exchange: exchange,
+		Account:  account,
+		Position: &upd,
+	})
+}
+
+// WriteSnapshot appends snapshot and syncs the file to disk.
+// If journal grew over max size, it is replaced with a new file
+// containing only this snapshot.
+func (j *Journal) WriteSnapshot(snap Snapshot) error {
+	return j.WriteSnapshotFunc(func() Snapshot { return snap })
+}
+
+// WriteSnapshotFunc is WriteSnapshot of the state returned by collect.
+// collect is called under the journal lock, so no record is appended
+// between reading the state and writing the snapshot. collect must not
+// use the journal nor wait for locks held by callers of the journal.
+func (j *Journal) WriteSnapshotFunc(collect func() Snapshot) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	snap := collect()
+
+	if j.maxSize > 0 && j.size > j.maxSize {
+		return j.compactLocked(snap)
+	}
+
+	if err := j.appendLocked(Record{Type: RecordSnapshot, Snapshot: &snap}); err != nil {
+		return err
+	}
+
+	if err := j.f.Sync(); err != nil {
+		return fmt.Errorf("journal.WriteSnapshot failed to sync: %w", err)
+	}
+
+	return nil
+}
+
+// compactLocked writes snapshot to a temporary file and atomically
+// replaces the journal with it.
+func (j *Journal) compactLocked(snap Snapshot) error {
+	tmp := j.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("journal.compact failed to create %s: %w", tmp, err)
+	}
+
+	old := j.f
+	j.f, j.size = f, 0
+	err = j.appendLocked(Record{Type: RecordSnapshot, Snapshot: &snap})
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, j.path)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		j.f = old
+		return fmt.Errorf("journal.compact failed: %w", err)
+	}
+
+	old.Close()
+	return nil
+}
+
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if j.f == nil {
+		return nil
+	}
+
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
+
+// read calls fn for every record in the journal file and returns
+// size of complete lines. Truncated last line, which is left by a crash
+// during write, is ignored.
+func read(path string, fn func(Record) error) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var size int64
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Incomplete line without newline is a torn write.
+			return size, nil
+		}
+		if err != nil {
+			return size, fmt.Errorf("journal failed to read %s: %w", path, err)
+		}
+
+		var rec Record
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return size, fmt.Errorf("journal failed to parse %s:%d: %w", path, line, err)
+		}
+
+		if err := fn(rec); err != nil {
+			return size, err
+		}
+		size += int64(len(b))
+	}
+}
@@ -0,0 +1,235 @@
+package journal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestJournalRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.journal")
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := models.Order{
+		Exchange:      "ex",
+		Account:       "acc",
+		ClientOrderID: "1",
+		Symbol:        "ethusdt",
+		Status:        models.OrderStatusNew,
+	}
+	mustOK(t, j.RecordOrder(RecordOrderIntent, order, nil))
+	mustOK(t, j.RecordBalance("ex", "acc", models.BalanceUpdate{Asset: "usdt", Balance: decimal.NewFromInt(100)}))
+	mustOK(t, j.WriteSnapshot(Snapshot{
+		Orders:     []models.Order{order},
+		Strategies: map[string]json.RawMessage{"monkey": json.RawMessage(`{"a":1}`)},
+		Accounts: []AccountState{{
+			Exchange: "ex",
+			ID:       "acc",
+			Balances: map[string]models.Balance{"usdt": {Balance: decimal.NewFromInt(100)}},
+		}},
+	}))
+
+	order2 := order
+	order2.ClientOrderID = "2"
+	mustOK(t, j.RecordOrder(RecordOrderIntent, order2, nil))
+	mustOK(t, j.RecordOrderUpdate("ex", "acc", models.OrderUpdate{
+		ClientOrderID: "1",
+		Status:        models.OrderStatusFilled,
+	}))
+	mustOK(t, j.RecordFill("ex", "acc", models.Fill{ClientOrderID: "1", TradeID: "t1"}, nil))
+	mustOK(t, j.RecordPosition("ex", "acc", models.PositionUpdate{Symbol: "ethusdt", Amount: decimal.NewFromInt(1)}))
+	mustOK(t, j.Close())
+
+	// Simulating crash in the middle of a write.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"seq":8,"type":"fi`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	st, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if st.LastSeq != 7 {
+		t.Errorf("expected last seq 7, got %d", st.LastSeq)
+	}
+
+	open := st.OpenOrders("ex", "acc")
+	if len(open) != 1 || open[0].ClientOrderID != "2" {
+		t.Errorf("expected only order 2 to be open, got %+v", open)
+	}
+
+	if len(st.Fills) != 1 {
+		t.Errorf("expected 1 fill after snapshot, got %d", len(st.Fills))
+	}
+
+	if string(st.Strategies["monkey"]) != `{"a":1}` {
+		t.Errorf("unexpected strategy state %s", st.Strategies["monkey"])
+	}
+
+	acc, ok := st.Account("ex", "acc")
+	if !ok {
+		t.Fatal("account state not found")
+	}
+	if !acc.Balances["usdt"].Balance.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("unexpected balance %v", acc.Balances["usdt"].Balance)
+	}
+	if !acc.Positions["ethusdt"].Amount.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("unexpected position %v", acc.Positions["ethusdt"].Amount)
+	}
+
+	// Reopened journal continues sequence numbers.
+	j, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if j.seq != 7 {
+		t.Errorf("expected seq 7 after reopen, got %d", j.seq)
+	}
+}
+
+func TestJournalRestartAfterTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.journal")
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustOK(t, j.RecordFill("ex", "acc", models.Fill{TradeID: "t1"}, nil))
+	mustOK(t, j.Close())
+
+	// Simulating crash in the middle of a write.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"seq":2,"type":"fi`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for _, trade := range []string{"t2", "t3"} {
+		j, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustOK(t, j.RecordFill("ex", "acc", models.Fill{TradeID: trade}, nil))
+		mustOK(t, j.Close())
+
+		st, err := Load(path)
+		if err != nil {
+			t.Fatalf("failed to load after restart with %s: %v", trade, err)
+		}
+		last := st.Fills[len(st.Fills)-1].Fill
+		if last.TradeID != trade {
+			t.Errorf("expected last fill %s, got %s", trade, last.TradeID)
+		}
+	}
+
+	st, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Fills) != 3 || st.LastSeq != 3 {
+		t.Errorf("expected 3 fills up to seq 3, got %d fills up to seq %d", len(st.Fills), st.LastSeq)
+	}
+}
+
+func TestSnapshotDuringFill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.journal")
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fills are counted by apply and the count is snapshotted. Snapshot
+	// requested while the fill is being applied must either include it
+	// or be written before its record.
+	var count int
+	snapped := make(chan error)
+	mustOK(t, j.RecordFill("ex", "acc", models.Fill{TradeID: "t1"}, func() {
+		go func() {
+			snapped <- j.WriteSnapshotFunc(func() Snapshot {
+				return Snapshot{Strategies: map[string]json.RawMessage{
+					"count": json.RawMessage(strconv.Itoa(count)),
+				}}
+			})
+		}()
+		time.Sleep(10 * time.Millisecond)
+		count++
+	}))
+	mustOK(t, <-snapped)
+	mustOK(t, j.Close())
+
+	st, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	mustOK(t, json.Unmarshal(st.Strategies["count"], &n))
+	if n+len(st.Fills) != 1 {
+		t.Errorf("expected 1 fill, got %d in snapshot and %d after it", n, len(st.Fills))
+	}
+}
+
+func TestJournalCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.journal")
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	j.SetMaxSize(1)
+
+	for i := 0; i < 10; i++ {
+		mustOK(t, j.RecordBalance("ex", "acc", models.BalanceUpdate{Asset: "usdt", Balance: decimal.NewFromInt(int64(i))}))
+	}
+	mustOK(t, j.WriteSnapshot(Snapshot{}))
+	mustOK(t, j.RecordFill("ex", "acc", models.Fill{TradeID: "t1"}, nil))
+
+	var types []RecordType
+	_, err = read(path, func(rec Record) error {
+		types = append(types, rec.Type)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(types) != 2 || types[0] != RecordSnapshot || types[1] != RecordFill {
+		t.Errorf("expected snapshot and fill after compaction, got %v", types)
+	}
+}
+
+func TestNilJournal(t *testing.T) {
+	var j *Journal
+	mustOK(t, j.RecordFill("ex", "acc", models.Fill{}, nil))
+	mustOK(t, j.WriteSnapshot(Snapshot{}))
+	mustOK(t, j.Close())
+}
+
+func mustOK(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,206 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+
+	"degen/pkg/models"
+	"degen/pkg/pnl"
+)
+
+type accountKey struct {
+	exchange, id string
+}
+
+// State is a state rebuilt from the journal: the last snapshot
+// with records after it applied on top.
+type State struct {
+	Accounts   map[accountKey]*AccountState
+	Orders     map[string]models.Order
+	PnL        *pnl.Snapshot
+	Strategies map[string]json.RawMessage
+	// Fills are fills journaled after the last snapshot,
+	// they are not included into PnL snapshot yet.
+	Fills []Record
+
+	LastSeq uint64
+}
+
+func newState() *State {
+	return &State{
+		Accounts:   make(map[accountKey]*AccountState),
+		Orders:     make(map[string]models.Order),
+		Strategies: make(map[string]json.RawMessage),
+	}
+}
+
+// Load rebuilds state from journal file. Missing file results in empty state.
+func Load(path string) (*State, error) {
+	st := newState()
+	_, err := read(path, func(rec Record) error {
+		st.apply(rec)
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return st, nil
+}
+
+// Account returns saved state of the account.
+func (s *State) Account(exchange, id string) (AccountState, bool) {
+	acc, ok := s.Accounts[accountKey{exchange, id}]
+	if !ok {
+		return AccountState{}, false
+	}
+	return *acc, true
+}
+
+// OpenOrders returns orders of the account which were not finished
+// according to the journal, sorted by creation time.
+func (s *State) OpenOrders(exchange, account string) []models.Order {
+	var orders []models.Order
+	for _, o := range s.Orders {
+		if o.Exchange == exchange && o.Account == account {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+	})
+	return orders
+}
+
+func (s *State) account(exchange, id string) *AccountState {
+	k := accountKey{exchange, id}
+	acc, ok := s.Accounts[k]
+	if !ok {
+		acc = &AccountState{
+			Exchange:  exchange,
+			ID:        id,
+			Balances:  make(map[string]models.Balance),
+			Positions: make(map[string]models.Position),
+		}
+		s.Accounts[k] = acc
+	}
+	return acc
+}
+
+func orderKey(clientOrderID, exchangeOrderID string) string {
+	if clientOrderID != "" {
+		return clientOrderID
+	}
+	return exchangeOrderID
+}
+
+func (s *State) apply(rec Record) {
+	s.LastSeq = rec.Seq
+
+	switch rec.Type {
+	case RecordSnapshot:
+		if rec.Snapshot == nil {
+			return
+		}
+		*s = *newState()
+		s.LastSeq = rec.Seq
+		for _, acc := range rec.Snapshot.Accounts {
+			a := s.account(acc.Exchange, acc.ID)
+			for asset, b := range acc.Balances {
+				a.Balances[asset] = b
+			}
+			for symbol, p := range acc.Positions {
+				a.Positions[symbol] = p
+			}
+		}
+		for _, o := range rec.Snapshot.Orders {
+			s.Orders[orderKey(o.ClientOrderID, o.ExchangeOrderID)] = o
+		}
+		for name, raw := range rec.Snapshot.Strategies {
+			s.Strategies[name] = raw
+		}
+		s.PnL = rec.Snapshot.PnL
+	case RecordOrderIntent, RecordOrderAck:
+		if rec.Order == nil {
+			return
+		}
+		k := orderKey(rec.Order.ClientOrderID, rec.Order.ExchangeOrderID)
+		if rec.Order.Status.IsFinal() {
+			delete(s.Orders, k)
+			return
+		}
+		s.Orders[k] = *rec.Order
+	case RecordOrderReject:
+		if rec.Order != nil {
+			delete(s.Orders, orderKey(rec.Order.ClientOrderID, rec.Order.ExchangeOrderID))
+		}
+	case RecordOrderUpdate:
+		upd := rec.OrderUpdate
+		if upd == nil {
+			return
+		}
+		k := orderKey(upd.ClientOrderID, upd.ExchangeOrderID)
+		if upd.Status.IsFinal() {
+			delete(s.Orders, k)
+			return
+		}
+		o, ok := s.Orders[k]
+		if !ok {
+			o = models.Order{
+				Exchange:      rec.Exchange,
+				Account:       rec.Account,
+				ClientOrderID: upd.ClientOrderID,
+				CreatedAt:     upd.UpdatedAt,
+				Symbol:        upd.Symbol,
+				Side:          upd.Side,
+			}
+		}
+		o.ExchangeOrderID = upd.ExchangeOrderID
+		o.Status = upd.Status
+		o.UpdatedAt = upd.UpdatedAt
+		o.FilledSize = upd.FilledSize
+		o.AveragePrice = upd.AveragePrice
+		s.Orders[k] = o
+	case RecordFill:
+		if rec.Fill != nil {
+			s.Fills = append(s.Fills, rec)
+		}
+	case RecordBalance:
+		if rec.Balance != nil {
+			s.account(rec.Exchange, rec.Account).Balances[rec.Balance.Asset] = models.Balance{
+				Balance:   rec.Balance.Balance,
+				UpdatedAt: rec.Time,
+			}
+		}
+	case RecordPosition:
+		if rec.Position != nil {
+			s.account(rec.Exchange, rec.Account).Positions[rec.Position.Symbol] = models.Position{
+				Amount:     rec.Position.Amount,
+				EntryPrice: rec.Position.EntryPrice,
+				UpdatedAt:  rec.Time,
+			}
+		}
+	}
+}
+
+// NewAccountState copies account balances and positions for a snapshot.
+func NewAccountState(acc *models.Account) AccountState {
+	return AccountState{
+		Exchange:  acc.Exchange(),
+		ID:        acc.ID(),
+		Balances:  acc.Balances(),
+		Positions: acc.Positions(),
+	}
+}
+
+// Restore applies saved balances and positions to the account.
+func (s AccountState) Restore(acc *models.Account) {
+	for asset, b := range s.Balances {
+		acc.UpdateBalance(asset, b.Balance, b.UpdatedAt)
+	}
+	for symbol, p := range s.Positions {
+		acc.UpdatePosition(symbol, p.Amount, p.EntryPrice, p.UpdatedAt)
+	}
+}
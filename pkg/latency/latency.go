@@ -0,0 +1,155 @@
+// Package latency records latencies of messages passing the pipeline
+// and reports their percentiles per stream and per stage:
+//
+//	exchange event -> wire -> decode -> dispatch -> strategy -> order ack
+package latency
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"degen/pkg/models"
+)
+
+type Stage string
+
+const (
+	// StageWire is from exchange event time to local receive time.
+	StageWire Stage = "wire"
+	// StageDecode is from receive time to message being decoded.
+	StageDecode Stage = "decode"
+	// StageDispatch is from decoding to event bus delivering message.
+	StageDispatch Stage = "dispatch"
+	// StageStrategy is from delivery to strategy processing the message.
+	StageStrategy Stage = "strategy"
+	// StageOrderAck is from placing an order to exchange acknowledging it.
+	StageOrderAck Stage = "order_ack"
+)
+
+// Stream names messages of the type in the exchange symbol,
+// e.g. binance/ethusdt/bbo.
+func Stream(msg models.ExchangeMessage) string {
+	return StreamOf(msg.Exchange, msg.Symbol, msg.MsgType.String())
+}
+
+func StreamOf(exchange, symbol, kind string) string {
+	return exchange + "/" + symbol + "/" + kind
+}
+
+// Stats are percentiles of recent samples of the stream stage.
+type Stats struct {
+	Stream string        `json:"stream"`
+	Stage  Stage         `json:"stage"`
+	Count  uint64        `json:"count"`
+	P50    time.Duration `json:"p50"`
+	P90    time.Duration `json:"p90"`
+	P99    time.Duration `json:"p99"`
+	Max    time.Duration `json:"max"`
+}
+
+type key struct {
+	stream string
+	stage  Stage
+}
+
+// window is a ring buffer of recent samples.
+type window struct {
+	samples []time.Duration
+	next    int
+	count   uint64
+}
+
+// Recorder keeps the last size samples of every stream stage.
+type Recorder struct {
+	size    int
+	windows map[key]*window
+	mux     sync.Mutex
+}
+
+func NewRecorder(size int) *Recorder {
+	if size <= 0 {
+		size = 1000
+	}
+	return &Recorder{
+		size:    size,
+		windows: make(map[key]*window),
+	}
+}
+
+// Record adds a sample, negative ones caused by clock skew are kept
+// as they are, so skew is visible rather than hidden.
+func (r *Recorder) Record(stream string, stage Stage, d time.Duration) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	k := key{stream, stage}
+	w, ok := r.windows[k]
+	if !ok {
+		w = &window{samples: make([]time.Duration, 0, r.size)}
+		r.windows[k] = w
+	}
+
+	if len(w.samples) < r.size {
+		w.samples = append(w.samples, d)
+	} else {
+		w.samples[w.next] = d
+	}
+	w.next = (w.next + 1) % r.size
+	w.count++
+}
+
+// ObserveMessage records wire, decode and dispatch stages
+// of the message, skipping ones with unknown times.
+func (r *Recorder) ObserveMessage(msg models.ExchangeMessage) {
+	stream := Stream(msg)
+	record := func(stage Stage, from, to time.Time) {
+		if !from.IsZero() && !to.IsZero() {
+			r.Record(stream, stage, to.Sub(from))
+		}
+	}
+	record(StageWire, msg.Timestamp, msg.ReceivedAt)
+	record(StageDecode, msg.ReceivedAt, msg.DecodedAt)
+	record(StageDispatch, msg.DecodedAt, msg.DispatchedAt)
+}
+
+// Stats returns stats sorted by stream and stage.
+func (r *Recorder) Stats() []Stats {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	res := make([]Stats, 0, len(r.windows))
+	for k, w := range r.windows {
+		sorted := append([]time.Duration(nil), w.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		res = append(res, Stats{
+			Stream: k.stream,
+			Stage:  k.stage,
+			Count:  w.count,
+			P50:    percentile(sorted, 0.5),
+			P90:    percentile(sorted, 0.9),
+			P99:    percentile(sorted, 0.99),
+			Max:    sorted[len(sorted)-1],
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Stream != res[j].Stream {
+			return res[i].Stream < res[j].Stream
+		}
+		return res[i].Stage < res[j].Stage
+	})
+	return res
+}
+
+// percentile uses the nearest rank method on sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
@@ -0,0 +1,62 @@
+package latency
+
+import (
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(100)
+	// The first 50 samples are pushed out of the window.
+	for i := 1; i <= 150; i++ {
+		r.Record("s", StageStrategy, time.Duration(i)*time.Millisecond)
+	}
+
+	stats := r.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 stats, got %d", len(stats))
+	}
+	st := stats[0]
+	if st.Count != 150 {
+		t.Errorf("expected count 150, got %d", st.Count)
+	}
+	if st.P50 != 100*time.Millisecond || st.P90 != 140*time.Millisecond ||
+		st.P99 != 149*time.Millisecond || st.Max != 150*time.Millisecond {
+		t.Errorf("unexpected percentiles %+v", st)
+	}
+}
+
+func TestObserveMessage(t *testing.T) {
+	now := time.Now().UTC()
+	msg := models.NewBBOMessage("binance", "ethusdt", now, models.BBO{})
+	msg.ReceivedAt = now.Add(10 * time.Millisecond)
+	msg.DecodedAt = now.Add(11 * time.Millisecond)
+	msg.DispatchedAt = now.Add(15 * time.Millisecond)
+
+	r := NewRecorder(10)
+	r.ObserveMessage(msg)
+
+	want := map[Stage]time.Duration{
+		StageDecode:   time.Millisecond,
+		StageDispatch: 4 * time.Millisecond,
+		StageWire:     10 * time.Millisecond,
+	}
+	stats := r.Stats()
+	if len(stats) != len(want) {
+		t.Fatalf("expected %d stats, got %+v", len(want), stats)
+	}
+	for _, st := range stats {
+		if st.Stream != "binance/ethusdt/bbo" || st.P50 != want[st.Stage] {
+			t.Errorf("unexpected stats %+v", st)
+		}
+	}
+
+	// Simulated messages have no receive time, so nothing is recorded.
+	r = NewRecorder(10)
+	r.ObserveMessage(models.NewBBOMessage("paper", "ethusdt", now, models.BBO{}))
+	if stats := r.Stats(); len(stats) != 0 {
+		t.Errorf("expected no stats, got %+v", stats)
+	}
+}
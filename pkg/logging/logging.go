@@ -0,0 +1,314 @@
+// Package logging is a structured leveled logger in the spirit of log/slog.
+// Loggers are created per component and write messages with key-value
+// fields as text or JSON lines:
+//
+//	var logger = logging.New("oms")
+//	logger.Info("order placed", "exchange", "binance", "order_id", id)
+//
+// Levels and output are configured globally with Configure,
+// loggers created before it follow the new configuration.
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+}
+
+// ParseLevel parses level name: debug, info, warn or error.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(l.String())), nil
+}
+
+func (l *Level) UnmarshalText(b []byte) error {
+	level, err := ParseLevel(string(b))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// Options configure all loggers.
+type Options struct {
+	// Output defaults to stderr.
+	Output io.Writer
+	// JSON switches output from text to JSON lines.
+	JSON bool
+	// Level is the minimal level of messages written.
+	Level Level
+	// Components override level of particular components.
+	Components map[string]Level
+}
+
+var (
+	current  atomic.Pointer[Options]
+	writeMux sync.Mutex
+)
+
+func init() {
+	Configure(Options{})
+}
+
+// Configure replaces configuration of all loggers. Messages of
+// the standard log package are redirected to "log" component.
+func Configure(opts Options) {
+	if opts.Output == nil {
+		opts.Output = os.Stderr
+	}
+	current.Store(&opts)
+
+	log.SetFlags(0)
+	log.SetOutput(stdWriter{New("log")})
+}
+
+// stdWriter writes standard logger output as info messages.
+type stdWriter struct {
+	l *Logger
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	w.l.Info(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// Logger writes messages of a component with bound fields.
+type Logger struct {
+	component string
+	fields    []interface{}
+	sampler   *sampler
+}
+
+func New(component string) *Logger {
+	return &Logger{component: component}
+}
+
+// With returns logger adding key-value pairs to every message.
+func (l *Logger) With(args ...interface{}) *Logger {
+	c := *l
+	c.fields = append(append([]interface{}(nil), l.fields...), args...)
+	return &c
+}
+
+// Sampled returns logger writing only the first and then every n-th
+// message with the same level and text, e.g. for every market data update.
+func (l *Logger) Sampled(n uint64) *Logger {
+	c := *l
+	c.sampler = &sampler{n: n}
+	return &c
+}
+
+type sampler struct {
+	n      uint64
+	counts sync.Map
+}
+
+func (s *sampler) allow(level Level, msg string) bool {
+	if s.n <= 1 {
+		return true
+	}
+	k := level.String() + msg
+	v, ok := s.counts.Load(k)
+	if !ok {
+		v, _ = s.counts.LoadOrStore(k, new(uint64))
+	}
+	return (atomic.AddUint64(v.(*uint64), 1)-1)%s.n == 0
+}
+
+// Enabled tells if messages of the level are written, so expensive
+// fields are not computed in vain.
+func (l *Logger) Enabled(level Level) bool {
+	opts := current.Load()
+	minLevel := opts.Level
+	if cl, ok := opts.Components[l.component]; ok {
+		minLevel = cl
+	}
+	return level >= minLevel
+}
+
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.Log(LevelDebug, msg, args...)
+}
+
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.Log(LevelInfo, msg, args...)
+}
+
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.Log(LevelWarn, msg, args...)
+}
+
+func (l *Logger) Error(msg string, args ...interface{}) {
+	l.Log(LevelError, msg, args...)
+}
+
+// Log writes message with alternating keys and values.
+func (l *Logger) Log(level Level, msg string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	if l.sampler != nil && !l.sampler.allow(level, msg) {
+		return
+	}
+
+	opts := current.Load()
+	fields := args
+	if len(l.fields) > 0 {
+		fields = append(append([]interface{}(nil), l.fields...), args...)
+	}
+
+	var buf bytes.Buffer
+	now := time.Now().UTC()
+	if opts.JSON {
+		writeJSON(&buf, now, level, l.component, msg, fields)
+	} else {
+		writeText(&buf, now, level, l.component, msg, fields)
+	}
+
+	writeMux.Lock()
+	defer writeMux.Unlock()
+	_, _ = opts.Output.Write(buf.Bytes())
+}
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// pairs calls fn for every key-value pair of args.
+func pairs(args []interface{}, fn func(key string, value interface{})) {
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok || i+1 == len(args) {
+			// Same as slog, value without key is reported with !BADKEY.
+			fn("!BADKEY", args[i])
+			i--
+			continue
+		}
+		fn(key, args[i+1])
+	}
+}
+
+func writeText(buf *bytes.Buffer, t time.Time, level Level, component, msg string, args []interface{}) {
+	buf.WriteString(t.Format(timeFormat))
+	buf.WriteByte(' ')
+	buf.WriteString(level.String())
+	buf.WriteByte(' ')
+	if component != "" {
+		buf.WriteString(component)
+		buf.WriteString(": ")
+	}
+	buf.WriteString(msg)
+	pairs(args, func(key string, value interface{}) {
+		buf.WriteByte(' ')
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(quoteText(textValue(value)))
+	})
+	buf.WriteByte('\n')
+}
+
+func textValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.UTC().Format(timeFormat)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+func quoteText(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r == ' ' || r == '=' || r == '"' || r < 0x20 || r == utf8.RuneError {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+func writeJSON(buf *bytes.Buffer, t time.Time, level Level, component, msg string, args []interface{}) {
+	buf.WriteString(`{"time":"`)
+	buf.WriteString(t.Format(timeFormat))
+	buf.WriteString(`","level":"`)
+	buf.WriteString(level.String())
+	buf.WriteByte('"')
+	if component != "" {
+		writeJSONField(buf, "component", component)
+	}
+	writeJSONField(buf, "msg", msg)
+	pairs(args, func(key string, value interface{}) {
+		writeJSONField(buf, key, value)
+	})
+	buf.WriteString("}\n")
+}
+
+func writeJSONField(buf *bytes.Buffer, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	buf.WriteByte(',')
+	buf.Write(k)
+	buf.WriteByte(':')
+
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case time.Duration:
+		value = v.String()
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(b)
+}
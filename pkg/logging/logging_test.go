@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	Configure(Options{
+		Output:     &buf,
+		Level:      LevelInfo,
+		Components: map[string]Level{"feed": LevelWarn},
+	})
+	defer Configure(Options{})
+
+	l := New("oms").With("exchange", "binance")
+	l.Debug("not written")
+	l.Info("order placed", "order_id", "abc", "err", errors.New("oh no"), "dangling")
+	New("feed").Info("not written")
+
+	line := buf.String()
+	if strings.Count(line, "\n") != 1 {
+		t.Fatalf("expected 1 line, got %q", line)
+	}
+	expected := ` INFO oms: order placed exchange=binance order_id=abc err="oh no" !BADKEY=dangling` + "\n"
+	if !strings.HasSuffix(line, expected) {
+		t.Errorf("expected %q suffix, got %q", expected, line)
+	}
+}
+
+func TestJSONAndSampling(t *testing.T) {
+	var buf bytes.Buffer
+	Configure(Options{Output: &buf, JSON: true, Level: LevelDebug})
+	defer Configure(Options{})
+
+	l := New("feed").Sampled(3)
+	for i := 0; i < 7; i++ {
+		l.Debug("bbo", "symbol", "ethusdt", "i", i, "lag", time.Millisecond)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 sampled lines, got %d", len(lines))
+	}
+
+	var rec map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["level"] != "DEBUG" || rec["component"] != "feed" || rec["msg"] != "bbo" ||
+		rec["symbol"] != "ethusdt" || rec["i"] != float64(3) || rec["lag"] != "1ms" {
+		t.Errorf("unexpected record %v", rec)
+	}
+}
@@ -0,0 +1,343 @@
+// Package metrics is a minimal implementation of Prometheus counters,
+// gauges and histograms exposed in the text exposition format:
+// https://prometheus.io/docs/instrumenting/exposition_formats/
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"degen/pkg/logging"
+)
+
+// DefaultBuckets are latency buckets in seconds, from 1ms to 10s.
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+type series struct {
+	labels []string
+	value  float64
+	// Histogram bucket counts, not cumulative, and count of observations.
+	buckets []uint64
+	count   uint64
+}
+
+// vec is a metric with a set of series identified by label values.
+type vec struct {
+	name    string
+	help    string
+	typ     metricType
+	labels  []string
+	buckets []float64
+
+	series map[string]*series
+	mux    sync.Mutex
+}
+
+func newVec(name, help string, typ metricType, labels []string) *vec {
+	return &vec{
+		name:   name,
+		help:   help,
+		typ:    typ,
+		labels: labels,
+		series: make(map[string]*series),
+	}
+}
+
+// get returns series with given label values, caller must hold the lock.
+func (v *vec) get(values []string) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+
+	k := strings.Join(values, "\xff")
+	s, ok := v.series[k]
+	if !ok {
+		s = &series{labels: append([]string(nil), values...)}
+		if v.typ == typeHistogram {
+			s.buckets = make([]uint64, len(v.buckets))
+		}
+		v.series[k] = s
+	}
+	return s
+}
+
+func (v *vec) reset() {
+	v.mux.Lock()
+	v.series = make(map[string]*series)
+	v.mux.Unlock()
+}
+
+func (v *vec) write(w io.Writer) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	if len(v.series) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.typ)
+
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := v.series[k]
+		if v.typ != typeHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labels, s.labels, "", ""), formatFloat(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, le := range v.buckets {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n",
+				v.name, formatLabels(v.labels, s.labels, "le", formatFloat(le)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, s.labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, formatLabels(v.labels, s.labels, "", ""), formatFloat(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, formatLabels(v.labels, s.labels, "", ""), s.count)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, labelEscaper.Replace(values[i]))
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extraName, extraValue)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	v *vec
+}
+
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *Counter) Add(delta float64, labels ...string) {
+	c.v.mux.Lock()
+	c.v.get(labels).value += delta
+	c.v.mux.Unlock()
+}
+
+// Gauge is a value which can go up and down.
+type Gauge struct {
+	v *vec
+}
+
+func (g *Gauge) Set(value float64, labels ...string) {
+	g.v.mux.Lock()
+	g.v.get(labels).value = value
+	g.v.mux.Unlock()
+}
+
+// Reset removes all series, e.g. before setting gauges of
+// positions, so closed ones are not reported anymore.
+func (g *Gauge) Reset() {
+	g.v.reset()
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	v *vec
+}
+
+func (h *Histogram) Observe(value float64, labels ...string) {
+	h.v.mux.Lock()
+	defer h.v.mux.Unlock()
+
+	s := h.v.get(labels)
+	s.value += value
+	s.count++
+	for i, le := range h.v.buckets {
+		if value <= le {
+			s.buckets[i]++
+			break
+		}
+	}
+}
+
+// Registry is a set of metrics written together.
+type Registry struct {
+	metrics    []*vec
+	names      map[string]bool
+	collectors []func()
+	mux        sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// Default is a registry package level constructors register metrics in.
+var Default = NewRegistry()
+
+func (r *Registry) register(v *vec) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.names[v.name] {
+		panic("metrics: duplicate metric " + v.name)
+	}
+	r.names[v.name] = true
+	r.metrics = append(r.metrics, v)
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	v := newVec(name, help, typeCounter, labels)
+	r.register(v)
+	return &Counter{v}
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	v := newVec(name, help, typeGauge, labels)
+	r.register(v)
+	return &Gauge{v}
+}
+
+// NewHistogram creates histogram with sorted upper bounds of buckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	v := newVec(name, help, typeHistogram, labels)
+	v.buckets = buckets
+	r.register(v)
+	return &Histogram{v}
+}
+
+// OnCollect adds a function called before metrics are written,
+// e.g. to set gauges from the current state.
+func (r *Registry) OnCollect(fn func()) {
+	r.mux.Lock()
+	r.collectors = append(r.collectors, fn)
+	r.mux.Unlock()
+}
+
+// WriteTo writes all metrics in the text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mux.Lock()
+	collectors := append([]func(){}, r.collectors...)
+	metrics := append([]*vec{}, r.metrics...)
+	r.mux.Unlock()
+
+	for _, fn := range collectors {
+		fn()
+	}
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, v := range metrics {
+		v.write(cw)
+	}
+	err := cw.w.Flush()
+	return cw.n, err
+}
+
+// ServeHTTP serves metrics for Prometheus to scrape.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = r.WriteTo(w)
+}
+
+// ListenAndServe serves metrics on addr at /metrics until context is canceled.
+func (r *Registry) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("metrics.ListenAndServe failed to listen: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r)
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+
+	logging.New("metrics").Info("metrics are served", "url", "http://"+l.Addr().String()+"/metrics")
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("metrics.ListenAndServe: %w", err)
+	}
+	return nil
+}
+
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+func OnCollect(fn func()) {
+	Default.OnCollect(fn)
+}
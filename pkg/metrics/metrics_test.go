@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_messages_total", "Messages received.", "stream")
+	g := r.NewGauge("test_balance", "Balance.")
+	h := r.NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+
+	c.Inc("trade")
+	c.Add(2, `book"ticker`)
+	r.OnCollect(func() { g.Set(1.5) })
+	h.Observe(0.05, "place")
+	h.Observe(0.5, "place")
+	h.Observe(5, "place")
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# HELP test_messages_total Messages received.
+# TYPE test_messages_total counter
+test_messages_total{stream="book\"ticker"} 2
+test_messages_total{stream="trade"} 1
+# HELP test_balance Balance.
+# TYPE test_balance gauge
+test_balance 1.5
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{op="place",le="0.1"} 1
+test_latency_seconds_bucket{op="place",le="1"} 2
+test_latency_seconds_bucket{op="place",le="+Inf"} 3
+test_latency_seconds_sum{op="place"} 5.55
+test_latency_seconds_count{op="place"} 3
+`
+	if b.String() != expected {
+		t.Errorf("unexpected output:\n%s", b.String())
+	}
+}
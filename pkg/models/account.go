@@ -12,6 +12,8 @@ type Account struct {
 	exchange  string
 	balances  map[string]Balance
 	positions map[string]Position
+	// funding is accumulated funding by symbol, positive when received.
+	funding map[string]decimal.Decimal
 
 	mux sync.RWMutex
 }
@@ -22,9 +24,18 @@ func NewAccount(id, exchange string) *Account {
 		exchange:  exchange,
 		balances:  make(map[string]Balance),
 		positions: make(map[string]Position),
+		funding:   make(map[string]decimal.Decimal),
 	}
 }
 
+func (a *Account) ID() string {
+	return a.id
+}
+
+func (a *Account) Exchange() string {
+	return a.exchange
+}
+
 type Balance struct {
 	Balance   decimal.Decimal
 	UpdatedAt time.Time
@@ -83,3 +94,44 @@ func (a *Account) GetPosition(symbol string) Position {
 
 	return a.positions[symbol]
 }
+
+// Balances returns copy of all asset balances.
+func (a *Account) Balances() map[string]Balance {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+
+	res := make(map[string]Balance, len(a.balances))
+	for k, v := range a.balances {
+		res[k] = v
+	}
+	return res
+}
+
+// Positions returns copy of all positions.
+func (a *Account) Positions() map[string]Position {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+
+	res := make(map[string]Position, len(a.positions))
+	for k, v := range a.positions {
+		res[k] = v
+	}
+	return res
+}
+
+// AddFunding adds funding received (positive) or paid (negative)
+// on the symbol position.
+func (a *Account) AddFunding(symbol string, amount decimal.Decimal) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	a.funding[symbol] = a.funding[symbol].Add(amount)
+}
+
+// GetFunding returns funding accumulated on the symbol position.
+func (a *Account) GetFunding(symbol string) decimal.Decimal {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+
+	return a.funding[symbol]
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+func (t MsgType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *MsgType) UnmarshalText(b []byte) error {
+	for i, name := range msgTypeNames {
+		if name == string(b) {
+			*t = MsgType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown message type %q", string(b))
+}
+
+// messageJSON is a wire format of ExchangeMessage used for recording
+// market data and replaying it in backtests.
+type messageJSON struct {
+	Exchange  string    `json:"exchange"`
+	Account   string    `json:"account,omitempty"`
+	Symbol    string    `json:"symbol,omitempty"`
+	Timestamp time.Time `json:"ts"`
+	// Optional times are pointers, so they are omitted when zero.
+	TransactTime *time.Time `json:"transact_ts,omitempty"`
+	ReceivedAt   *time.Time `json:"received_ts,omitempty"`
+	MsgType      MsgType    `json:"type"`
+
+	BBO      *BBO            `json:"bbo,omitempty"`
+	Trade    *Trade          `json:"trade,omitempty"`
+	Order    *OrderUpdate    `json:"order,omitempty"`
+	Balance  *BalanceUpdate  `json:"balance,omitempty"`
+	Position *PositionUpdate `json:"position,omitempty"`
+	Fill     *Fill           `json:"fill,omitempty"`
+	Funding  *Funding        `json:"funding,omitempty"`
+}
+
+func (m ExchangeMessage) MarshalJSON() ([]byte, error) {
+	v := messageJSON{
+		Exchange:  m.Exchange,
+		Account:   m.Account,
+		Symbol:    m.Symbol,
+		Timestamp: m.Timestamp,
+		MsgType:   m.MsgType,
+	}
+	if !m.TransactTime.IsZero() {
+		v.TransactTime = &m.TransactTime
+	}
+	if !m.ReceivedAt.IsZero() {
+		v.ReceivedAt = &m.ReceivedAt
+	}
+
+	switch m.MsgType {
+	case MsgTypeBBO:
+		v.BBO = &m.bbo
+	case MsgTypeTrade:
+		v.Trade = &m.trade
+	case MsgTypeOrderStatus:
+		v.Order = m.order
+	case MsgTypeBalanceUpdate:
+		v.Balance = m.balance
+	case MsgTypePositionUpdate:
+		v.Position = m.position
+	case MsgTypeFill:
+		v.Fill = m.fill
+	case MsgTypeFunding:
+		v.Funding = m.funding
+	}
+
+	return json.Marshal(v)
+}
+
+func (m *ExchangeMessage) UnmarshalJSON(b []byte) error {
+	var v messageJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*m = ExchangeMessage{
+		Exchange:  v.Exchange,
+		Account:   v.Account,
+		Symbol:    v.Symbol,
+		Timestamp: v.Timestamp,
+		MsgType:   v.MsgType,
+	}
+	if v.TransactTime != nil {
+		m.TransactTime = *v.TransactTime
+	}
+	if v.ReceivedAt != nil {
+		m.ReceivedAt = *v.ReceivedAt
+	}
+
+	var ok bool
+	switch v.MsgType {
+	case MsgTypeBBO:
+		if ok = v.BBO != nil; ok {
+			m.bbo = *v.BBO
+		}
+	case MsgTypeTrade:
+		if ok = v.Trade != nil; ok {
+			m.trade = *v.Trade
+		}
+	case MsgTypeOrderStatus:
+		if ok = v.Order != nil; ok {
+			m.order = v.Order
+		}
+	case MsgTypeBalanceUpdate:
+		if ok = v.Balance != nil; ok {
+			m.balance = v.Balance
+		}
+	case MsgTypePositionUpdate:
+		if ok = v.Position != nil; ok {
+			m.position = v.Position
+		}
+	case MsgTypeFill:
+		if ok = v.Fill != nil; ok {
+			m.fill = v.Fill
+		}
+	case MsgTypeFunding:
+		if ok = v.Funding != nil; ok {
+			m.funding = v.Funding
+		}
+	}
+
+	if !ok {
+		return fmt.Errorf("message of type %s has no payload", v.MsgType)
+	}
+
+	return nil
+}
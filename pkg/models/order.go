@@ -33,6 +33,13 @@ const (
 	OrderStatusCanceled        OrderStatus = "canceled"
 )
 
+// IsFinal returns true if order can not change anymore.
+func (s OrderStatus) IsFinal() bool {
+	return s == OrderStatusFilled ||
+		s == OrderStatusCanceled ||
+		s == OrderStatusRejected
+}
+
 type TimeInForce string
 
 const (
@@ -43,6 +50,8 @@ const (
 )
 
 type Order struct {
+	Exchange        string
+	Account         string
 	ClientOrderID   string
 	ExchangeOrderID string
 	CreatedAt       time.Time
@@ -73,3 +82,20 @@ type OrderUpdate struct {
 	FilledSize   decimal.Decimal
 	AveragePrice decimal.Decimal
 }
+
+// Fill is a single execution of an order.
+type Fill struct {
+	ClientOrderID   string
+	ExchangeOrderID string
+	TradeID         string
+	Symbol          string
+	Side            OrderSide
+	Size            decimal.Decimal
+	Price           decimal.Decimal
+	Commission      decimal.Decimal
+	CommissionAsset string
+	// RealizedProfit is profit of the fill as reported by exchange.
+	RealizedProfit decimal.Decimal
+	IsMaker        bool
+	Timestamp      time.Time
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -9,19 +10,222 @@ import (
 type MsgType uint8
 
 const (
-	MsgTypeBBO = iota
+	MsgTypeBBO MsgType = iota
 	MsgTypeOrderStatus
 	MsgTypeBalanceUpdate
 	MsgTypePositionUpdate
 	MsgTypeTrade
+	MsgTypeFill
+	MsgTypeFunding
 )
 
+var msgTypeNames = [...]string{
+	MsgTypeBBO:            "bbo",
+	MsgTypeOrderStatus:    "order_status",
+	MsgTypeBalanceUpdate:  "balance_update",
+	MsgTypePositionUpdate: "position_update",
+	MsgTypeTrade:          "trade",
+	MsgTypeFill:           "fill",
+	MsgTypeFunding:        "funding",
+}
+
+func (t MsgType) String() string {
+	if int(t) < len(msgTypeNames) && msgTypeNames[t] != "" {
+		return msgTypeNames[t]
+	}
+	return "unknown(" + strconv.Itoa(int(t)) + ")"
+}
+
+// ExchangeMessage carries one of the payloads defined by MsgType.
+// Hot BBO and trade payloads are stored by value, so passing them does
+// not allocate. Rare user data and funding payloads are stored by
+// pointer to keep the message small, they are shared by copies of the
+// message and must not be modified.
+// Use constructors to create messages and accessors to read payloads.
 type ExchangeMessage struct {
-	Exchange  string
-	Symbol    string
+	Exchange string
+	// Account is set for user data messages (orders, fills, balances
+	// and positions) by connectors bound to an account.
+	Account string
+	Symbol  string
+	// Timestamp is exchange event time. Messages without exchange
+	// time, e.g. produced by simulators, carry local time instead.
 	Timestamp time.Time
-	MsgType   MsgType
-	Payload   any
+	// TransactTime is exchange transaction (matching) time if known.
+	TransactTime time.Time
+	// ReceivedAt is local time the raw message was read from the wire,
+	// DecodedAt is time it was decoded into this message and
+	// DispatchedAt is time the event bus delivered it to subscriber.
+	ReceivedAt   time.Time
+	DecodedAt    time.Time
+	DispatchedAt time.Time
+	MsgType      MsgType
+
+	bbo      BBO
+	trade    Trade
+	order    *OrderUpdate
+	balance  *BalanceUpdate
+	position *PositionUpdate
+	fill     *Fill
+	funding  *Funding
+}
+
+func NewBBOMessage(exchange, symbol string, ts time.Time, bbo BBO) ExchangeMessage {
+	return ExchangeMessage{
+		Exchange:  exchange,
+		Symbol:    symbol,
+		Timestamp: ts,
+		MsgType:   MsgTypeBBO,
+		bbo:       bbo,
+	}
+}
+
+func NewTradeMessage(exchange, symbol string, ts time.Time, trade Trade) ExchangeMessage {
+	return ExchangeMessage{
+		Exchange:  exchange,
+		Symbol:    symbol,
+		Timestamp: ts,
+		MsgType:   MsgTypeTrade,
+		trade:     trade,
+	}
+}
+
+func NewOrderUpdateMessage(exchange, symbol string, ts time.Time, upd OrderUpdate) ExchangeMessage {
+	return ExchangeMessage{
+		Exchange:  exchange,
+		Symbol:    symbol,
+		Timestamp: ts,
+		MsgType:   MsgTypeOrderStatus,
+		order:     &upd,
+	}
+}
+
+func NewBalanceUpdateMessage(exchange string, ts time.Time, upd BalanceUpdate) ExchangeMessage {
+	return ExchangeMessage{
+		Exchange:  exchange,
+		Timestamp: ts,
+		MsgType:   MsgTypeBalanceUpdate,
+		balance:   &upd,
+	}
+}
+
+func NewPositionUpdateMessage(exchange string, ts time.Time, upd PositionUpdate) ExchangeMessage {
+	return ExchangeMessage{
+		Exchange:  exchange,
+		Symbol:    upd.Symbol,
+		Timestamp: ts,
+		MsgType:   MsgTypePositionUpdate,
+		position:  &upd,
+	}
+}
+
+func NewFillMessage(exchange string, ts time.Time, fill Fill) ExchangeMessage {
+	return ExchangeMessage{
+		Exchange:  exchange,
+		Symbol:    fill.Symbol,
+		Timestamp: ts,
+		MsgType:   MsgTypeFill,
+		fill:      &fill,
+	}
+}
+
+func NewFundingMessage(exchange, symbol string, ts time.Time, funding Funding) ExchangeMessage {
+	return ExchangeMessage{
+		Exchange:  exchange,
+		Symbol:    symbol,
+		Timestamp: ts,
+		MsgType:   MsgTypeFunding,
+		funding:   &funding,
+	}
+}
+
+// BBO returns message payload if message type is MsgTypeBBO.
+func (m ExchangeMessage) BBO() (BBO, bool) {
+	return m.bbo, m.MsgType == MsgTypeBBO
+}
+
+// Trade returns message payload if message type is MsgTypeTrade.
+func (m ExchangeMessage) Trade() (Trade, bool) {
+	return m.trade, m.MsgType == MsgTypeTrade
+}
+
+// OrderUpdate returns message payload if message type is MsgTypeOrderStatus.
+func (m ExchangeMessage) OrderUpdate() (OrderUpdate, bool) {
+	return deref(m.order), m.MsgType == MsgTypeOrderStatus
+}
+
+// BalanceUpdate returns message payload if message type is MsgTypeBalanceUpdate.
+func (m ExchangeMessage) BalanceUpdate() (BalanceUpdate, bool) {
+	return deref(m.balance), m.MsgType == MsgTypeBalanceUpdate
+}
+
+// PositionUpdate returns message payload if message type is MsgTypePositionUpdate.
+func (m ExchangeMessage) PositionUpdate() (PositionUpdate, bool) {
+	return deref(m.position), m.MsgType == MsgTypePositionUpdate
+}
+
+// Fill returns message payload if message type is MsgTypeFill.
+func (m ExchangeMessage) Fill() (Fill, bool) {
+	return deref(m.fill), m.MsgType == MsgTypeFill
+}
+
+// Funding returns message payload if message type is MsgTypeFunding.
+func (m ExchangeMessage) Funding() (Funding, bool) {
+	return deref(m.funding), m.MsgType == MsgTypeFunding
+}
+
+// deref returns the value p points to or zero value if p is nil.
+func deref[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
+
+// Handlers calls a handler matching message type.
+// Messages without handler set are ignored.
+type Handlers struct {
+	BBO            func(msg ExchangeMessage, bbo BBO)
+	Trade          func(msg ExchangeMessage, trade Trade)
+	OrderUpdate    func(msg ExchangeMessage, upd OrderUpdate)
+	BalanceUpdate  func(msg ExchangeMessage, upd BalanceUpdate)
+	PositionUpdate func(msg ExchangeMessage, upd PositionUpdate)
+	Fill           func(msg ExchangeMessage, fill Fill)
+	Funding        func(msg ExchangeMessage, funding Funding)
+}
+
+func (h *Handlers) Handle(msg ExchangeMessage) {
+	switch msg.MsgType {
+	case MsgTypeBBO:
+		if h.BBO != nil {
+			h.BBO(msg, msg.bbo)
+		}
+	case MsgTypeTrade:
+		if h.Trade != nil {
+			h.Trade(msg, msg.trade)
+		}
+	case MsgTypeOrderStatus:
+		if h.OrderUpdate != nil {
+			h.OrderUpdate(msg, deref(msg.order))
+		}
+	case MsgTypeBalanceUpdate:
+		if h.BalanceUpdate != nil {
+			h.BalanceUpdate(msg, deref(msg.balance))
+		}
+	case MsgTypePositionUpdate:
+		if h.PositionUpdate != nil {
+			h.PositionUpdate(msg, deref(msg.position))
+		}
+	case MsgTypeFill:
+		if h.Fill != nil {
+			h.Fill(msg, deref(msg.fill))
+		}
+	case MsgTypeFunding:
+		if h.Funding != nil {
+			h.Funding(msg, deref(msg.funding))
+		}
+	}
 }
 
 type PriceLevel struct {
@@ -35,9 +239,20 @@ type BBO struct {
 	Timestamp time.Time
 }
 
+type BalanceUpdateReason string
+
+const (
+	BalanceUpdateReasonOrder   BalanceUpdateReason = "order"
+	BalanceUpdateReasonFunding BalanceUpdateReason = "funding_fee"
+	BalanceUpdateReasonOther   BalanceUpdateReason = "other"
+)
+
 type BalanceUpdate struct {
 	Asset   string
 	Balance decimal.Decimal
+	// Change is balance change excluding PnL and commission.
+	Change decimal.Decimal
+	Reason BalanceUpdateReason
 }
 
 type PositionUpdate struct {
@@ -52,3 +267,12 @@ type Trade struct {
 	Price     decimal.Decimal
 	Timestamp time.Time
 }
+
+// Funding is a perpetual contract mark price and funding rate,
+// which is predicted until the next funding time.
+type Funding struct {
+	MarkPrice       decimal.Decimal
+	IndexPrice      decimal.Decimal
+	Rate            decimal.Decimal
+	NextFundingTime time.Time
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMsgTypeString(t *testing.T) {
+	cases := map[MsgType]string{
+		MsgTypeBBO:         "bbo",
+		MsgTypeOrderStatus: "order_status",
+		MsgTypeTrade:       "trade",
+		MsgType(200):       "unknown(200)",
+	}
+
+	for typ, expected := range cases {
+		if typ.String() != expected {
+			t.Errorf("expected %q, got %q", expected, typ.String())
+		}
+	}
+}
+
+func TestPayloadAccessors(t *testing.T) {
+	bbo := BBO{
+		Bid: PriceLevel{Price: decimal.NewFromInt(1)},
+		Ask: PriceLevel{Price: decimal.NewFromInt(2)},
+	}
+	msg := NewBBOMessage("test", "ethusdt", time.Now(), bbo)
+
+	if got, ok := msg.BBO(); !ok || !got.Ask.Price.Equal(bbo.Ask.Price) {
+		t.Errorf("expected BBO payload %v, got %v (%v)", bbo, got, ok)
+	}
+
+	if _, ok := msg.Trade(); ok {
+		t.Error("expected BBO message not to have trade payload")
+	}
+
+	var handled MsgType = 255
+	h := Handlers{
+		BBO:   func(msg ExchangeMessage, bbo BBO) { handled = msg.MsgType },
+		Trade: func(msg ExchangeMessage, trade Trade) { handled = msg.MsgType },
+	}
+	h.Handle(msg)
+	if handled != MsgTypeBBO {
+		t.Errorf("expected BBO handler to be called, got %v", handled)
+	}
+}
+
+func TestHotPathAllocations(t *testing.T) {
+	bbo := BBO{
+		Bid: PriceLevel{Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)},
+		Ask: PriceLevel{Price: decimal.NewFromInt(2), Size: decimal.NewFromInt(1)},
+	}
+	trade := Trade{Side: OrderSideBuy, Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)}
+	ch := make(chan ExchangeMessage, 1)
+	ts := time.Now()
+	h := Handlers{
+		BBO:   func(msg ExchangeMessage, bbo BBO) {},
+		Trade: func(msg ExchangeMessage, trade Trade) {},
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		ch <- NewBBOMessage("test", "ethusdt", ts, bbo)
+		msg := <-ch
+		h.Handle(msg)
+
+		ch <- NewTradeMessage("test", "ethusdt", ts, trade)
+		msg = <-ch
+		h.Handle(msg)
+	})
+
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestMessageSize(t *testing.T) {
+	// Messages are copied on every send, rare payloads must stay out of line.
+	if size := unsafe.Sizeof(ExchangeMessage{}); size > 400 {
+		t.Errorf("expected message to be at most 400 bytes, got %d", size)
+	}
+
+	var msg ExchangeMessage
+	msg.MsgType = MsgTypeFill
+	if fill, ok := msg.Fill(); !ok || fill.TradeID != "" {
+		t.Errorf("expected zero fill, got %+v (%v)", fill, ok)
+	}
+}
+
+func BenchmarkMessageSend(b *testing.B) {
+	bbo := BBO{
+		Bid: PriceLevel{Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)},
+		Ask: PriceLevel{Price: decimal.NewFromInt(2), Size: decimal.NewFromInt(1)},
+	}
+	msg := NewBBOMessage("test", "ethusdt", time.Now(), bbo)
+	ch := make(chan ExchangeMessage, 1)
+	h := Handlers{BBO: func(msg ExchangeMessage, bbo BBO) {}}
+
+	for i := 0; i < b.N; i++ {
+		ch <- msg
+		h.Handle(<-ch)
+	}
+}
+
+func TestMessageJSON(t *testing.T) {
+	bbo := BBO{
+		Bid: PriceLevel{Price: decimal.NewFromFloat(1.5), Size: decimal.NewFromInt(3)},
+		Ask: PriceLevel{Price: decimal.NewFromInt(2), Size: decimal.NewFromInt(4)},
+	}
+	msg := NewBBOMessage("test", "ethusdt", time.Unix(1, 0).UTC(), bbo)
+
+	b, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got ExchangeMessage
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	gotBBO, ok := got.BBO()
+	if !ok || got.Symbol != "ethusdt" || !got.Timestamp.Equal(msg.Timestamp) {
+		t.Fatalf("unexpected message %s", b)
+	}
+	if !gotBBO.Bid.Price.Equal(bbo.Bid.Price) || !gotBBO.Ask.Size.Equal(bbo.Ask.Size) {
+		t.Errorf("expected %+v, got %+v", bbo, gotBBO)
+	}
+
+	if err := json.Unmarshal([]byte(`{"type":"trade"}`), &got); err == nil {
+		t.Error("expected error for message without payload")
+	}
+}
@@ -0,0 +1,549 @@
+package oms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"degen/pkg/connectors"
+	"degen/pkg/journal"
+	"degen/pkg/logging"
+	"degen/pkg/metrics"
+	"degen/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrRiskLimit = errors.New("risk limit exceeded")
+	ErrHalted    = errors.New("trading is halted")
+	// ErrNotUnwound is returned when legs of a failed batch stay open.
+	ErrNotUnwound = errors.New("placed legs are not unwound")
+)
+
+var (
+	orderLatency = metrics.NewHistogram("degen_order_latency_seconds",
+		"Order request round-trip time.", metrics.DefaultBuckets, "exchange", "op")
+	orderRejects = metrics.NewCounter("degen_order_rejects_total",
+		"Rejected orders by error code.", "exchange", "code")
+)
+
+// Limits are pre-trade risk checks. Zero value disables a check.
+type Limits struct {
+	MaxOrderSize  decimal.Decimal `json:"max_order_size"`
+	MaxPosition   decimal.Decimal `json:"max_position"`
+	MaxOpenOrders int             `json:"max_open_orders"`
+}
+
+// OMS places orders of a single account and keeps track of its open orders.
+// Every order intent and outcome is written to the journal before
+// and after the exchange call, so open orders can be recovered after crash.
+type OMS struct {
+	api      connectors.OrderAPI
+	journal  *journal.Journal
+	acc      *models.Account
+	exchange string
+	account  string
+	limits   Limits
+	halted   bool
+	log      *logging.Logger
+
+	orders map[string]models.Order
+	mux    sync.RWMutex
+}
+
+// New creates OMS. Journal can be nil.
+func New(api connectors.OrderAPI, acc *models.Account, j *journal.Journal) *OMS {
+	return &OMS{
+		api:      api,
+		journal:  j,
+		acc:      acc,
+		exchange: acc.Exchange(),
+		account:  acc.ID(),
+		log:      logging.New("oms").With("exchange", acc.Exchange(), "account", acc.ID()),
+		orders:   make(map[string]models.Order),
+	}
+}
+
+// SetLimits sets pre-trade risk limits checked by PlaceOrder.
+func (o *OMS) SetLimits(limits Limits) {
+	o.mux.Lock()
+	o.limits = limits
+	o.mux.Unlock()
+}
+
+// Halt stops accepting orders except ones reducing position.
+// It can not be undone, the process has to be restarted.
+func (o *OMS) Halt() {
+	o.mux.Lock()
+	o.halted = true
+	o.mux.Unlock()
+}
+
+func (o *OMS) Halted() bool {
+	o.mux.RLock()
+	defer o.mux.RUnlock()
+
+	return o.halted
+}
+
+func (o *OMS) checkLimits(order models.Order) error {
+	o.mux.RLock()
+	defer o.mux.RUnlock()
+
+	if o.halted {
+		pos := o.acc.GetPosition(order.Symbol).Amount
+		reducing := (pos.IsPositive() && order.Side == models.OrderSideSell) ||
+			(pos.IsNegative() && order.Side == models.OrderSideBuy)
+		if !reducing || order.Size.GreaterThan(pos.Abs()) {
+			return ErrHalted
+		}
+	}
+
+	l := o.limits
+	if l.MaxOrderSize.IsPositive() && order.Size.GreaterThan(l.MaxOrderSize) {
+		return fmt.Errorf("order size %v is over %v: %w", order.Size, l.MaxOrderSize, ErrRiskLimit)
+	}
+
+	if l.MaxOpenOrders > 0 && len(o.orders) >= l.MaxOpenOrders {
+		return fmt.Errorf("%d open orders: %w", len(o.orders), ErrRiskLimit)
+	}
+
+	if l.MaxPosition.IsPositive() {
+		pos := o.acc.GetPosition(order.Symbol).Amount
+		next := pos.Add(order.Size)
+		if order.Side == models.OrderSideSell {
+			next = pos.Sub(order.Size)
+		}
+		// Reducing position is always allowed.
+		if next.Abs().GreaterThan(l.MaxPosition) && next.Abs().GreaterThan(pos.Abs()) {
+			return fmt.Errorf("position %v would be over %v: %w", next, l.MaxPosition, ErrRiskLimit)
+		}
+	}
+
+	return nil
+}
+
+func orderKey(clientOrderID, exchangeOrderID string) string {
+	if clientOrderID != "" {
+		return clientOrderID
+	}
+	return exchangeOrderID
+}
+
+func (o *OMS) journalErr(err error) {
+	if err != nil {
+		o.log.Error("failed to journal", "err", err)
+	}
+}
+
+// PlaceOrder places order, assigning client order ID if it is empty.
+func (o *OMS) PlaceOrder(ctx context.Context, order models.Order) (*models.Order, error) {
+	order.Exchange = o.exchange
+	order.Account = o.account
+	if order.ClientOrderID == "" {
+		order.ClientOrderID = uuid.New().String()
+	}
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = time.Now().UTC()
+	}
+	order.Status = models.OrderStatusNew
+
+	if err := o.checkLimits(order); err != nil {
+		code := "risk_limit"
+		if errors.Is(err, ErrHalted) {
+			code = "halted"
+		}
+		orderRejects.Inc(o.exchange, code)
+		return nil, fmt.Errorf("oms.PlaceOrder: %w", err)
+	}
+
+	// Intent is tracked before the call, so the order is reconciled
+	// if we crash before getting the response.
+	o.journalErr(o.journal.RecordOrder(journal.RecordOrderIntent, order, nil))
+	o.set(order)
+
+	start := time.Now()
+	res, err := o.api.PlaceOrder(ctx, order)
+	orderLatency.Observe(time.Since(start).Seconds(), o.exchange, "place")
+	if err != nil {
+		orderRejects.Inc(o.exchange, connectors.ErrorCode(err))
+		o.journalErr(o.journal.RecordOrder(journal.RecordOrderReject, order, err))
+		o.remove(order)
+		return nil, err
+	}
+
+	res.Exchange = o.exchange
+	res.Account = o.account
+	o.journalErr(o.journal.RecordOrder(journal.RecordOrderAck, *res, nil))
+	o.set(*res)
+
+	return res, nil
+}
+
+// PlaceOrders places orders at once, e.g. legs of a spread, all or none.
+// All orders are checked against risk limits first and none is placed if
+// any check fails. If placing some of them fails, placed ones are unwound:
+// canceled and their filled size closed with market orders. track, if not
+// nil, is called with every order before it is sent, unwinding ones too,
+// e.g. to attribute their fills.
+// Results are in order of orders. On error only legs which could not be
+// unwound are set and the error wraps ErrNotUnwound, otherwise it is the
+// first placement error.
+func (o *OMS) PlaceOrders(ctx context.Context, orders []models.Order, track func(models.Order)) ([]*models.Order, error) {
+	for _, order := range orders {
+		if err := o.checkLimits(order); err != nil {
+			return nil, fmt.Errorf("oms.PlaceOrders: %w", err)
+		}
+	}
+	o.mux.RLock()
+	limit, open := o.limits.MaxOpenOrders, len(o.orders)
+	o.mux.RUnlock()
+	if limit > 0 && open+len(orders) > limit {
+		return nil, fmt.Errorf("oms.PlaceOrders: %d open orders: %w", open, ErrRiskLimit)
+	}
+
+	var (
+		res  = make([]*models.Order, len(orders))
+		errs = make([]error, len(orders))
+		wg   sync.WaitGroup
+	)
+	for i := range orders {
+		order := orders[i]
+		if order.ClientOrderID == "" {
+			order.ClientOrderID = uuid.New().String()
+		}
+		if track != nil {
+			track(order)
+		}
+
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res[i], errs[i] = o.PlaceOrder(ctx, order)
+		}()
+	}
+	wg.Wait()
+
+	var placeErr error
+	for _, err := range errs {
+		if err != nil {
+			placeErr = err
+			break
+		}
+	}
+	if placeErr == nil {
+		return res, nil
+	}
+
+	var stuck []string
+	for i, leg := range res {
+		if leg == nil {
+			continue
+		}
+		if err := o.unwind(ctx, *leg, track); err != nil {
+			o.log.Error("failed to unwind leg",
+				"symbol", leg.Symbol,
+				"client_order_id", leg.ClientOrderID,
+				"err", err,
+			)
+			stuck = append(stuck, fmt.Sprintf("%s %s %s", leg.Symbol, leg.Side, leg.ClientOrderID))
+			continue
+		}
+		res[i] = nil
+	}
+	if len(stuck) > 0 {
+		return res, fmt.Errorf("oms.PlaceOrders failed to unwind %s after %v: %w",
+			strings.Join(stuck, ", "), placeErr, ErrNotUnwound)
+	}
+	return res, placeErr
+}
+
+// unwind cancels the order if it is open and closes its filled size
+// with a market order.
+func (o *OMS) unwind(ctx context.Context, order models.Order, track func(models.Order)) error {
+	if !order.Status.IsFinal() {
+		res, err := o.CancelOrder(ctx, order)
+		if err != nil {
+			// Order could be filled in the meantime.
+			var qerr error
+			if res, qerr = o.QueryOrder(ctx, order); qerr != nil || !res.Status.IsFinal() {
+				return fmt.Errorf("failed to cancel: %w", err)
+			}
+		}
+		order = *res
+	}
+	if !order.FilledSize.IsPositive() {
+		return nil
+	}
+
+	side := models.OrderSideSell
+	if order.Side == models.OrderSideSell {
+		side = models.OrderSideBuy
+	}
+	closing := models.Order{
+		ClientOrderID: uuid.New().String(),
+		Symbol:        order.Symbol,
+		Side:          side,
+		Type:          models.OrderTypeMarket,
+		Size:          order.FilledSize,
+	}
+	if track != nil {
+		track(closing)
+	}
+	if _, err := o.PlaceOrder(ctx, closing); err != nil {
+		return fmt.Errorf("failed to close filled %v: %w", order.FilledSize, err)
+	}
+	return nil
+}
+
+func (o *OMS) CancelOrder(ctx context.Context, order models.Order) (*models.Order, error) {
+	start := time.Now()
+	res, err := o.api.CancelOrder(ctx, order)
+	orderLatency.Observe(time.Since(start).Seconds(), o.exchange, "cancel")
+	if err != nil {
+		return nil, err
+	}
+
+	o.update(*res)
+	return res, nil
+}
+
+func (o *OMS) QueryOrder(ctx context.Context, order models.Order) (*models.Order, error) {
+	start := time.Now()
+	res, err := o.api.QueryOrder(ctx, order)
+	orderLatency.Observe(time.Since(start).Seconds(), o.exchange, "query")
+	if err != nil {
+		return nil, err
+	}
+
+	o.update(*res)
+	return res, nil
+}
+
+// CancelAll cancels all open orders of the symbol, or all open orders
+// if symbol is empty. It returns number of canceled orders and
+// the first error, trying to cancel the rest of orders anyway.
+func (o *OMS) CancelAll(ctx context.Context, symbol string) (int, error) {
+	var (
+		canceled int
+		firstErr error
+	)
+	for _, order := range o.OpenOrders() {
+		if symbol != "" && order.Symbol != symbol {
+			continue
+		}
+		if _, err := o.CancelOrder(ctx, order); err != nil {
+			o.log.Error("failed to cancel order",
+				"symbol", order.Symbol,
+				"client_order_id", order.ClientOrderID,
+				"order_id", order.ExchangeOrderID,
+				"err", err,
+			)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("oms.CancelAll failed to cancel order %s: %w", order.ClientOrderID, err)
+			}
+			continue
+		}
+		canceled++
+	}
+	return canceled, firstErr
+}
+
+// Flatten cancels open orders of the symbol and closes its position
+// with a market order. It returns nil order if there is no position.
+func (o *OMS) Flatten(ctx context.Context, symbol string) (*models.Order, error) {
+	if _, err := o.CancelAll(ctx, symbol); err != nil {
+		return nil, err
+	}
+
+	pos := o.acc.GetPosition(symbol).Amount
+	if pos.IsZero() {
+		return nil, nil
+	}
+
+	side := models.OrderSideSell
+	if pos.IsNegative() {
+		side = models.OrderSideBuy
+	}
+	return o.PlaceOrder(ctx, models.Order{
+		Symbol: symbol,
+		Side:   side,
+		Type:   models.OrderTypeMarket,
+		Size:   pos.Abs(),
+	})
+}
+
+// update stores order state coming from exchange and journals it.
+func (o *OMS) update(order models.Order) {
+	order.Exchange = o.exchange
+	order.Account = o.account
+	o.journalErr(o.journal.RecordOrder(journal.RecordOrderAck, order, nil))
+	o.set(order)
+}
+
+// OnOrderUpdate applies order update from user data stream.
+func (o *OMS) OnOrderUpdate(upd models.OrderUpdate) {
+	o.journalErr(o.journal.RecordOrderUpdate(o.exchange, o.account, upd))
+
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	k := orderKey(upd.ClientOrderID, upd.ExchangeOrderID)
+	if upd.Status.IsFinal() {
+		delete(o.orders, k)
+		return
+	}
+
+	order, ok := o.orders[k]
+	if !ok {
+		// Order placed outside of OMS or before restart.
+		order = models.Order{
+			Exchange:      o.exchange,
+			Account:       o.account,
+			ClientOrderID: upd.ClientOrderID,
+			CreatedAt:     upd.UpdatedAt,
+			Symbol:        upd.Symbol,
+			Side:          upd.Side,
+		}
+	}
+	order.ExchangeOrderID = upd.ExchangeOrderID
+	order.Status = upd.Status
+	order.UpdatedAt = upd.UpdatedAt
+	order.FilledSize = upd.FilledSize
+	order.AveragePrice = upd.AveragePrice
+	o.orders[k] = order
+}
+
+// OnFill journals order execution and calls apply atomically with
+// respect to journal snapshots, see journal.RecordFill.
+func (o *OMS) OnFill(fill models.Fill, apply func()) {
+	o.journalErr(o.journal.RecordFill(o.exchange, o.account, fill, apply))
+}
+
+func (o *OMS) set(order models.Order) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	k := orderKey(order.ClientOrderID, order.ExchangeOrderID)
+	if order.Status.IsFinal() {
+		delete(o.orders, k)
+		return
+	}
+	o.orders[k] = order
+}
+
+func (o *OMS) remove(order models.Order) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	delete(o.orders, orderKey(order.ClientOrderID, order.ExchangeOrderID))
+}
+
+// Restore replaces open orders with the recovered ones.
+func (o *OMS) Restore(orders []models.Order) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	o.orders = make(map[string]models.Order, len(orders))
+	for _, order := range orders {
+		o.orders[orderKey(order.ClientOrderID, order.ExchangeOrderID)] = order
+	}
+}
+
+// OpenOrders returns open orders sorted by creation time.
+func (o *OMS) OpenOrders() []models.Order {
+	o.mux.RLock()
+	defer o.mux.RUnlock()
+
+	orders := make([]models.Order, 0, len(o.orders))
+	for _, order := range o.orders {
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+	})
+	return orders
+}
+
+// Reconcile replaces local account and open orders state with
+// the exchange one, logging any differences found.
+// Strategies should not trade until reconciliation is done.
+func (o *OMS) Reconcile(ctx context.Context, api connectors.AccountAPI) error {
+	acc := o.acc
+	balances, positions, err := api.GetAccountState(ctx)
+	if err != nil {
+		return fmt.Errorf("oms.Reconcile failed to get account state: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, b := range balances {
+		if local := acc.GetBalance(b.Asset); !local.Balance.Equal(b.Balance) {
+			o.log.Warn("reconcile: balance differs",
+				"asset", b.Asset, "local", local.Balance, "remote", b.Balance)
+		}
+		acc.UpdateBalance(b.Asset, b.Balance, now)
+		o.journalErr(o.journal.RecordBalance(o.exchange, o.account, b))
+	}
+
+	seen := make(map[string]bool, len(positions))
+	for _, p := range positions {
+		seen[p.Symbol] = true
+		if local := acc.GetPosition(p.Symbol); !local.Amount.Equal(p.Amount) {
+			o.log.Warn("reconcile: position differs",
+				"symbol", p.Symbol, "local", local.Amount, "remote", p.Amount)
+		}
+		acc.UpdatePosition(p.Symbol, p.Amount, p.EntryPrice, now)
+		o.journalErr(o.journal.RecordPosition(o.exchange, o.account, p))
+	}
+	for symbol, local := range acc.Positions() {
+		if seen[symbol] || local.Amount.IsZero() {
+			continue
+		}
+		o.log.Warn("reconcile: position differs",
+			"symbol", symbol, "local", local.Amount, "remote", 0)
+		upd := models.PositionUpdate{Symbol: symbol}
+		acc.UpdatePosition(symbol, upd.Amount, upd.EntryPrice, now)
+		o.journalErr(o.journal.RecordPosition(o.exchange, o.account, upd))
+	}
+
+	open, err := api.GetOpenOrders(ctx)
+	if err != nil {
+		return fmt.Errorf("oms.Reconcile failed to get open orders: %w", err)
+	}
+
+	exchangeOrders := make(map[string]bool, len(open))
+	for _, order := range open {
+		order.Account = o.account
+		exchangeOrders[orderKey(order.ClientOrderID, order.ExchangeOrderID)] = true
+		o.update(order)
+	}
+
+	// Orders we think are open, but exchange does not list them,
+	// were finished or never reached the exchange.
+	for _, order := range o.OpenOrders() {
+		if exchangeOrders[orderKey(order.ClientOrderID, order.ExchangeOrderID)] {
+			continue
+		}
+
+		res, err := o.QueryOrder(ctx, order)
+		if err != nil {
+			o.log.Warn("reconcile: order not found",
+				"symbol", order.Symbol, "client_order_id", order.ClientOrderID, "err", err)
+			order.Status = models.OrderStatusRejected
+			o.journalErr(o.journal.RecordOrder(journal.RecordOrderReject, order, err))
+			o.remove(order)
+			continue
+		}
+		o.log.Info("reconcile: order is not open",
+			"symbol", order.Symbol, "client_order_id", order.ClientOrderID, "status", res.Status)
+	}
+
+	return nil
+}
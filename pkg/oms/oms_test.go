@@ -0,0 +1,275 @@
+package oms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+type fakeAPI struct {
+	mux      sync.Mutex
+	placed   []models.Order
+	open     []models.Order
+	known    map[string]models.Order
+	balances []models.BalanceUpdate
+	pos      []models.PositionUpdate
+}
+
+func (f *fakeAPI) PlaceOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.placed = append(f.placed, order)
+	order.ExchangeOrderID = "ex" + order.ClientOrderID
+	order.Status = models.OrderStatusPlaced
+	return &order, nil
+}
+
+func (f *fakeAPI) CancelOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	order.Status = models.OrderStatusCanceled
+	return &order, nil
+}
+
+func (f *fakeAPI) QueryOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	o, ok := f.known[order.ClientOrderID]
+	if !ok {
+		return nil, errors.New("order does not exist")
+	}
+	return &o, nil
+}
+
+func (f *fakeAPI) GetOpenOrders(context.Context) ([]models.Order, error) {
+	return f.open, nil
+}
+
+func (f *fakeAPI) GetAccountState(context.Context) ([]models.BalanceUpdate, []models.PositionUpdate, error) {
+	return f.balances, f.pos, nil
+}
+
+func TestPlaceOrder(t *testing.T) {
+	api := &fakeAPI{}
+	o := New(api, models.NewAccount("acc", "ex"), nil)
+
+	res, err := o.PlaceOrder(context.Background(), models.Order{Symbol: "ethusdt", Type: models.OrderTypeLimit})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.ClientOrderID == "" || res.Exchange != "ex" || res.Account != "acc" {
+		t.Errorf("unexpected order %+v", res)
+	}
+
+	if open := o.OpenOrders(); len(open) != 1 || open[0].Status != models.OrderStatusPlaced {
+		t.Fatalf("expected 1 placed order, got %+v", open)
+	}
+
+	o.OnOrderUpdate(models.OrderUpdate{
+		ClientOrderID:   res.ClientOrderID,
+		ExchangeOrderID: res.ExchangeOrderID,
+		Status:          models.OrderStatusFilled,
+	})
+
+	if open := o.OpenOrders(); len(open) != 0 {
+		t.Errorf("expected no open orders, got %+v", open)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	acc := models.NewAccount("acc", "ex")
+	acc.UpdatePosition("btcusdt", decimal.NewFromInt(1), decimal.NewFromInt(100), time.Now())
+
+	api := &fakeAPI{
+		open: []models.Order{{ClientOrderID: "unknown", Status: models.OrderStatusPlaced}},
+		known: map[string]models.Order{
+			"filled": {ClientOrderID: "filled", Status: models.OrderStatusFilled},
+		},
+		balances: []models.BalanceUpdate{{Asset: "usdt", Balance: decimal.NewFromInt(10)}},
+		pos:      []models.PositionUpdate{{Symbol: "ethusdt", Amount: decimal.NewFromInt(2)}},
+	}
+
+	o := New(api, acc, nil)
+	o.Restore([]models.Order{
+		{ClientOrderID: "filled", Status: models.OrderStatusPlaced},
+		{ClientOrderID: "lost", Status: models.OrderStatusNew},
+	})
+
+	if err := o.Reconcile(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+
+	open := o.OpenOrders()
+	if len(open) != 1 || open[0].ClientOrderID != "unknown" {
+		t.Errorf("expected only exchange order to be open, got %+v", open)
+	}
+
+	if !acc.GetBalance("usdt").Balance.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("unexpected balance %v", acc.GetBalance("usdt").Balance)
+	}
+	if !acc.GetPosition("ethusdt").Amount.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("unexpected ethusdt position %v", acc.GetPosition("ethusdt").Amount)
+	}
+	if !acc.GetPosition("btcusdt").Amount.IsZero() {
+		t.Errorf("expected btcusdt position to be closed, got %v", acc.GetPosition("btcusdt").Amount)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	acc := models.NewAccount("acc", "ex")
+	acc.UpdatePosition("ethusdt", decimal.NewFromInt(2), decimal.NewFromInt(100), time.Now())
+
+	o := New(&fakeAPI{}, acc, nil)
+	o.SetLimits(Limits{
+		MaxOrderSize: decimal.NewFromInt(5),
+		MaxPosition:  decimal.NewFromInt(3),
+	})
+
+	cases := []struct {
+		side models.OrderSide
+		size int64
+		ok   bool
+	}{
+		{models.OrderSideBuy, 6, false},
+		{models.OrderSideBuy, 2, false},
+		{models.OrderSideBuy, 1, true},
+		{models.OrderSideSell, 5, true},
+	}
+
+	for _, c := range cases {
+		_, err := o.PlaceOrder(context.Background(), models.Order{
+			Symbol: "ethusdt",
+			Side:   c.side,
+			Size:   decimal.NewFromInt(c.size),
+		})
+		if c.ok != (err == nil) {
+			t.Errorf("%s %d: unexpected error %v", c.side, c.size, err)
+		}
+		if err != nil && !errors.Is(err, ErrRiskLimit) {
+			t.Errorf("expected ErrRiskLimit, got %v", err)
+		}
+	}
+}
+
+func TestPlaceOrders(t *testing.T) {
+	api := &fakeAPI{}
+	o := New(api, models.NewAccount("acc", "ex"), nil)
+	o.SetLimits(Limits{MaxOrderSize: decimal.NewFromInt(5)})
+
+	legs := []models.Order{
+		{Symbol: "ethusdt", Side: models.OrderSideBuy, Size: decimal.NewFromInt(1)},
+		{Symbol: "btcusdt", Side: models.OrderSideSell, Size: decimal.NewFromInt(6)},
+	}
+	if _, err := o.PlaceOrders(context.Background(), legs, nil); !errors.Is(err, ErrRiskLimit) || len(api.placed) != 0 {
+		t.Fatalf("expected no leg to be placed, got %v and %d orders", err, len(api.placed))
+	}
+
+	legs[1].Size = decimal.NewFromInt(2)
+	res, err := o.PlaceOrders(context.Background(), legs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].Symbol != "ethusdt" || res[1].Symbol != "btcusdt" || len(o.OpenOrders()) != 2 {
+		t.Errorf("expected both legs placed in order, got %+v", res)
+	}
+}
+
+// failingAPI fills market orders at once and fails orders matching fail.
+type failingAPI struct {
+	fakeAPI
+	fail func(order models.Order) bool
+}
+
+func (f *failingAPI) PlaceOrder(ctx context.Context, order models.Order) (*models.Order, error) {
+	if f.fail(order) {
+		return nil, errors.New("rejected")
+	}
+	res, err := f.fakeAPI.PlaceOrder(ctx, order)
+	if err == nil && order.Type == models.OrderTypeMarket {
+		res.Status, res.FilledSize = models.OrderStatusFilled, order.Size
+	}
+	return res, err
+}
+
+func TestPlaceOrdersUnwind(t *testing.T) {
+	legs := []models.Order{
+		{Symbol: "ethusdt", Side: models.OrderSideBuy, Type: models.OrderTypeMarket, Size: decimal.NewFromInt(1)},
+		{Symbol: "solusdt", Side: models.OrderSideSell, Type: models.OrderTypeLimit, Size: decimal.NewFromInt(2)},
+		{Symbol: "btcusdt", Side: models.OrderSideSell, Type: models.OrderTypeMarket, Size: decimal.NewFromInt(1)},
+	}
+
+	// Filled leg is closed and resting one canceled.
+	api := &failingAPI{fail: func(order models.Order) bool { return order.Symbol == "btcusdt" }}
+	o := New(api, models.NewAccount("acc", "ex"), nil)
+	var tracked []models.Order
+	res, err := o.PlaceOrders(context.Background(), legs, func(order models.Order) {
+		tracked = append(tracked, order)
+	})
+	if err == nil || errors.Is(err, ErrNotUnwound) {
+		t.Fatalf("expected placement error, got %v", err)
+	}
+	for i, r := range res {
+		if r != nil {
+			t.Errorf("expected leg %d to be unwound, got %+v", i, r)
+		}
+	}
+	last := api.placed[len(api.placed)-1]
+	if last.Symbol != "ethusdt" || last.Side != models.OrderSideSell || !last.Size.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("expected filled leg to be closed, got %+v", last)
+	}
+	if len(tracked) != 4 || tracked[3].ClientOrderID != last.ClientOrderID {
+		t.Errorf("expected closing order to be tracked, got %d orders", len(tracked))
+	}
+	if len(o.OpenOrders()) != 0 {
+		t.Errorf("expected no open orders, got %+v", o.OpenOrders())
+	}
+
+	// Leg failed to close is returned.
+	api = &failingAPI{fail: func(order models.Order) bool {
+		return order.Symbol == "btcusdt" || (order.Symbol == "ethusdt" && order.Side == models.OrderSideSell)
+	}}
+	o = New(api, models.NewAccount("acc", "ex"), nil)
+	res, err = o.PlaceOrders(context.Background(), legs, nil)
+	if !errors.Is(err, ErrNotUnwound) || !strings.Contains(err.Error(), "ethusdt buy") {
+		t.Fatalf("expected ethusdt leg not to be unwound, got %v", err)
+	}
+	if res[0] == nil || res[0].Symbol != "ethusdt" || res[1] != nil || res[2] != nil {
+		t.Errorf("expected only ethusdt leg left, got %+v", res)
+	}
+}
+
+func TestFlattenAndHalt(t *testing.T) {
+	ctx := context.Background()
+	acc := models.NewAccount("acc", "ex")
+	acc.UpdatePosition("ethusdt", decimal.NewFromInt(-2), decimal.NewFromInt(100), time.Now())
+
+	api := &fakeAPI{}
+	o := New(api, acc, nil)
+	if _, err := o.PlaceOrder(ctx, models.Order{Symbol: "ethusdt", Type: models.OrderTypeLimit}); err != nil {
+		t.Fatal(err)
+	}
+
+	o.Halt()
+	_, err := o.PlaceOrder(ctx, models.Order{Symbol: "ethusdt", Side: models.OrderSideSell, Size: decimal.NewFromInt(1)})
+	if !errors.Is(err, ErrHalted) {
+		t.Errorf("expected ErrHalted for order increasing position, got %v", err)
+	}
+
+	res, err := o.Flatten(ctx, "ethusdt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Side != models.OrderSideBuy || !res.Size.Equal(decimal.NewFromInt(2)) || res.Type != models.OrderTypeMarket {
+		t.Errorf("unexpected flatten order %+v", res)
+	}
+
+	// Limit order is canceled, flatten order is placed.
+	if open := o.OpenOrders(); len(open) != 1 || open[0].ClientOrderID != res.ClientOrderID {
+		t.Errorf("expected only flatten order to be open, got %+v", open)
+	}
+}
@@ -0,0 +1,334 @@
+// Package optimize searches strategy parameters with walk-forward
+// validation. Market data is split into consecutive segments, every
+// parameter set is replayed on each of them and fold k selects the best
+// parameters on segment k-1 (in-sample) and tests them on segment k
+// (out-of-sample), so results are not measured on data they were picked on.
+package optimize
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Space maps parameter names to values tried, names of nested
+// parameters are dotted, e.g. "sizing.percent".
+type Space map[string][]json.RawMessage
+
+// ParseSpace parses space from JSON object of value arrays.
+func ParseSpace(raw []byte) (Space, error) {
+	var s Space
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("optimize.ParseSpace failed to parse: %w", err)
+	}
+	if len(s) == 0 {
+		return nil, errors.New("optimize.ParseSpace: space is empty")
+	}
+	for name, values := range s {
+		if len(values) == 0 {
+			return nil, fmt.Errorf("optimize.ParseSpace: parameter %q has no values", name)
+		}
+	}
+	return s, nil
+}
+
+func (s Space) names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Size returns number of parameter combinations.
+func (s Space) Size() int {
+	n := 1
+	for _, values := range s {
+		n *= len(values)
+	}
+	return n
+}
+
+// at returns i-th combination of the grid.
+func (s Space) at(i int) Params {
+	p := make(Params, len(s))
+	for _, name := range s.names() {
+		values := s[name]
+		p[name] = values[i%len(values)]
+		i /= len(values)
+	}
+	return p
+}
+
+// Grid returns all parameter combinations.
+func (s Space) Grid() []Params {
+	res := make([]Params, s.Size())
+	for i := range res {
+		res[i] = s.at(i)
+	}
+	return res
+}
+
+// Random returns n distinct combinations sampled with the seed,
+// the whole grid if it is not larger.
+func (s Space) Random(n int, seed int64) []Params {
+	size := s.Size()
+	if n >= size {
+		return s.Grid()
+	}
+	perm := rand.New(rand.NewSource(seed)).Perm(size)
+	res := make([]Params, n)
+	for i := range res {
+		res[i] = s.at(perm[i])
+	}
+	return res
+}
+
+// Params is a combination of parameter values.
+type Params map[string]json.RawMessage
+
+func (p Params) String() string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, p[name]); err != nil {
+			buf.Reset()
+			buf.Write(p[name])
+		}
+		parts[i] = name + "=" + buf.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// Apply returns strategy parameters with values of p.
+func (p Params) Apply(raw json.RawMessage) (json.RawMessage, error) {
+	params := make(map[string]interface{})
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, fmt.Errorf("optimize.Apply failed to parse params: %w", err)
+		}
+	}
+
+	for name, value := range p {
+		var v interface{}
+		if err := json.Unmarshal(value, &v); err != nil {
+			return nil, fmt.Errorf("optimize.Apply failed to parse %s: %w", name, err)
+		}
+		path := strings.Split(name, ".")
+		obj := params
+		for _, key := range path[:len(path)-1] {
+			next, ok := obj[key].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				obj[key] = next
+			}
+			obj = next
+		}
+		obj[path[len(path)-1]] = v
+	}
+
+	res, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("optimize.Apply failed to marshal params: %w", err)
+	}
+	return res, nil
+}
+
+// Result is an outcome of a replay.
+type Result struct {
+	PnL         float64
+	MaxDrawdown float64
+	Trades      int
+}
+
+// Evaluate replays the segment with the parameters.
+type Evaluate func(ctx context.Context, p Params, segment int) (Result, error)
+
+// Stats are robustness statistics of parameters.
+type Stats struct {
+	Params Params
+	// Results are per segment.
+	Results []Result
+	// InSample is mean PnL of in-sample segments,
+	// OutOfSample of out-of-sample ones.
+	InSample    float64
+	OutOfSample float64
+	// StdDev is standard deviation of out-of-sample PnL.
+	StdDev float64
+	// Profitable is a share of profitable out-of-sample segments.
+	Profitable float64
+	// Efficiency is out-of-sample to in-sample mean PnL ratio,
+	// zero when in-sample PnL is not positive.
+	Efficiency  float64
+	MaxDrawdown float64
+	Trades      int
+	Err         error
+}
+
+// Fold is a walk-forward step: parameters best in-sample
+// and their result on the next segment.
+type Fold struct {
+	Segment     int
+	Params      Params
+	InSample    float64
+	OutOfSample float64
+}
+
+// Report of an optimization.
+type Report struct {
+	// Ranked are statistics sorted by out-of-sample PnL,
+	// failed parameters are the last.
+	Ranked []Stats
+	Folds  []Fold
+	// WalkForward is total out-of-sample PnL of parameters
+	// selected in folds.
+	WalkForward float64
+}
+
+// Run evaluates candidates on all segments with workers in parallel.
+func Run(ctx context.Context, candidates []Params, segments, workers int, eval Evaluate) (Report, error) {
+	if segments < 2 {
+		return Report{}, fmt.Errorf("optimize.Run: walk-forward needs at least 2 segments, got %d", segments)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	stats := make([]Stats, len(candidates))
+	for i, p := range candidates {
+		stats[i] = Stats{Params: p, Results: make([]Result, segments)}
+	}
+
+	type job struct{ candidate, segment int }
+	jobs := make(chan job)
+	var (
+		wg  sync.WaitGroup
+		mux sync.Mutex
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				res, err := eval(ctx, candidates[j.candidate], j.segment)
+
+				mux.Lock()
+				st := &stats[j.candidate]
+				if err != nil && st.Err == nil {
+					st.Err = fmt.Errorf("segment %d: %w", j.segment, err)
+				}
+				st.Results[j.segment] = res
+				mux.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := range candidates {
+		for seg := 0; seg < segments; seg++ {
+			select {
+			case jobs <- job{i, seg}:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return Report{}, err
+	}
+
+	for i := range stats {
+		stats[i].summarize()
+	}
+	report := Report{Ranked: rank(stats), Folds: folds(stats, segments)}
+	for _, f := range report.Folds {
+		report.WalkForward += f.OutOfSample
+	}
+	return report, nil
+}
+
+// summarize computes statistics from segment results.
+func (s *Stats) summarize() {
+	n := len(s.Results)
+	var inSample, outSample, sumSq float64
+	for i, r := range s.Results {
+		if i < n-1 {
+			inSample += r.PnL
+		}
+		if i > 0 {
+			outSample += r.PnL
+			if r.PnL > 0 {
+				s.Profitable++
+			}
+		}
+		s.MaxDrawdown = math.Max(s.MaxDrawdown, r.MaxDrawdown)
+		s.Trades += r.Trades
+	}
+	folds := float64(n - 1)
+	s.InSample, s.OutOfSample = inSample/folds, outSample/folds
+	s.Profitable /= folds
+	for _, r := range s.Results[1:] {
+		sumSq += (r.PnL - s.OutOfSample) * (r.PnL - s.OutOfSample)
+	}
+	if n > 2 {
+		s.StdDev = math.Sqrt(sumSq / (folds - 1))
+	}
+	if s.InSample > 0 {
+		s.Efficiency = s.OutOfSample / s.InSample
+	}
+}
+
+func rank(stats []Stats) []Stats {
+	res := append([]Stats(nil), stats...)
+	sort.SliceStable(res, func(i, j int) bool {
+		if (res[i].Err == nil) != (res[j].Err == nil) {
+			return res[i].Err == nil
+		}
+		return res[i].OutOfSample > res[j].OutOfSample
+	})
+	return res
+}
+
+// best returns index of successful stats with the best PnL on the segment.
+func best(stats []Stats, segment int) int {
+	idx := -1
+	for i, st := range stats {
+		if st.Err == nil && (idx < 0 || st.Results[segment].PnL > stats[idx].Results[segment].PnL) {
+			idx = i
+		}
+	}
+	return idx
+}
+
+func folds(stats []Stats, segments int) []Fold {
+	var res []Fold
+	for seg := 1; seg < segments; seg++ {
+		i := best(stats, seg-1)
+		if i < 0 {
+			continue
+		}
+		res = append(res, Fold{
+			Segment:     seg,
+			Params:      stats[i].Params,
+			InSample:    stats[i].Results[seg-1].PnL,
+			OutOfSample: stats[i].Results[seg].PnL,
+		})
+	}
+	return res
+}
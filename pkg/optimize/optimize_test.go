@@ -0,0 +1,89 @@
+package optimize
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSpace(t *testing.T) {
+	s, err := ParseSpace([]byte(`{"patience": [1, 2, 3], "sizing.percent": [10, 20]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grid := s.Grid()
+	seen := make(map[string]bool)
+	for _, p := range grid {
+		seen[p.String()] = true
+	}
+	if len(grid) != 6 || len(seen) != 6 || !seen["patience=2 sizing.percent=20"] {
+		t.Errorf("unexpected grid %v", seen)
+	}
+
+	random := s.Random(4, 1)
+	for _, p := range random {
+		if !seen[p.String()] {
+			t.Errorf("unexpected sample %v", p)
+		}
+		delete(seen, p.String())
+	}
+	if len(random) != 4 || len(seen) != 2 {
+		t.Errorf("expected 4 distinct samples, got %v", random)
+	}
+
+	raw, err := grid[0].Apply(json.RawMessage(`{"symbol": "ethusdt", "sizing": {"model": "percent_of_equity"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"patience":1,"sizing":{"model":"percent_of_equity","percent":10},"symbol":"ethusdt"}`
+	if string(raw) != want {
+		t.Errorf("expected %s, got %s", want, raw)
+	}
+}
+
+func TestRun(t *testing.T) {
+	s, _ := ParseSpace([]byte(`{"x": [1, 2, 3]}`))
+	// PnL of x on segments, x=3 overfits the first segment.
+	pnl := map[string][]float64{
+		"x=1": {1, 1, 1},
+		"x=2": {2, 2, -1},
+		"x=3": {5, -3, 0},
+	}
+	eval := func(_ context.Context, p Params, segment int) (Result, error) {
+		return Result{PnL: pnl[p.String()][segment], Trades: 1}, nil
+	}
+
+	report, err := Run(context.Background(), s.Grid(), 3, 2, eval)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, st := range report.Ranked {
+		order = append(order, st.Params.String())
+	}
+	if len(order) != 3 || order[0] != "x=1" || order[2] != "x=3" {
+		t.Fatalf("unexpected ranking %v", order)
+	}
+	if st := report.Ranked[0]; st.OutOfSample != 1 || st.Profitable != 1 || st.Efficiency != 1 || st.Trades != 3 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+	if st := report.Ranked[1]; st.OutOfSample != 0.5 || st.Profitable != 0.5 || st.Efficiency != 0.25 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+
+	// Parameters best in-sample fail out-of-sample.
+	if len(report.Folds) != 2 || report.Folds[0].Params.String() != "x=3" || report.Folds[1].Params.String() != "x=2" {
+		t.Fatalf("unexpected folds %+v", report.Folds)
+	}
+	if report.WalkForward != -4 {
+		t.Errorf("expected walk-forward PnL -4, got %v", report.WalkForward)
+	}
+
+	failing := func(context.Context, Params, int) (Result, error) { return Result{}, errors.New("boom") }
+	if report, err := Run(context.Background(), s.Grid(), 2, 1, failing); err != nil || report.Ranked[0].Err == nil || len(report.Folds) != 0 {
+		t.Errorf("expected failed stats, got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,334 @@
+package paper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrNoMarketData  = errors.New("no market data for symbol")
+	ErrOrderNotFound = errors.New("order not found")
+	ErrWouldTake     = errors.New("post-only order would take liquidity")
+)
+
+// Config of simulated account.
+type Config struct {
+	// Asset is a margin asset, PnL and fees are settled in it.
+	Asset   string          `json:"asset"`
+	Balance decimal.Decimal `json:"balance"`
+	// TakerFee and MakerFee are fee rates relative to notional.
+	TakerFee decimal.Decimal `json:"taker_fee"`
+	MakerFee decimal.Decimal `json:"maker_fee"`
+}
+
+type position struct {
+	amount, entry decimal.Decimal
+}
+
+// maxFinishedOrders is a number of finished orders kept for QueryOrder.
+const maxFinishedOrders = 1000
+
+// Exchange simulates perpetual futures exchange account filling orders
+// against best bid and offer fed with OnBBO. Market orders are filled
+// at the opposite side of BBO, limit orders when BBO crosses their price.
+// Order, fill, balance and position updates are passed to emit
+// the same way a real connector sends them from user data stream.
+type Exchange struct {
+	exchange string
+	account  string
+	cfg      Config
+	emit     func(models.ExchangeMessage)
+
+	balance   decimal.Decimal
+	positions map[string]position
+	books     map[string]models.BBO
+	orders    map[string]*models.Order
+	finished  []string
+	nextID    int64
+	now       time.Time
+
+	mux sync.Mutex
+}
+
+func New(exchange, account string, cfg Config, emit func(models.ExchangeMessage)) *Exchange {
+	return &Exchange{
+		exchange:  exchange,
+		account:   account,
+		cfg:       cfg,
+		emit:      emit,
+		balance:   cfg.Balance,
+		positions: make(map[string]position),
+		books:     make(map[string]models.BBO),
+		orders:    make(map[string]*models.Order),
+	}
+}
+
+// nowLocked returns time of the last market data update,
+// so backtests use recorded time instead of wall clock.
+func (e *Exchange) nowLocked() time.Time {
+	if e.now.IsZero() {
+		return time.Now().UTC()
+	}
+	return e.now
+}
+
+func (e *Exchange) send(msgs []models.ExchangeMessage) {
+	for _, msg := range msgs {
+		msg.Account = e.account
+		e.emit(msg)
+	}
+}
+
+// OnBBO updates market state and fills resting limit orders crossed by it.
+func (e *Exchange) OnBBO(symbol string, bbo models.BBO, ts time.Time) {
+	e.mux.Lock()
+	e.books[symbol] = bbo
+	if !ts.IsZero() {
+		e.now = ts
+	}
+
+	var msgs []models.ExchangeMessage
+	for _, o := range e.orders {
+		if o.Symbol != symbol || o.Status.IsFinal() {
+			continue
+		}
+		if (o.Side == models.OrderSideBuy && bbo.Ask.Price.LessThanOrEqual(o.Price)) ||
+			(o.Side == models.OrderSideSell && bbo.Bid.Price.GreaterThanOrEqual(o.Price)) {
+			msgs = append(msgs, e.fillLocked(o, o.Price, true)...)
+		}
+	}
+	e.mux.Unlock()
+
+	e.send(msgs)
+}
+
+func (e *Exchange) PlaceOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	e.mux.Lock()
+
+	bbo, ok := e.books[order.Symbol]
+	if !ok {
+		e.mux.Unlock()
+		return nil, fmt.Errorf("paper.PlaceOrder %s: %w", order.Symbol, ErrNoMarketData)
+	}
+
+	e.nextID++
+	o := order
+	o.ExchangeOrderID = strconv.FormatInt(e.nextID, 10)
+	o.Status = models.OrderStatusPlaced
+	o.UpdatedAt = e.nowLocked()
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = o.UpdatedAt
+	}
+
+	price := bbo.Ask.Price
+	if o.Side == models.OrderSideSell {
+		price = bbo.Bid.Price
+	}
+	marketable := o.Type == models.OrderTypeMarket ||
+		(o.Side == models.OrderSideBuy && price.LessThanOrEqual(o.Price)) ||
+		(o.Side == models.OrderSideSell && price.GreaterThanOrEqual(o.Price))
+
+	if marketable && o.TimeInForce == models.TimeInForceGTX {
+		e.mux.Unlock()
+		return nil, fmt.Errorf("paper.PlaceOrder: %w", ErrWouldTake)
+	}
+
+	e.orders[o.ClientOrderID] = &o
+	msgs := []models.ExchangeMessage{e.orderUpdateLocked(&o)}
+	switch {
+	case marketable:
+		msgs = append(msgs, e.fillLocked(&o, price, false)...)
+	case o.TimeInForce == models.TimeInForceIOC || o.TimeInForce == models.TimeInForceFOK:
+		msgs = append(msgs, e.finishLocked(&o, models.OrderStatusCanceled))
+	}
+	res := o
+	e.mux.Unlock()
+
+	e.send(msgs)
+	return &res, nil
+}
+
+func (e *Exchange) CancelOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	e.mux.Lock()
+
+	o, ok := e.orders[order.ClientOrderID]
+	if !ok {
+		e.mux.Unlock()
+		return nil, fmt.Errorf("paper.CancelOrder %s: %w", order.ClientOrderID, ErrOrderNotFound)
+	}
+	if o.Status.IsFinal() {
+		e.mux.Unlock()
+		return nil, fmt.Errorf("paper.CancelOrder %s is %s: %w", order.ClientOrderID, o.Status, ErrOrderNotFound)
+	}
+
+	msg := e.finishLocked(o, models.OrderStatusCanceled)
+	res := *o
+	e.mux.Unlock()
+
+	e.send([]models.ExchangeMessage{msg})
+	return &res, nil
+}
+
+func (e *Exchange) QueryOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	o, ok := e.orders[order.ClientOrderID]
+	if !ok {
+		return nil, fmt.Errorf("paper.QueryOrder %s: %w", order.ClientOrderID, ErrOrderNotFound)
+	}
+
+	res := *o
+	return &res, nil
+}
+
+func (e *Exchange) GetOpenOrders(context.Context) ([]models.Order, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	var orders []models.Order
+	for _, o := range e.orders {
+		if !o.Status.IsFinal() {
+			orders = append(orders, *o)
+		}
+	}
+	return orders, nil
+}
+
+func (e *Exchange) GetAccountState(context.Context) ([]models.BalanceUpdate, []models.PositionUpdate, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	balances := []models.BalanceUpdate{{
+		Asset:   e.cfg.Asset,
+		Balance: e.balance,
+		Reason:  models.BalanceUpdateReasonOther,
+	}}
+
+	var positions []models.PositionUpdate
+	for symbol, p := range e.positions {
+		if !p.amount.IsZero() {
+			positions = append(positions, models.PositionUpdate{
+				Symbol:     symbol,
+				Amount:     p.amount,
+				EntryPrice: p.entry,
+			})
+		}
+	}
+
+	return balances, positions, nil
+}
+
+func (e *Exchange) orderUpdateLocked(o *models.Order) models.ExchangeMessage {
+	return models.NewOrderUpdateMessage(e.exchange, o.Symbol, o.UpdatedAt, models.OrderUpdate{
+		ClientOrderID:   o.ClientOrderID,
+		ExchangeOrderID: o.ExchangeOrderID,
+		UpdatedAt:       o.UpdatedAt,
+		Status:          o.Status,
+		Side:            o.Side,
+		Symbol:          o.Symbol,
+		FilledSize:      o.FilledSize,
+		AveragePrice:    o.AveragePrice,
+	})
+}
+
+func (e *Exchange) finishLocked(o *models.Order, status models.OrderStatus) models.ExchangeMessage {
+	o.Status = status
+	o.UpdatedAt = e.nowLocked()
+
+	e.finished = append(e.finished, o.ClientOrderID)
+	if len(e.finished) > maxFinishedOrders {
+		delete(e.orders, e.finished[0])
+		e.finished = e.finished[1:]
+	}
+
+	return e.orderUpdateLocked(o)
+}
+
+// fillLocked fully fills the order at price, updating position and balance.
+func (e *Exchange) fillLocked(o *models.Order, price decimal.Decimal, maker bool) []models.ExchangeMessage {
+	ts := e.nowLocked()
+	qty := o.Size.Sub(o.FilledSize)
+
+	feeRate := e.cfg.TakerFee
+	if maker {
+		feeRate = e.cfg.MakerFee
+	}
+	fee := qty.Mul(price).Mul(feeRate)
+
+	realized := e.applyFillLocked(o.Symbol, o.Side, qty, price)
+	e.balance = e.balance.Add(realized).Sub(fee)
+
+	o.AveragePrice = price
+	o.FilledSize = o.Size
+	status := e.finishLocked(o, models.OrderStatusFilled)
+
+	e.nextID++
+	p := e.positions[o.Symbol]
+	return []models.ExchangeMessage{
+		models.NewFillMessage(e.exchange, ts, models.Fill{
+			ClientOrderID:   o.ClientOrderID,
+			ExchangeOrderID: o.ExchangeOrderID,
+			TradeID:         strconv.FormatInt(e.nextID, 10),
+			Symbol:          o.Symbol,
+			Side:            o.Side,
+			Size:            qty,
+			Price:           price,
+			Commission:      fee,
+			CommissionAsset: e.cfg.Asset,
+			RealizedProfit:  realized,
+			IsMaker:         maker,
+			Timestamp:       ts,
+		}),
+		status,
+		models.NewBalanceUpdateMessage(e.exchange, ts, models.BalanceUpdate{
+			Asset:   e.cfg.Asset,
+			Balance: e.balance,
+			Reason:  models.BalanceUpdateReasonOrder,
+		}),
+		models.NewPositionUpdateMessage(e.exchange, ts, models.PositionUpdate{
+			Symbol:     o.Symbol,
+			Amount:     p.amount,
+			EntryPrice: p.entry,
+		}),
+	}
+}
+
+// applyFillLocked updates position and returns realized PnL.
+func (e *Exchange) applyFillLocked(symbol string, side models.OrderSide, qty, price decimal.Decimal) decimal.Decimal {
+	p := e.positions[symbol]
+	signed := qty
+	if side == models.OrderSideSell {
+		signed = qty.Neg()
+	}
+
+	realized := decimal.Zero
+	switch {
+	case p.amount.IsZero() || p.amount.Sign() == signed.Sign():
+		total := p.amount.Abs().Add(qty)
+		p.entry = p.amount.Abs().Mul(p.entry).Add(qty.Mul(price)).Div(total)
+	default:
+		closed := decimal.Min(qty, p.amount.Abs())
+		realized = price.Sub(p.entry).Mul(closed)
+		if p.amount.IsNegative() {
+			realized = realized.Neg()
+		}
+		if qty.GreaterThan(p.amount.Abs()) {
+			p.entry = price
+		} else if qty.Equal(p.amount.Abs()) {
+			p.entry = decimal.Zero
+		}
+	}
+
+	p.amount = p.amount.Add(signed)
+	e.positions[symbol] = p
+	return realized
+}
@@ -0,0 +1,107 @@
+package paper
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func bbo(bid, ask int64) models.BBO {
+	return models.BBO{
+		Bid: models.PriceLevel{Price: decimal.NewFromInt(bid), Size: decimal.NewFromInt(1)},
+		Ask: models.PriceLevel{Price: decimal.NewFromInt(ask), Size: decimal.NewFromInt(1)},
+	}
+}
+
+func TestPaperExchange(t *testing.T) {
+	var msgs []models.ExchangeMessage
+	ex := New("test", "acc", Config{
+		Asset:    "usdt",
+		Balance:  decimal.NewFromInt(1000),
+		TakerFee: decimal.NewFromFloat(0.001),
+	}, func(msg models.ExchangeMessage) {
+		msgs = append(msgs, msg)
+	})
+	ctx := context.Background()
+
+	_, err := ex.PlaceOrder(ctx, models.Order{ClientOrderID: "0", Symbol: "ethusdt"})
+	if !errors.Is(err, ErrNoMarketData) {
+		t.Fatalf("expected ErrNoMarketData, got %v", err)
+	}
+
+	ex.OnBBO("ethusdt", bbo(99, 100), time.Unix(1, 0))
+
+	// Market buy fills at ask.
+	_, err = ex.PlaceOrder(ctx, models.Order{
+		ClientOrderID: "1",
+		Symbol:        "ethusdt",
+		Side:          models.OrderSideBuy,
+		Type:          models.OrderTypeMarket,
+		Size:          decimal.NewFromInt(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fill, ok := msgs[1].Fill()
+	if !ok || !fill.Price.Equal(decimal.NewFromInt(100)) || !fill.Commission.Equal(decimal.NewFromFloat(0.2)) {
+		t.Fatalf("unexpected fill %+v", fill)
+	}
+	if msgs[1].Account != "acc" {
+		t.Errorf("expected account to be set, got %q", msgs[1].Account)
+	}
+
+	// Post-only sell crossing bid is rejected.
+	_, err = ex.PlaceOrder(ctx, models.Order{
+		ClientOrderID: "2",
+		Symbol:        "ethusdt",
+		Side:          models.OrderSideSell,
+		Type:          models.OrderTypeLimit,
+		TimeInForce:   models.TimeInForceGTX,
+		Price:         decimal.NewFromInt(99),
+		Size:          decimal.NewFromInt(2),
+	})
+	if !errors.Is(err, ErrWouldTake) {
+		t.Fatalf("expected ErrWouldTake, got %v", err)
+	}
+
+	// Resting sell is filled when bid reaches its price.
+	_, err = ex.PlaceOrder(ctx, models.Order{
+		ClientOrderID: "3",
+		Symbol:        "ethusdt",
+		Side:          models.OrderSideSell,
+		Type:          models.OrderTypeLimit,
+		TimeInForce:   models.TimeInForceGTC,
+		Price:         decimal.NewFromInt(110),
+		Size:          decimal.NewFromInt(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open, _ := ex.GetOpenOrders(ctx)
+	if len(open) != 1 {
+		t.Fatalf("expected 1 open order, got %d", len(open))
+	}
+
+	ex.OnBBO("ethusdt", bbo(110, 111), time.Unix(2, 0))
+
+	balances, positions, _ := ex.GetAccountState(ctx)
+	// 1000 - 0.2 fee + 20 realized, maker fee is zero.
+	if !balances[0].Balance.Equal(decimal.NewFromFloat(1019.8)) {
+		t.Errorf("unexpected balance %v", balances[0].Balance)
+	}
+	if len(positions) != 0 {
+		t.Errorf("expected flat position, got %+v", positions)
+	}
+
+	o, err := ex.QueryOrder(ctx, models.Order{ClientOrderID: "3"})
+	if err != nil || o.Status != models.OrderStatusFilled {
+		t.Errorf("expected filled order, got %+v, %v", o, err)
+	}
+}
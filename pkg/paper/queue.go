@@ -0,0 +1,60 @@
+package paper
+
+import (
+	"context"
+	"sync"
+
+	"degen/pkg/models"
+)
+
+// Queue passes updates of simulated exchanges to emit in order from
+// its own goroutine. Consumers of updates place orders while handling
+// them, so simulated exchanges must not wait for them the way a real
+// exchange does not.
+type Queue struct {
+	emit func(models.ExchangeMessage)
+	msgs []models.ExchangeMessage
+	wake chan struct{}
+	mux  sync.Mutex
+}
+
+// NewQueue starts passing queued updates to emit until ctx is done.
+func NewQueue(ctx context.Context, emit func(models.ExchangeMessage)) *Queue {
+	q := &Queue{
+		emit: emit,
+		wake: make(chan struct{}, 1),
+	}
+	go q.run(ctx)
+	return q
+}
+
+// Emit queues the update without blocking.
+func (q *Queue) Emit(msg models.ExchangeMessage) {
+	q.mux.Lock()
+	q.msgs = append(q.msgs, msg)
+	q.mux.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) run(ctx context.Context) {
+	for {
+		select {
+		case <-q.wake:
+		case <-ctx.Done():
+			return
+		}
+
+		q.mux.Lock()
+		msgs := q.msgs
+		q.msgs = nil
+		q.mux.Unlock()
+
+		for _, msg := range msgs {
+			q.emit(msg)
+		}
+	}
+}
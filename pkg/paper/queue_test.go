@@ -0,0 +1,64 @@
+package paper
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The consumer is stuck until released, as a strategy placing
+	// orders from a full subscription would be.
+	release := make(chan struct{})
+	got := make(chan models.ExchangeMessage, 1000)
+	q := NewQueue(ctx, func(msg models.ExchangeMessage) {
+		<-release
+		got <- msg
+	})
+
+	ex := New("test", "acc", Config{Asset: "usdt"}, q.Emit)
+	ex.OnBBO("ethusdt", bbo(99, 100), time.Unix(1, 0))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			_, _ = ex.PlaceOrder(ctx, models.Order{
+				ClientOrderID: strconv.Itoa(i),
+				Symbol:        "ethusdt",
+				Side:          models.OrderSideBuy,
+				Type:          models.OrderTypeLimit,
+				Size:          decimal.NewFromInt(1),
+				Price:         decimal.NewFromInt(90),
+			})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("paper exchange waits for the consumer")
+	}
+
+	close(release)
+	next := 0
+	for next < 200 {
+		select {
+		case msg := <-got:
+			if u, ok := msg.OrderUpdate(); ok {
+				if u.ClientOrderID != strconv.Itoa(next) {
+					t.Fatalf("expected update of order %d, got %s", next, u.ClientOrderID)
+				}
+				next++
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected 200 order updates, got %d", next)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+package pnl
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// SymbolPnL is PnL of a position in one symbol.
+// Fees are positive when paid, funding is positive when received.
+// Fees are in the quote asset, fees paid in other assets, e.g. bnb,
+// are kept in OtherFees by asset and are not counted in Total.
+type SymbolPnL struct {
+	Exchange   string
+	Account    string
+	Strategy   string
+	Symbol     string
+	Position   decimal.Decimal
+	AvgEntry   decimal.Decimal
+	MarkPrice  decimal.Decimal
+	Realized   decimal.Decimal
+	Unrealized decimal.Decimal
+	Fees       decimal.Decimal
+	OtherFees  map[string]decimal.Decimal
+	Funding    decimal.Decimal
+	UpdatedAt  time.Time
+}
+
+// Total returns net PnL: realized + unrealized - fees + funding.
+func (p SymbolPnL) Total() decimal.Decimal {
+	return p.Realized.Add(p.Unrealized).Sub(p.Fees).Add(p.Funding)
+}
+
+// AccountPnL sums PnL of all account symbols.
+type AccountPnL struct {
+	Exchange   string
+	Account    string
+	Realized   decimal.Decimal
+	Unrealized decimal.Decimal
+	Fees       decimal.Decimal
+	OtherFees  map[string]decimal.Decimal
+	Funding    decimal.Decimal
+}
+
+func (p AccountPnL) Total() decimal.Decimal {
+	return p.Realized.Add(p.Unrealized).Sub(p.Fees).Add(p.Funding)
+}
+
+// Snapshot is a point in time copy of the tracker state.
+type Snapshot struct {
+	Time       time.Time
+	Accounts   []AccountPnL
+	Symbols    []SymbolPnL
+	Strategies []SymbolPnL
+}
+
+type key struct {
+	exchange, account, strategy, symbol string
+}
+
+type markKey struct {
+	exchange, symbol string
+}
+
+// orderState keeps cumulative fills of an order to derive
+// individual fills from cumulative order updates.
+type orderState struct {
+	exchange, account, strategy string
+
+	filled     decimal.Decimal
+	avgPrice   decimal.Decimal
+	finishedAt time.Time
+
+	// trades is set when order fills come from OnFill,
+	// so cumulative order updates are not used for PnL.
+	trades map[string]struct{}
+}
+
+const finishedOrderTTL = time.Hour
+
+// Tracker calculates PnL per account and symbol, and per strategy
+// for orders registered with TrackOrder.
+type Tracker struct {
+	symbols    map[key]*SymbolPnL
+	strategies map[key]*SymbolPnL
+	marks      map[markKey]decimal.Decimal
+	orders     map[string]*orderState
+
+	mux sync.RWMutex
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		symbols:    make(map[key]*SymbolPnL),
+		strategies: make(map[key]*SymbolPnL),
+		marks:      make(map[markKey]decimal.Decimal),
+		orders:     make(map[string]*orderState),
+	}
+}
+
+func orderKey(clientOrderID, exchangeOrderID string) string {
+	if clientOrderID != "" {
+		return clientOrderID
+	}
+	return exchangeOrderID
+}
+
+// TrackOrder attributes fills of the order to the strategy.
+func (t *Tracker) TrackOrder(acc *models.Account, strategy string, order models.Order) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.orders[orderKey(order.ClientOrderID, order.ExchangeOrderID)] = &orderState{
+		exchange: acc.Exchange(),
+		account:  acc.ID(),
+		strategy: strategy,
+	}
+}
+
+func (t *Tracker) order(acc *models.Account, clientOrderID, exchangeOrderID string) *orderState {
+	k := orderKey(clientOrderID, exchangeOrderID)
+	st, ok := t.orders[k]
+	if !ok {
+		st = &orderState{
+			exchange: acc.Exchange(),
+			account:  acc.ID(),
+		}
+		t.orders[k] = st
+	}
+
+	return st
+}
+
+// OnFill applies a single order execution with its commission.
+// Commission in the base asset is converted to the quote asset
+// at the fill price, commission in other assets is kept apart.
+// If fills are fed, they should precede order updates of the same
+// execution, so the updates are not counted twice.
+func (t *Tracker) OnFill(acc *models.Account, fill models.Fill) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	st := t.order(acc, fill.ClientOrderID, fill.ExchangeOrderID)
+	if st.trades == nil {
+		st.trades = make(map[string]struct{})
+	}
+	if _, ok := st.trades[fill.TradeID]; ok {
+		return
+	}
+	st.trades[fill.TradeID] = struct{}{}
+
+	notional := st.filled.Mul(st.avgPrice).Add(fill.Size.Mul(fill.Price))
+	st.filled = st.filled.Add(fill.Size)
+	if st.filled.IsPositive() {
+		st.avgPrice = notional.Div(st.filled)
+	}
+
+	t.applyFill(st.exchange, st.account, st.strategy, fill.Symbol, fill.Side, fill.Size, fill.Price, fill.Timestamp)
+	fee, quoted := quoteFee(fill)
+	for _, p := range t.books(st.exchange, st.account, st.strategy, fill.Symbol) {
+		if quoted {
+			p.Fees = p.Fees.Add(fee)
+			continue
+		}
+		if p.OtherFees == nil {
+			p.OtherFees = make(map[string]decimal.Decimal)
+		}
+		p.OtherFees[fill.CommissionAsset] = p.OtherFees[fill.CommissionAsset].Add(fee)
+	}
+}
+
+// quoteFee returns commission of the fill in the quote asset of its
+// symbol or, if it is in another asset, as is and false. Commission
+// without asset is expected to be in the quote asset.
+func quoteFee(fill models.Fill) (decimal.Decimal, bool) {
+	pair := strings.SplitN(fill.Symbol, "_", 2)[0]
+	switch asset := fill.CommissionAsset; {
+	case asset == "" || strings.HasSuffix(pair, asset):
+		return fill.Commission, true
+	case strings.HasPrefix(pair, asset):
+		return fill.Commission.Mul(fill.Price), true
+	}
+	return fill.Commission, false
+}
+
+// OnOrderUpdate derives fill from cumulative filled size and average
+// price of the order update, unless fills of the order come from OnFill.
+// Fills of untracked orders are not attributed to any strategy.
+func (t *Tracker) OnOrderUpdate(acc *models.Account, upd models.OrderUpdate) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	st := t.order(acc, upd.ClientOrderID, upd.ExchangeOrderID)
+
+	switch upd.Status {
+	case models.OrderStatusFilled, models.OrderStatusCanceled, models.OrderStatusRejected:
+		// Finished orders are kept for a while to ignore repeated updates.
+		if st.finishedAt.IsZero() {
+			st.finishedAt = time.Now()
+			t.pruneOrders()
+		}
+	}
+
+	if st.trades != nil || !upd.FilledSize.GreaterThan(st.filled) {
+		return
+	}
+
+	qty := upd.FilledSize.Sub(st.filled)
+	notional := upd.FilledSize.Mul(upd.AveragePrice).Sub(st.filled.Mul(st.avgPrice))
+	price := notional.Div(qty)
+	st.filled = upd.FilledSize
+	st.avgPrice = upd.AveragePrice
+
+	t.applyFill(st.exchange, st.account, st.strategy, upd.Symbol, upd.Side, qty, price, upd.UpdatedAt)
+}
+
+func (t *Tracker) pruneOrders() {
+	for k, st := range t.orders {
+		if !st.finishedAt.IsZero() && time.Since(st.finishedAt) > finishedOrderTTL {
+			delete(t.orders, k)
+		}
+	}
+}
+
+// applyFill updates position, average entry and realized PnL.
+func (t *Tracker) applyFill(
+	exchange, account, strategy, symbol string,
+	side models.OrderSide,
+	qty, price decimal.Decimal,
+	ts time.Time,
+) {
+	signed := qty
+	if side == models.OrderSideSell {
+		signed = qty.Neg()
+	}
+
+	for _, p := range t.books(exchange, account, strategy, symbol) {
+		pos := p.Position
+		switch {
+		case pos.IsZero() || pos.Sign() == signed.Sign():
+			// Increasing position.
+			total := pos.Abs().Add(qty)
+			p.AvgEntry = pos.Abs().Mul(p.AvgEntry).Add(qty.Mul(price)).Div(total)
+		default:
+			// Reducing, closing or flipping position.
+			closed := decimal.Min(qty, pos.Abs())
+			pnl := price.Sub(p.AvgEntry).Mul(closed)
+			if pos.IsNegative() {
+				pnl = pnl.Neg()
+			}
+			p.Realized = p.Realized.Add(pnl)
+
+			if qty.GreaterThan(pos.Abs()) {
+				p.AvgEntry = price
+			} else if qty.Equal(pos.Abs()) {
+				p.AvgEntry = decimal.Zero
+			}
+		}
+
+		p.Position = pos.Add(signed)
+		p.UpdatedAt = ts
+		t.updateUnrealized(p)
+	}
+}
+
+// AddFee adds fee paid for a trade.
+func (t *Tracker) AddFee(acc *models.Account, strategy, symbol string, fee decimal.Decimal) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	for _, p := range t.books(acc.Exchange(), acc.ID(), strategy, symbol) {
+		p.Fees = p.Fees.Add(fee)
+	}
+}
+
+// AddFunding adds funding received (positive) or paid (negative).
+// Symbol can be empty if exchange reports funding per account.
+// Funding is not attributed to strategies.
+func (t *Tracker) AddFunding(acc *models.Account, symbol string, amount decimal.Decimal) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	p := book(t.symbols, key{acc.Exchange(), acc.ID(), "", symbol})
+	p.Funding = p.Funding.Add(amount)
+}
+
+// SetMark updates mark price used for unrealized PnL.
+func (t *Tracker) SetMark(exchange, symbol string, price decimal.Decimal) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.marks[markKey{exchange, symbol}] = price
+	for k, p := range t.symbols {
+		if k.exchange == exchange && k.symbol == symbol {
+			t.updateUnrealized(p)
+		}
+	}
+	for k, p := range t.strategies {
+		if k.exchange == exchange && k.symbol == symbol {
+			t.updateUnrealized(p)
+		}
+	}
+}
+
+// OnBBO uses mid price as a mark price.
+func (t *Tracker) OnBBO(exchange, symbol string, bbo models.BBO) {
+	mid := bbo.Bid.Price.Add(bbo.Ask.Price).Div(decimal.NewFromInt(2))
+	t.SetMark(exchange, symbol, mid)
+}
+
+func (t *Tracker) updateUnrealized(p *SymbolPnL) {
+	mark, ok := t.marks[markKey{p.Exchange, p.Symbol}]
+	if !ok || p.Position.IsZero() {
+		p.Unrealized = decimal.Zero
+		return
+	}
+
+	p.MarkPrice = mark
+	p.Unrealized = mark.Sub(p.AvgEntry).Mul(p.Position)
+}
+
+// books returns account level and strategy level PnL records to update.
+func (t *Tracker) books(exchange, account, strategy, symbol string) []*SymbolPnL {
+	return []*SymbolPnL{
+		book(t.symbols, key{exchange, account, "", symbol}),
+		book(t.strategies, key{exchange, account, strategy, symbol}),
+	}
+}
+
+func book(m map[key]*SymbolPnL, k key) *SymbolPnL {
+	p, ok := m[k]
+	if !ok {
+		p = &SymbolPnL{
+			Exchange: k.exchange,
+			Account:  k.account,
+			Strategy: k.strategy,
+			Symbol:   k.symbol,
+		}
+		m[k] = p
+	}
+	return p
+}
+
+// copy returns a copy not sharing fees by asset.
+func (p *SymbolPnL) copy() SymbolPnL {
+	c := *p
+	if p.OtherFees != nil {
+		c.OtherFees = make(map[string]decimal.Decimal, len(p.OtherFees))
+		for asset, fee := range p.OtherFees {
+			c.OtherFees[asset] = fee
+		}
+	}
+	return c
+}
+
+// Snapshot returns copy of current PnL state sorted by account,
+// strategy and symbol.
+func (t *Tracker) Snapshot() Snapshot {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+
+	snap := Snapshot{Time: time.Now().UTC()}
+	accounts := make(map[key]*AccountPnL)
+	for k, p := range t.symbols {
+		snap.Symbols = append(snap.Symbols, p.copy())
+
+		ak := key{exchange: k.exchange, account: k.account}
+		acc, ok := accounts[ak]
+		if !ok {
+			acc = &AccountPnL{Exchange: k.exchange, Account: k.account}
+			accounts[ak] = acc
+		}
+		acc.Realized = acc.Realized.Add(p.Realized)
+		acc.Unrealized = acc.Unrealized.Add(p.Unrealized)
+		acc.Fees = acc.Fees.Add(p.Fees)
+		for asset, fee := range p.OtherFees {
+			if acc.OtherFees == nil {
+				acc.OtherFees = make(map[string]decimal.Decimal)
+			}
+			acc.OtherFees[asset] = acc.OtherFees[asset].Add(fee)
+		}
+		acc.Funding = acc.Funding.Add(p.Funding)
+	}
+
+	for _, p := range t.strategies {
+		snap.Strategies = append(snap.Strategies, p.copy())
+	}
+
+	for _, acc := range accounts {
+		snap.Accounts = append(snap.Accounts, *acc)
+	}
+
+	sortPnL(snap.Symbols)
+	sortPnL(snap.Strategies)
+	sort.Slice(snap.Accounts, func(i, j int) bool {
+		a, b := snap.Accounts[i], snap.Accounts[j]
+		if a.Exchange != b.Exchange {
+			return a.Exchange < b.Exchange
+		}
+		return a.Account < b.Account
+	})
+
+	return snap
+}
+
+func sortPnL(s []SymbolPnL) {
+	sort.Slice(s, func(i, j int) bool {
+		a, b := s[i], s[j]
+		if a.Exchange != b.Exchange {
+			return a.Exchange < b.Exchange
+		}
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		if a.Strategy != b.Strategy {
+			return a.Strategy < b.Strategy
+		}
+		return a.Symbol < b.Symbol
+	})
+}
+
+// Restore replaces tracker positions and PnL with snapshot ones.
+// Order states are not restored, so fills of orders placed before
+// the snapshot are not attributed to strategies.
+func (t *Tracker) Restore(snap Snapshot) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.symbols = make(map[key]*SymbolPnL, len(snap.Symbols))
+	t.strategies = make(map[key]*SymbolPnL, len(snap.Strategies))
+	for i := range snap.Symbols {
+		p := snap.Symbols[i]
+		t.symbols[key{p.Exchange, p.Account, "", p.Symbol}] = &p
+		if p.MarkPrice.IsPositive() {
+			t.marks[markKey{p.Exchange, p.Symbol}] = p.MarkPrice
+		}
+	}
+	for i := range snap.Strategies {
+		p := snap.Strategies[i]
+		t.strategies[key{p.Exchange, p.Account, p.Strategy, p.Symbol}] = &p
+	}
+}
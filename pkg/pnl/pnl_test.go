@@ -0,0 +1,158 @@
+package pnl
+
+import (
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func update(id string, side models.OrderSide, filled, avg string) models.OrderUpdate {
+	return models.OrderUpdate{
+		ClientOrderID: id,
+		Symbol:        "ethusdt",
+		Side:          side,
+		Status:        models.OrderStatusPartiallyFilled,
+		FilledSize:    d(filled),
+		AveragePrice:  d(avg),
+		UpdatedAt:     time.Now(),
+	}
+}
+
+func TestTracker(t *testing.T) {
+	acc := models.NewAccount("acc", "test")
+	tr := NewTracker()
+	tr.TrackOrder(acc, "monkey", models.Order{ClientOrderID: "1"})
+	tr.TrackOrder(acc, "monkey", models.Order{ClientOrderID: "2"})
+
+	// Buy 2 in two fills: 1 at 100 and 1 at 110.
+	tr.OnOrderUpdate(acc, update("1", models.OrderSideBuy, "1", "100"))
+	tr.OnOrderUpdate(acc, update("1", models.OrderSideBuy, "2", "105"))
+	tr.AddFee(acc, "monkey", "ethusdt", d("0.5"))
+
+	// Sell 3 at 120: realize 2*(120-105) = 30, flip to short 1 at 120.
+	tr.OnOrderUpdate(acc, update("2", models.OrderSideSell, "3", "120"))
+
+	tr.SetMark("test", "ethusdt", d("115"))
+	tr.AddFunding(acc, "ethusdt", d("-0.1"))
+
+	snap := tr.Snapshot()
+	if len(snap.Symbols) != 1 || len(snap.Strategies) != 1 || len(snap.Accounts) != 1 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+
+	p := snap.Strategies[0]
+	if p.Strategy != "monkey" {
+		t.Errorf("expected monkey strategy, got %q", p.Strategy)
+	}
+
+	checks := []struct {
+		name     string
+		got      decimal.Decimal
+		expected string
+	}{
+		{"position", p.Position, "-1"},
+		{"avg entry", p.AvgEntry, "120"},
+		{"realized", p.Realized, "30"},
+		{"unrealized", p.Unrealized, "5"},
+		{"fees", p.Fees, "0.5"},
+		{"total", snap.Accounts[0].Total(), "34.4"},
+	}
+
+	for _, c := range checks {
+		if !c.got.Equal(d(c.expected)) {
+			t.Errorf("expected %s %s, got %v", c.name, c.expected, c.got)
+		}
+	}
+}
+
+func TestUntrackedOrder(t *testing.T) {
+	acc := models.NewAccount("acc", "test")
+	tr := NewTracker()
+
+	upd := update("x", models.OrderSideSell, "2", "50")
+	upd.Status = models.OrderStatusFilled
+	tr.OnOrderUpdate(acc, upd)
+	// Repeated update must not be counted twice.
+	tr.OnOrderUpdate(acc, upd)
+
+	snap := tr.Snapshot()
+	if len(snap.Symbols) != 1 || !snap.Symbols[0].Position.Equal(d("-2")) {
+		t.Errorf("unexpected snapshot %+v", snap.Symbols)
+	}
+
+	if snap.Strategies[0].Strategy != "" {
+		t.Errorf("expected unattributed strategy, got %q", snap.Strategies[0].Strategy)
+	}
+}
+
+func TestFills(t *testing.T) {
+	acc := models.NewAccount("acc", "test")
+	tr := NewTracker()
+	tr.TrackOrder(acc, "monkey", models.Order{ClientOrderID: "1"})
+
+	fill := models.Fill{
+		ClientOrderID: "1",
+		TradeID:       "1",
+		Symbol:        "ethusdt",
+		Side:          models.OrderSideBuy,
+		Size:          d("1"),
+		Price:         d("100"),
+		Commission:    d("0.04"),
+	}
+	tr.OnFill(acc, fill)
+	// Repeated fill is ignored.
+	tr.OnFill(acc, fill)
+
+	fill.TradeID = "2"
+	fill.Price = d("110")
+	tr.OnFill(acc, fill)
+
+	// Order update of the same executions must not be counted.
+	upd := update("1", models.OrderSideBuy, "2", "105")
+	upd.Status = models.OrderStatusFilled
+	tr.OnOrderUpdate(acc, upd)
+
+	p := tr.Snapshot().Strategies[0]
+	if !p.Position.Equal(d("2")) || !p.AvgEntry.Equal(d("105")) || !p.Fees.Equal(d("0.08")) {
+		t.Errorf("unexpected PnL %+v", p)
+	}
+}
+
+func TestFillFeeAssets(t *testing.T) {
+	acc := models.NewAccount("acc", "test")
+	tr := NewTracker()
+
+	fill := func(id, asset, commission string) {
+		tr.OnFill(acc, models.Fill{
+			ClientOrderID:   id,
+			TradeID:         id,
+			Symbol:          "ethusdt",
+			Side:            models.OrderSideBuy,
+			Size:            d("1"),
+			Price:           d("100"),
+			Commission:      d(commission),
+			CommissionAsset: asset,
+		})
+	}
+	fill("1", "usdt", "0.1")
+	// Base asset commission is converted at the fill price.
+	fill("2", "eth", "0.001")
+	// Other assets are not mixed into fees.
+	fill("3", "bnb", "0.0005")
+	fill("4", "bnb", "0.0005")
+
+	p := tr.Snapshot().Accounts[0]
+	if !p.Fees.Equal(d("0.2")) || len(p.OtherFees) != 1 || !p.OtherFees["bnb"].Equal(d("0.001")) {
+		t.Errorf("unexpected fees %v %v", p.Fees, p.OtherFees)
+	}
+	if !p.Total().Equal(d("-0.2")) {
+		t.Errorf("expected total of quote fees -0.2, got %v", p.Total())
+	}
+}
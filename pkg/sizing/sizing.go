@@ -0,0 +1,297 @@
+// Package sizing turns target positions of strategies into orders.
+// Models size positions from account equity and market state, Sizer
+// trades the difference from the current position within exchange
+// filters, leverage and risk limits.
+package sizing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ModelFixed     = "fixed"
+	ModelPercent   = "percent_of_equity"
+	ModelVolTarget = "vol_target"
+	ModelKelly     = "kelly"
+)
+
+// Target is a desired position of a strategy.
+type Target struct {
+	Symbol string
+	// Position is a signed size in base asset, negative for short.
+	Position decimal.Decimal
+	// Price is a reference price notional limits are checked with.
+	Price decimal.Decimal
+}
+
+// Input is a state positions are sized with.
+type Input struct {
+	Price  decimal.Decimal
+	Equity decimal.Decimal
+	// Volatility is a standard deviation of returns over
+	// the period target volatility is set for.
+	Volatility float64
+}
+
+// Model sizes positions.
+type Model interface {
+	// Position returns absolute position size in base asset,
+	// zero when it can not be sized.
+	Position(in Input) decimal.Decimal
+}
+
+// Fixed is a position of the same size regardless of equity.
+type Fixed struct {
+	Size decimal.Decimal
+}
+
+func (m Fixed) Position(Input) decimal.Decimal {
+	return m.Size
+}
+
+// PercentOfEquity is a position of Percent of equity notional.
+type PercentOfEquity struct {
+	Percent float64
+}
+
+func (m PercentOfEquity) Position(in Input) decimal.Decimal {
+	return notional(in, m.Percent/100)
+}
+
+// VolTarget sizes position so its volatility is TargetVol of equity.
+type VolTarget struct {
+	TargetVol float64
+}
+
+func (m VolTarget) Position(in Input) decimal.Decimal {
+	if in.Volatility <= 0 {
+		return decimal.Zero
+	}
+	return notional(in, m.TargetVol/in.Volatility)
+}
+
+// Kelly sizes position at Fraction of Kelly criterion
+// for trades won with WinRate and Payoff ratio of average
+// win to average loss.
+type Kelly struct {
+	WinRate  float64
+	Payoff   float64
+	Fraction float64
+}
+
+func (m Kelly) Position(in Input) decimal.Decimal {
+	f := m.WinRate - (1-m.WinRate)/m.Payoff
+	if f <= 0 {
+		return decimal.Zero
+	}
+	return notional(in, f*m.Fraction)
+}
+
+// notional returns size of position with notional of equity share.
+func notional(in Input, share float64) decimal.Decimal {
+	if !in.Price.IsPositive() || !in.Equity.IsPositive() {
+		return decimal.Zero
+	}
+	return in.Equity.Mul(decimal.NewFromFloat(share)).Div(in.Price)
+}
+
+// Config selects a sizing model and exchange filters.
+type Config struct {
+	Model string `json:"model"`
+	// Size of fixed model.
+	Size decimal.Decimal `json:"size,omitempty"`
+	// Percent of equity notional.
+	Percent float64 `json:"percent,omitempty"`
+	// TargetVol is a target standard deviation of 1 minute position
+	// returns relative to equity, realized volatility is estimated
+	// over VolWindow 1 minute returns.
+	TargetVol float64 `json:"target_vol,omitempty"`
+	VolWindow int     `json:"vol_window,omitempty"`
+	// WinRate, Payoff and Fraction configure Kelly model.
+	WinRate  float64 `json:"win_rate,omitempty"`
+	Payoff   float64 `json:"payoff,omitempty"`
+	Fraction float64 `json:"fraction,omitempty"`
+
+	// StepSize orders are rounded down to.
+	StepSize decimal.Decimal `json:"step_size,omitempty"`
+	// MinNotional is a minimal order notional, smaller orders are not sent.
+	MinNotional decimal.Decimal `json:"min_notional,omitempty"`
+	// MaxLeverage caps position notional at equity times leverage.
+	MaxLeverage float64 `json:"max_leverage,omitempty"`
+	// Asset equity is counted in, usdt by default.
+	Asset string `json:"asset,omitempty"`
+}
+
+func (c Config) Validate() error {
+	switch c.Model {
+	case "", ModelFixed:
+		if !c.Size.IsPositive() {
+			return fmt.Errorf("fixed size %v is not positive", c.Size)
+		}
+	case ModelPercent:
+		if c.Percent <= 0 {
+			return fmt.Errorf("percent of equity %v is not positive", c.Percent)
+		}
+	case ModelVolTarget:
+		if c.TargetVol <= 0 || c.VolWindow < 2 {
+			return fmt.Errorf("target volatility %v must be positive and window %d at least 2", c.TargetVol, c.VolWindow)
+		}
+	case ModelKelly:
+		if c.WinRate <= 0 || c.WinRate >= 1 || c.Payoff <= 0 || c.Fraction <= 0 || c.Fraction > 1 {
+			return fmt.Errorf("kelly win rate %v, payoff %v or fraction %v is out of range", c.WinRate, c.Payoff, c.Fraction)
+		}
+	default:
+		return fmt.Errorf("unsupported sizing model %q", c.Model)
+	}
+	if c.StepSize.IsNegative() || c.MinNotional.IsNegative() || c.MaxLeverage < 0 {
+		return fmt.Errorf("sizing step size %v, min notional %v and max leverage %v must not be negative",
+			c.StepSize, c.MinNotional, c.MaxLeverage)
+	}
+	return nil
+}
+
+// New creates sizing model of the config.
+func New(cfg Config) (Model, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("sizing.New: %w", err)
+	}
+	switch cfg.Model {
+	case ModelPercent:
+		return PercentOfEquity{Percent: cfg.Percent}, nil
+	case ModelVolTarget:
+		return VolTarget{TargetVol: cfg.TargetVol}, nil
+	case ModelKelly:
+		return Kelly{WinRate: cfg.WinRate, Payoff: cfg.Payoff, Fraction: cfg.Fraction}, nil
+	}
+	return Fixed{Size: cfg.Size}, nil
+}
+
+// Limits restrict orders of Sizer, zero values are not checked.
+type Limits struct {
+	StepSize     decimal.Decimal
+	MinNotional  decimal.Decimal
+	MaxLeverage  float64
+	MaxPosition  decimal.Decimal
+	MaxOrderSize decimal.Decimal
+	Asset        string
+}
+
+// Limits returns exchange filters of the config.
+func (c Config) Limits() Limits {
+	return Limits{
+		StepSize:    c.StepSize,
+		MinNotional: c.MinNotional,
+		MaxLeverage: c.MaxLeverage,
+		Asset:       c.Asset,
+	}
+}
+
+// Equity returns balance of the asset, usdt by default, with unrealized
+// PnL of position in the symbol at the price.
+func Equity(acc *models.Account, asset, symbol string, price decimal.Decimal) decimal.Decimal {
+	if asset == "" {
+		asset = "usdt"
+	}
+	pos := acc.GetPosition(symbol)
+	equity := acc.GetBalance(asset).Balance
+	if !pos.Amount.IsZero() && price.IsPositive() {
+		equity = equity.Add(pos.Amount.Mul(price.Sub(pos.EntryPrice)))
+	}
+	return equity
+}
+
+// Sizer converts target positions to orders of the account.
+type Sizer struct {
+	acc    *models.Account
+	limits Limits
+}
+
+func NewSizer(acc *models.Account, limits Limits) *Sizer {
+	return &Sizer{acc: acc, limits: limits}
+}
+
+// Delta returns signed size to trade from the current position towards
+// the target. Target is capped by max position and leverage, the size
+// is rounded down to step size and zero if it is under min notional.
+func (s *Sizer) Delta(t Target) (decimal.Decimal, error) {
+	if !t.Price.IsPositive() {
+		return decimal.Zero, errors.New("sizing.Delta: target price is not positive")
+	}
+
+	l := s.limits
+	target := t.Position
+	if l.MaxPosition.IsPositive() {
+		target = clamp(target, l.MaxPosition)
+	}
+	if l.MaxLeverage > 0 {
+		equity := Equity(s.acc, l.Asset, t.Symbol, t.Price)
+		if !equity.IsPositive() {
+			equity = decimal.Zero
+		}
+		target = clamp(target, equity.Mul(decimal.NewFromFloat(l.MaxLeverage)).Div(t.Price))
+	}
+
+	delta := target.Sub(s.acc.GetPosition(t.Symbol).Amount)
+	if l.StepSize.IsPositive() {
+		delta = delta.Div(l.StepSize).Truncate(0).Mul(l.StepSize)
+	}
+	if l.MinNotional.IsPositive() && delta.Abs().Mul(t.Price).LessThan(l.MinNotional) {
+		return decimal.Zero, nil
+	}
+	return delta, nil
+}
+
+// Orders returns market orders trading Delta
+// split into orders of max order size.
+func (s *Sizer) Orders(t Target) ([]models.Order, error) {
+	delta, err := s.Delta(t)
+	if err != nil || delta.IsZero() {
+		return nil, err
+	}
+
+	side := models.OrderSideBuy
+	if delta.IsNegative() {
+		side = models.OrderSideSell
+	}
+	var orders []models.Order
+	for left := delta.Abs(); left.IsPositive(); {
+		size := left
+		if limit := s.limits.MaxOrderSize; limit.IsPositive() && size.GreaterThan(limit) {
+			size = limit
+			if s.limits.StepSize.IsPositive() {
+				size = size.Div(s.limits.StepSize).Floor().Mul(s.limits.StepSize)
+			}
+			if !size.IsPositive() {
+				return nil, fmt.Errorf("sizing.Orders: max order size %v is under step size %v", limit, s.limits.StepSize)
+			}
+		}
+		left = left.Sub(size)
+		orders = append(orders, models.Order{
+			ClientOrderID: uuid.New().String(),
+			CreatedAt:     time.Now().UTC(),
+			Symbol:        t.Symbol,
+			Side:          side,
+			Type:          models.OrderTypeMarket,
+			Size:          size,
+		})
+	}
+	return orders, nil
+}
+
+// clamp limits size to [-limit, limit].
+func clamp(size, limit decimal.Decimal) decimal.Decimal {
+	if size.GreaterThan(limit) {
+		return limit
+	}
+	if size.LessThan(limit.Neg()) {
+		return limit.Neg()
+	}
+	return size
+}
@@ -0,0 +1,98 @@
+package sizing
+
+import (
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func d(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v)
+}
+
+func TestModels(t *testing.T) {
+	in := Input{Price: d(2000), Equity: d(10000), Volatility: 0.002}
+	tests := []struct {
+		cfg  Config
+		want decimal.Decimal
+	}{
+		{Config{Size: d(0.25)}, d(0.25)},
+		{Config{Model: ModelPercent, Percent: 50}, d(2.5)},
+		// Volatility of 0.002 is targeted at 0.001 with half of equity.
+		{Config{Model: ModelVolTarget, TargetVol: 0.001, VolWindow: 10}, d(2.5)},
+		// Kelly fraction is 0.5 - 0.5/2 = 0.25, half of it is taken.
+		{Config{Model: ModelKelly, WinRate: 0.5, Payoff: 2, Fraction: 0.5}, d(0.625)},
+		{Config{Model: ModelKelly, WinRate: 0.3, Payoff: 1, Fraction: 1}, decimal.Zero},
+	}
+	for _, tt := range tests {
+		m, err := New(tt.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Position(in); !got.Equal(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.cfg.Model, tt.want, got)
+		}
+	}
+
+	if _, err := New(Config{Model: ModelVolTarget, TargetVol: 0.01}); err == nil {
+		t.Error("expected error for missing volatility window")
+	}
+}
+
+func TestSizer(t *testing.T) {
+	acc := models.NewAccount("acc", "ex")
+	acc.UpdateBalance("usdt", d(1000), time.Now())
+	acc.UpdatePosition("ethusdt", d(0.5), d(2000), time.Now())
+
+	s := NewSizer(acc, Limits{
+		StepSize:     d(0.01),
+		MinNotional:  d(20),
+		MaxLeverage:  5,
+		MaxPosition:  d(10),
+		MaxOrderSize: d(1),
+	})
+
+	tests := []struct {
+		target float64
+		want   float64
+	}{
+		// Leverage caps position at 5000 notional.
+		{10, 2},
+		{-10, -3},
+		{1.2345, 0.73},
+		// Under min notional.
+		{0.505, 0},
+		{0, -0.5},
+	}
+	for _, tt := range tests {
+		delta, err := s.Delta(Target{Symbol: "ethusdt", Position: d(tt.target), Price: d(2500)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Equity is 1000 + 0.5 * (2500 - 2000) = 1250.
+		if !delta.Equal(d(tt.want)) {
+			t.Errorf("target %v: expected delta %v, got %v", tt.target, tt.want, delta)
+		}
+	}
+
+	orders, err := s.Orders(Target{Symbol: "ethusdt", Position: d(-10), Price: d(2500)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := []string{"1", "1", "1"}
+	if len(orders) != len(sizes) {
+		t.Fatalf("expected %d orders, got %+v", len(sizes), orders)
+	}
+	for i, o := range orders {
+		if o.Size.String() != sizes[i] || o.Side != models.OrderSideSell || o.Type != models.OrderTypeMarket {
+			t.Errorf("unexpected order %+v", o)
+		}
+	}
+
+	if _, err := s.Delta(Target{Symbol: "ethusdt", Position: d(1)}); err == nil {
+		t.Error("expected error without price")
+	}
+}
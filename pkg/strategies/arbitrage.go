@@ -0,0 +1,471 @@
+package strategies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// LegRisk defines what is done when only one leg of arbitrage is filled.
+type LegRisk string
+
+const (
+	// LegRiskHedge completes the missing leg with market order.
+	LegRiskHedge LegRisk = "hedge"
+	// LegRiskUnwind closes the filled leg excess with market order.
+	LegRiskUnwind LegRisk = "unwind"
+)
+
+// ArbitrageParams configure Arbitrage strategy.
+type ArbitrageParams struct {
+	Symbol string `json:"symbol"`
+	// OtherExchange is the second exchange, the first one
+	// is the exchange of the strategy.
+	OtherExchange string          `json:"other_exchange"`
+	OrderSize     decimal.Decimal `json:"order_size"`
+	// MaxPosition limits absolute position on every exchange.
+	MaxPosition decimal.Decimal `json:"max_position"`
+	// TakerFee is a fee rate on both exchanges.
+	TakerFee decimal.Decimal `json:"taker_fee"`
+	// Slippage is an expected slippage relative to price on every leg.
+	Slippage decimal.Decimal `json:"slippage"`
+	// MinEdge is a minimal profit relative to price after
+	// fees and slippage required to trade.
+	MinEdge decimal.Decimal `json:"min_edge"`
+	// MaxQuoteAge is a maximal difference in seconds between
+	// times of BBO on exchanges, older quotes are not traded.
+	MaxQuoteAge float64 `json:"max_quote_age"`
+	// LegTimeout is a time in seconds to wait for legs to finish,
+	// before leg risk is handled with the last known fills.
+	LegTimeout float64 `json:"leg_timeout"`
+	LegRisk    LegRisk `json:"leg_risk"`
+}
+
+func DefaultArbitrageParams() ArbitrageParams {
+	return ArbitrageParams{
+		Symbol:      "ethusdt",
+		OrderSize:   decimal.NewFromFloat(0.05),
+		MaxPosition: decimal.NewFromFloat(0.5),
+		TakerFee:    decimal.NewFromFloat(0.0004),
+		Slippage:    decimal.NewFromFloat(0.0001),
+		MinEdge:     decimal.NewFromFloat(0.0005),
+		MaxQuoteAge: 0.5,
+		LegTimeout:  5,
+		LegRisk:     LegRiskHedge,
+	}
+}
+
+// ParseArbitrageParams parses parameters over the defaults and validates them.
+func ParseArbitrageParams(raw json.RawMessage) (ArbitrageParams, error) {
+	p := DefaultArbitrageParams()
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return p, fmt.Errorf("failed to parse arbitrage params: %w", err)
+		}
+	}
+
+	return p, p.Validate()
+}
+
+func (p ArbitrageParams) Validate() error {
+	switch {
+	case p.Symbol == "":
+		return errors.New("arbitrage symbol is empty")
+	case p.OtherExchange == "":
+		return errors.New("arbitrage other exchange is empty")
+	case !p.OrderSize.IsPositive():
+		return fmt.Errorf("arbitrage order size %v is not positive", p.OrderSize)
+	case p.MaxPosition.LessThan(p.OrderSize):
+		return fmt.Errorf("arbitrage max position %v is less than order size %v", p.MaxPosition, p.OrderSize)
+	case p.TakerFee.IsNegative() || p.Slippage.IsNegative():
+		return fmt.Errorf("arbitrage taker fee %v and slippage %v must not be negative", p.TakerFee, p.Slippage)
+	case !p.MinEdge.IsPositive():
+		return fmt.Errorf("arbitrage min edge %v is not positive", p.MinEdge)
+	case p.MaxQuoteAge <= 0 || p.LegTimeout <= 0:
+		return fmt.Errorf("arbitrage max quote age %v and leg timeout %v must be positive", p.MaxQuoteAge, p.LegTimeout)
+	case p.LegRisk != LegRiskHedge && p.LegRisk != LegRiskUnwind:
+		return fmt.Errorf("arbitrage leg risk %q is not %s or %s", p.LegRisk, LegRiskHedge, LegRiskUnwind)
+	}
+	return nil
+}
+
+// Edge returns profit relative to price of buying at ask and selling
+// at bid on another exchange after fees and slippage on both legs.
+func (p ArbitrageParams) Edge(ask, bid decimal.Decimal) decimal.Decimal {
+	cost := p.TakerFee.Add(p.Slippage)
+	one := decimal.NewFromInt(1)
+	sell := bid.Mul(one.Sub(cost))
+	buy := ask.Mul(one.Add(cost))
+	return sell.Sub(buy).Div(ask)
+}
+
+// arbLeg is an IOC order of arbitrage on one exchange.
+type arbLeg struct {
+	venue  Venue
+	order  models.Order
+	filled decimal.Decimal
+	done   bool
+}
+
+// arbTrade is arbitrage waiting for both legs to finish.
+type arbTrade struct {
+	started time.Time
+	timer   *time.Timer
+	buy     *arbLeg
+	sell    *arbLeg
+}
+
+type arbBook struct {
+	bbo models.BBO
+	ts  time.Time
+}
+
+// Arbitrage watches BBO of the symbol on two exchanges and when
+// buying on one and selling on another has edge over MinEdge, it sends
+// IOC orders on both exchanges at once. If legs are filled partially
+// or only one of them is filled, the difference is hedged on the
+// unfilled leg exchange or unwound on the filled one, see LegRisk.
+type Arbitrage struct {
+	ctx    context.Context
+	params atomic.Pointer[ArbitrageParams]
+	venues map[string]Venue
+
+	books    map[string]arbBook
+	inflight *arbTrade
+
+	mux sync.Mutex
+}
+
+// NewArbitrage creates strategy trading on venues of the strategy
+// exchange and ArbitrageParams.OtherExchange.
+func NewArbitrage(ctx context.Context, venues []Venue, params ArbitrageParams) (*Arbitrage, error) {
+	if len(venues) != 2 || venues[0].Exchange == venues[1].Exchange {
+		return nil, errors.New("strategies.NewArbitrage: two different exchanges are required")
+	}
+
+	a := &Arbitrage{
+		ctx:    ctx,
+		venues: make(map[string]Venue),
+		books:  make(map[string]arbBook),
+	}
+	for _, v := range venues {
+		a.venues[v.Exchange] = v
+	}
+	a.params.Store(&params)
+	return a, nil
+}
+
+func (a *Arbitrage) See(e models.ExchangeMessage) {
+	bbo, ok := e.BBO()
+	if !ok {
+		return
+	}
+
+	a.mux.Lock()
+	if _, ok := a.venues[e.Exchange]; !ok {
+		a.mux.Unlock()
+		return
+	}
+	a.books[e.Exchange] = arbBook{bbo: bbo, ts: e.Timestamp}
+
+	params := a.Params()
+	if a.inflight != nil {
+		// Replayed messages time out legs in market time,
+		// the timer of the trade does it on quiet books.
+		var h *arbHedge
+		if e.Timestamp.Sub(a.inflight.started).Seconds() > params.LegTimeout {
+			logger.Warn("arbitrage legs timed out, handling leg risk with known fills", "symbol", params.Symbol)
+			h = a.finish(params)
+		}
+		a.mux.Unlock()
+		a.hedge(params, h)
+		return
+	}
+
+	trade := a.opportunity(params)
+	if trade == nil {
+		a.mux.Unlock()
+		return
+	}
+	trade.started = e.Timestamp
+	trade.timer = time.AfterFunc(time.Duration(params.LegTimeout*float64(time.Second)), func() {
+		a.timeout(trade)
+	})
+	a.inflight = trade
+	// Both quotes have to be updated before the next trade.
+	a.books = make(map[string]arbBook)
+	a.mux.Unlock()
+
+	// Orders are placed without the lock, because simulated
+	// exchanges report fills before the orders are placed.
+	var wg sync.WaitGroup
+	for _, leg := range []*arbLeg{trade.buy, trade.sell} {
+		leg := leg
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := leg.venue.Orders.PlaceOrder(a.ctx, leg.order)
+
+			a.mux.Lock()
+			defer a.mux.Unlock()
+			if err != nil {
+				if !errors.Is(err, ErrPaused) {
+					logger.Warn("failed to place arbitrage leg",
+						"exchange", leg.venue.Exchange,
+						"symbol", leg.order.Symbol,
+						"side", leg.order.Side,
+						"err", err,
+					)
+				}
+				leg.done = true
+			} else if res.Status.IsFinal() {
+				leg.filled, leg.done = res.FilledSize, true
+			}
+		}()
+	}
+	wg.Wait()
+
+	a.mux.Lock()
+	h := a.finishIfDone(params)
+	a.mux.Unlock()
+	a.hedge(params, h)
+}
+
+// opportunity returns trade of the most profitable direction with edge
+// over MinEdge or nil. Caller must hold the lock.
+func (a *Arbitrage) opportunity(params ArbitrageParams) *arbTrade {
+	if len(a.books) != 2 {
+		return nil
+	}
+
+	var (
+		best     *arbTrade
+		bestEdge decimal.Decimal
+	)
+	for buyEx, buyBook := range a.books {
+		for sellEx, sellBook := range a.books {
+			if buyEx == sellEx {
+				continue
+			}
+			if age := buyBook.ts.Sub(sellBook.ts).Seconds(); age > params.MaxQuoteAge || age < -params.MaxQuoteAge {
+				return nil
+			}
+
+			ask, bid := buyBook.bbo.Ask, sellBook.bbo.Bid
+			if !ask.Price.IsPositive() || !bid.Price.IsPositive() {
+				continue
+			}
+			edge := params.Edge(ask.Price, bid.Price)
+			if edge.LessThan(params.MinEdge) || (best != nil && edge.LessThanOrEqual(bestEdge)) {
+				continue
+			}
+
+			buy, sell := a.venues[buyEx], a.venues[sellEx]
+			size := decimal.Min(
+				params.OrderSize,
+				ask.Size,
+				bid.Size,
+				params.MaxPosition.Sub(buy.Account.GetPosition(params.Symbol).Amount),
+				params.MaxPosition.Add(sell.Account.GetPosition(params.Symbol).Amount),
+			)
+			if !size.IsPositive() {
+				continue
+			}
+
+			best, bestEdge = &arbTrade{
+				buy:  &arbLeg{venue: buy, order: iocOrder(params.Symbol, models.OrderSideBuy, size, ask.Price)},
+				sell: &arbLeg{venue: sell, order: iocOrder(params.Symbol, models.OrderSideSell, size, bid.Price)},
+			}, edge
+		}
+	}
+
+	if best != nil {
+		logger.Info("arbitrage opportunity",
+			"symbol", params.Symbol,
+			"buy_exchange", best.buy.venue.Exchange,
+			"buy_price", best.buy.order.Price,
+			"sell_exchange", best.sell.venue.Exchange,
+			"sell_price", best.sell.order.Price,
+			"size", best.buy.order.Size,
+			"edge", bestEdge,
+		)
+	}
+	return best
+}
+
+func iocOrder(symbol string, side models.OrderSide, size, price decimal.Decimal) models.Order {
+	return models.Order{
+		ClientOrderID: uuid.New().String(),
+		CreatedAt:     time.Now().UTC(),
+		Symbol:        symbol,
+		Side:          side,
+		Type:          models.OrderTypeLimit,
+		TimeInForce:   models.TimeInForceIOC,
+		Size:          size,
+		Price:         price,
+	}
+}
+
+// OnOrderUpdate records fills of arbitrage legs.
+func (a *Arbitrage) OnOrderUpdate(upd models.OrderUpdate) {
+	params := a.Params()
+
+	a.mux.Lock()
+	if a.inflight == nil {
+		a.mux.Unlock()
+		return
+	}
+	for _, leg := range []*arbLeg{a.inflight.buy, a.inflight.sell} {
+		if leg.order.ClientOrderID != upd.ClientOrderID || leg.done {
+			continue
+		}
+		leg.filled = upd.FilledSize
+		leg.done = upd.Status.IsFinal()
+	}
+	h := a.finishIfDone(params)
+	a.mux.Unlock()
+
+	a.hedge(params, h)
+}
+
+// timeout finishes the trade with known fills, if its legs
+// are not finished in LegTimeout.
+func (a *Arbitrage) timeout(trade *arbTrade) {
+	params := a.Params()
+
+	a.mux.Lock()
+	if a.inflight != trade {
+		a.mux.Unlock()
+		return
+	}
+	logger.Warn("arbitrage legs timed out, handling leg risk with known fills", "symbol", params.Symbol)
+	h := a.finish(params)
+	a.mux.Unlock()
+	a.hedge(params, h)
+}
+
+// arbHedge is an order balancing legs fills.
+type arbHedge struct {
+	venue Venue
+	order models.Order
+}
+
+// finishIfDone finishes trade once both legs are finished.
+// Caller must hold the lock.
+func (a *Arbitrage) finishIfDone(params ArbitrageParams) *arbHedge {
+	if t := a.inflight; t != nil && t.buy.done && t.sell.done {
+		return a.finish(params)
+	}
+	return nil
+}
+
+// finish returns order hedging or unwinding the difference of legs
+// fills, if there is any. Caller must hold the lock.
+func (a *Arbitrage) finish(params ArbitrageParams) *arbHedge {
+	t := a.inflight
+	a.inflight = nil
+	t.timer.Stop()
+
+	diff := t.buy.filled.Sub(t.sell.filled)
+	logger.Info("arbitrage finished",
+		"symbol", params.Symbol,
+		"bought", t.buy.filled,
+		"sold", t.sell.filled,
+	)
+	if diff.IsZero() {
+		return nil
+	}
+
+	// Bought more than sold: sell the rest on the sell leg exchange
+	// or unwind buying excess on the buy leg one, and vice versa.
+	venue, side := t.sell.venue, models.OrderSideSell
+	if params.LegRisk == LegRiskUnwind {
+		venue = t.buy.venue
+	}
+	if diff.IsNegative() {
+		venue, side = t.buy.venue, models.OrderSideBuy
+		if params.LegRisk == LegRiskUnwind {
+			venue = t.sell.venue
+		}
+	}
+
+	return &arbHedge{
+		venue: venue,
+		order: models.Order{
+			ClientOrderID: uuid.New().String(),
+			CreatedAt:     time.Now().UTC(),
+			Symbol:        params.Symbol,
+			Side:          side,
+			Type:          models.OrderTypeMarket,
+			Size:          diff.Abs(),
+		},
+	}
+}
+
+// hedge places hedging order, if it is not nil. Hedges bypass the pause
+// of the strategy, if orders allow it, because they reduce the risk of
+// filled legs. It is called without the lock, because simulated
+// exchanges report fills synchronously.
+func (a *Arbitrage) hedge(params ArbitrageParams, h *arbHedge) {
+	if h == nil {
+		return
+	}
+
+	logger.Warn("arbitrage legs are unbalanced",
+		"symbol", params.Symbol,
+		"leg_risk", params.LegRisk,
+		"exchange", h.venue.Exchange,
+		"side", h.order.Side,
+		"size", h.order.Size,
+	)
+	place := h.venue.Orders.PlaceOrder
+	if hedger, ok := h.venue.Orders.(Hedger); ok {
+		place = hedger.PlaceHedge
+	}
+	if _, err := place(a.ctx, h.order); err != nil {
+		logger.Error("failed to handle arbitrage leg risk",
+			"exchange", h.venue.Exchange,
+			"symbol", params.Symbol,
+			"side", h.order.Side,
+			"size", h.order.Size,
+			"err", err,
+		)
+	}
+}
+
+func (a *Arbitrage) Params() ArbitrageParams {
+	return *a.params.Load()
+}
+
+// SetParams validates and atomically replaces parameters,
+// symbol and exchanges can not be changed.
+func (a *Arbitrage) SetParams(p ArbitrageParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	old := a.Params()
+	if p.Symbol != old.Symbol || p.OtherExchange != old.OtherExchange {
+		return fmt.Errorf("arbitrage symbol %s and exchange %s can not be changed at runtime", old.Symbol, old.OtherExchange)
+	}
+
+	a.params.Store(&p)
+	return nil
+}
+
+// State is empty, in-flight legs are reconciled as open orders.
+func (a *Arbitrage) State() (json.RawMessage, error) {
+	return json.RawMessage("{}"), nil
+}
+
+func (a *Arbitrage) Restore(json.RawMessage) error {
+	return nil
+}
@@ -0,0 +1,194 @@
+package strategies
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// iocOrders fills IOC orders up to fill size at once, other orders fully.
+type iocOrders struct {
+	fill   decimal.Decimal
+	placed []models.Order
+	mux    sync.Mutex
+}
+
+func (f *iocOrders) PlaceOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	order.Status, order.FilledSize = models.OrderStatusFilled, order.Size
+	if order.TimeInForce == models.TimeInForceIOC && f.fill.LessThan(order.Size) {
+		order.Status, order.FilledSize = models.OrderStatusCanceled, f.fill
+	}
+	f.placed = append(f.placed, order)
+	return &order, nil
+}
+
+func (f *iocOrders) CancelOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	order.Status = models.OrderStatusCanceled
+	return &order, nil
+}
+
+func (f *iocOrders) OpenOrders() []models.Order {
+	return nil
+}
+
+func TestArbitrageEdge(t *testing.T) {
+	p := DefaultArbitrageParams()
+	p.TakerFee, p.Slippage = decimal.NewFromFloat(0.001), decimal.Zero
+
+	// 1% spread minus 0.1% fee on both legs.
+	edge := p.Edge(decimal.NewFromInt(1000), decimal.NewFromInt(1010))
+	if !edge.Equal(decimal.NewFromFloat(0.00799)) {
+		t.Errorf("expected edge 0.00799, got %v", edge)
+	}
+	if edge := p.Edge(decimal.NewFromInt(1000), decimal.NewFromInt(1001)); edge.IsPositive() {
+		t.Errorf("expected negative edge, got %v", edge)
+	}
+}
+
+func TestArbitrage(t *testing.T) {
+	p := DefaultArbitrageParams()
+	p.OtherExchange = "b"
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bbo := func(exchange string, bid float64) models.ExchangeMessage {
+		return models.NewBBOMessage(exchange, p.Symbol, start, models.BBO{
+			Bid: models.PriceLevel{Price: decimal.NewFromFloat(bid), Size: decimal.NewFromInt(1)},
+			Ask: models.PriceLevel{Price: decimal.NewFromFloat(bid + 0.1), Size: decimal.NewFromInt(1)},
+		})
+	}
+
+	cases := []struct {
+		name    string
+		risk    LegRisk
+		fillA   decimal.Decimal
+		fillB   decimal.Decimal
+		hedgeOn string
+		hedge   models.OrderSide
+	}{
+		{"both filled", LegRiskHedge, p.OrderSize, p.OrderSize, "", ""},
+		{"sell missed, hedge", LegRiskHedge, p.OrderSize, decimal.Zero, "b", models.OrderSideSell},
+		{"sell missed, unwind", LegRiskUnwind, p.OrderSize, decimal.Zero, "a", models.OrderSideSell},
+		{"buy partial, hedge", LegRiskHedge, decimal.NewFromFloat(0.02), p.OrderSize, "a", models.OrderSideBuy},
+	}
+	for _, c := range cases {
+		p.LegRisk = c.risk
+		orders := map[string]*iocOrders{"a": {fill: c.fillA}, "b": {fill: c.fillB}}
+		a, err := NewArbitrage(context.Background(), []Venue{
+			{Exchange: "a", Account: models.NewAccount("acc", "a"), Orders: orders["a"]},
+			{Exchange: "b", Account: models.NewAccount("acc", "b"), Orders: orders["b"]},
+		}, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Buy on a at 2000.1 and sell on b at 2010.
+		a.See(bbo("a", 2000))
+		a.See(bbo("b", 2010))
+
+		buys, sells := orders["a"].placed, orders["b"].placed
+		if len(buys) == 0 || buys[0].Side != models.OrderSideBuy || buys[0].TimeInForce != models.TimeInForceIOC ||
+			len(sells) == 0 || sells[0].Side != models.OrderSideSell || !sells[0].Price.Equal(decimal.NewFromInt(2010)) {
+			t.Fatalf("%s: expected IOC legs, got %+v %+v", c.name, buys, sells)
+		}
+
+		var hedges []models.Order
+		for _, ex := range []string{"a", "b"} {
+			for _, o := range orders[ex].placed[1:] {
+				if ex != c.hedgeOn || o.Side != c.hedge || o.Type != models.OrderTypeMarket {
+					t.Errorf("%s: unexpected order on %s: %+v", c.name, ex, o)
+				}
+				hedges = append(hedges, o)
+			}
+		}
+		if want := c.fillA.Sub(c.fillB).Abs(); (len(hedges) == 0) != want.IsZero() ||
+			(len(hedges) > 0 && !hedges[0].Size.Equal(want)) {
+			t.Errorf("%s: expected hedge of %v, got %+v", c.name, want, hedges)
+		}
+
+		// Same quotes do not trade until both are updated.
+		a.See(bbo("a", 2000))
+		if len(orders["a"].placed)+len(orders["b"].placed) != 2+len(hedges) {
+			t.Errorf("%s: expected no trade on a single quote", c.name)
+		}
+	}
+}
+
+// pausedOrders are orders of a strategy paused after its legs are
+// placed, legs are left open unless filled.
+type pausedOrders struct {
+	*iocOrders
+	paused bool
+}
+
+func (f *pausedOrders) PlaceOrder(ctx context.Context, order models.Order) (*models.Order, error) {
+	f.mux.Lock()
+	paused := f.paused
+	f.paused = true
+	f.mux.Unlock()
+	if paused {
+		return nil, ErrPaused
+	}
+
+	res, err := f.iocOrders.PlaceOrder(ctx, order)
+	if err == nil && res.FilledSize.IsZero() {
+		res.Status = models.OrderStatusNew
+	}
+	return res, err
+}
+
+func (f *pausedOrders) PlaceHedge(ctx context.Context, order models.Order) (*models.Order, error) {
+	return f.iocOrders.PlaceOrder(ctx, order)
+}
+
+func TestArbitrageLegTimeout(t *testing.T) {
+	p := DefaultArbitrageParams()
+	p.OtherExchange, p.LegTimeout = "b", 0.05
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bbo := func(exchange string, bid float64) models.ExchangeMessage {
+		return models.NewBBOMessage(exchange, p.Symbol, start, models.BBO{
+			Bid: models.PriceLevel{Price: decimal.NewFromFloat(bid), Size: decimal.NewFromInt(1)},
+			Ask: models.PriceLevel{Price: decimal.NewFromFloat(bid + 0.1), Size: decimal.NewFromInt(1)},
+		})
+	}
+
+	// The buy leg fills, the sell leg is stuck open on a quiet book.
+	orders := map[string]*pausedOrders{
+		"a": {iocOrders: &iocOrders{fill: p.OrderSize}},
+		"b": {iocOrders: &iocOrders{fill: decimal.Zero}},
+	}
+	a, err := NewArbitrage(context.Background(), []Venue{
+		{Exchange: "a", Account: models.NewAccount("acc", "a"), Orders: orders["a"]},
+		{Exchange: "b", Account: models.NewAccount("acc", "b"), Orders: orders["b"]},
+	}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.See(bbo("a", 2000))
+	a.See(bbo("b", 2010))
+
+	placed := func() []models.Order {
+		b := orders["b"]
+		b.mux.Lock()
+		defer b.mux.Unlock()
+		return append([]models.Order(nil), b.placed...)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(placed()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	got := placed()
+	if len(got) != 2 || got[1].Type != models.OrderTypeMarket || got[1].Side != models.OrderSideSell ||
+		!got[1].Size.Equal(p.OrderSize) {
+		t.Fatalf("expected sell hedge of %v after leg timeout, got %+v", p.OrderSize, got)
+	}
+}
@@ -0,0 +1,360 @@
+package strategies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// FundingCarryParams configure FundingCarry strategy.
+type FundingCarryParams struct {
+	// Symbol is a perpetual contract traded on the strategy exchange.
+	Symbol string `json:"symbol"`
+	// SpotSymbol traded on SpotExchange defaults to Symbol. Without
+	// SpotExchange the spot leg is another contract on the strategy
+	// exchange, e.g. a delivery future, which pays no funding.
+	SpotExchange string `json:"spot_exchange,omitempty"`
+	SpotSymbol   string `json:"spot_symbol"`
+	// Notional is a size of every leg in quote asset.
+	Notional decimal.Decimal `json:"notional"`
+	// EntryRate is a minimal predicted funding rate to enter,
+	// position is closed when the rate falls below ExitRate.
+	EntryRate decimal.Decimal `json:"entry_rate"`
+	ExitRate  decimal.Decimal `json:"exit_rate"`
+	// Reverse allows short spot and long perpetual on negative funding.
+	Reverse bool `json:"reverse"`
+	// RebalanceThreshold is a leg drift from its target relative
+	// to Notional, after which the leg is rebalanced.
+	RebalanceThreshold decimal.Decimal `json:"rebalance_threshold"`
+	StepSize           decimal.Decimal `json:"step_size"`
+}
+
+func DefaultFundingCarryParams() FundingCarryParams {
+	return FundingCarryParams{
+		Symbol:             "ethusdt",
+		Notional:           decimal.NewFromInt(1000),
+		EntryRate:          decimal.NewFromFloat(0.0001),
+		ExitRate:           decimal.Zero,
+		RebalanceThreshold: decimal.NewFromFloat(0.05),
+		StepSize:           decimal.NewFromFloat(0.001),
+	}
+}
+
+// ParseFundingCarryParams parses parameters over the defaults and validates them.
+func ParseFundingCarryParams(raw json.RawMessage) (FundingCarryParams, error) {
+	p := DefaultFundingCarryParams()
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return p, fmt.Errorf("failed to parse funding carry params: %w", err)
+		}
+	}
+	if p.SpotSymbol == "" {
+		p.SpotSymbol = p.Symbol
+	}
+
+	return p, p.Validate()
+}
+
+func (p FundingCarryParams) Validate() error {
+	switch {
+	case p.Symbol == "" || p.SpotSymbol == "":
+		return errors.New("funding carry symbol is empty")
+	case !p.Notional.IsPositive():
+		return fmt.Errorf("funding carry notional %v is not positive", p.Notional)
+	case !p.EntryRate.IsPositive():
+		return fmt.Errorf("funding carry entry rate %v is not positive", p.EntryRate)
+	case !p.ExitRate.LessThan(p.EntryRate):
+		return fmt.Errorf("funding carry exit rate %v is not below entry rate %v", p.ExitRate, p.EntryRate)
+	case !p.RebalanceThreshold.IsPositive():
+		return fmt.Errorf("funding carry rebalance threshold %v is not positive", p.RebalanceThreshold)
+	case !p.StepSize.IsPositive():
+		return fmt.Errorf("funding carry step size %v is not positive", p.StepSize)
+	}
+	return nil
+}
+
+// FundingCarry is a delta neutral strategy collecting funding: when
+// predicted funding rate is over EntryRate, it buys spot and shorts the
+// perpetual for Notional on every leg, and the reverse on negative rate
+// if it is allowed. Legs are rebalanced when they drift from Notional
+// and closed when the rate falls below ExitRate, i.e. flips by default.
+// Funding settled on the perpetual position is added to its account.
+//
+// Spot holdings are read as the spot account position of SpotSymbol.
+// Until a spot connector exists, the spot leg can be a delivery future
+// on the perpetual exchange: the carry then collects funding against
+// the basis instead of spot.
+type FundingCarry struct {
+	ctx    context.Context
+	params atomic.Pointer[FundingCarryParams]
+	perp   Venue
+	spot   Venue
+
+	perpMid, spotMid decimal.Decimal
+	// rate is the predicted funding rate settled at nextFunding.
+	rate        decimal.Decimal
+	nextFunding time.Time
+	// direction is 1 for long spot and short perpetual, -1 for
+	// the reverse and 0 without position.
+	direction int
+	// funding is accumulated funding of the strategy.
+	funding decimal.Decimal
+	// pending are client order IDs of unfinished orders.
+	pending map[string]bool
+
+	mux sync.Mutex
+}
+
+type fundingCarryState struct {
+	Direction   int             `json:"direction"`
+	Funding     decimal.Decimal `json:"funding"`
+	Rate        decimal.Decimal `json:"rate"`
+	NextFunding time.Time       `json:"next_funding"`
+}
+
+// carryOrder is an order on one of the legs.
+type carryOrder struct {
+	venue Venue
+	order models.Order
+}
+
+// NewFundingCarry creates strategy trading the perpetual on perp venue
+// and spot on venue of FundingCarryParams.SpotExchange, which may be
+// the perp venue itself.
+func NewFundingCarry(ctx context.Context, perp, spot Venue, params FundingCarryParams) (*FundingCarry, error) {
+	if perp.Exchange == spot.Exchange && params.Symbol == params.SpotSymbol {
+		return nil, fmt.Errorf("strategies.NewFundingCarry: spot and perpetual legs are both %s on %s",
+			params.Symbol, perp.Exchange)
+	}
+
+	c := &FundingCarry{
+		ctx:     ctx,
+		perp:    perp,
+		spot:    spot,
+		pending: make(map[string]bool),
+	}
+	c.params.Store(&params)
+	return c, nil
+}
+
+func (c *FundingCarry) See(e models.ExchangeMessage) {
+	params := c.Params()
+
+	c.mux.Lock()
+	switch {
+	case e.Exchange == c.perp.Exchange && e.Symbol == params.Symbol:
+		if f, ok := e.Funding(); ok {
+			c.onFunding(params, f)
+		} else if bbo, ok := e.BBO(); ok {
+			c.perpMid = bbo.Bid.Price.Add(bbo.Ask.Price).Div(decimal.NewFromInt(2))
+		}
+	case e.Exchange == c.spot.Exchange && e.Symbol == params.SpotSymbol:
+		if bbo, ok := e.BBO(); ok {
+			c.spotMid = bbo.Bid.Price.Add(bbo.Ask.Price).Div(decimal.NewFromInt(2))
+		}
+	default:
+		c.mux.Unlock()
+		return
+	}
+	orders := c.rebalance(params)
+	c.mux.Unlock()
+
+	c.place(orders)
+}
+
+// onFunding accrues funding settled on the perpetual position and
+// updates predicted rate. Caller must hold the lock.
+func (c *FundingCarry) onFunding(params FundingCarryParams, f models.Funding) {
+	if !c.nextFunding.IsZero() && f.NextFundingTime.After(c.nextFunding) {
+		// Long position pays positive funding and short one receives it.
+		pos := c.perp.Account.GetPosition(params.Symbol).Amount
+		if amount := pos.Neg().Mul(f.MarkPrice).Mul(c.rate); !amount.IsZero() {
+			c.perp.Account.AddFunding(params.Symbol, amount)
+			c.funding = c.funding.Add(amount)
+			logger.Info("funding settled",
+				"symbol", params.Symbol,
+				"rate", c.rate,
+				"position", pos,
+				"amount", amount,
+				"total", c.funding,
+			)
+		}
+	}
+	c.rate, c.nextFunding = f.Rate, f.NextFundingTime
+}
+
+// rebalance updates direction by funding rate and returns orders
+// moving legs to their targets. Caller must hold the lock.
+func (c *FundingCarry) rebalance(params FundingCarryParams) []carryOrder {
+	if c.nextFunding.IsZero() || !c.perpMid.IsPositive() || !c.spotMid.IsPositive() || len(c.pending) > 0 {
+		return nil
+	}
+
+	was := c.direction
+	signed := c.rate.Mul(decimal.NewFromInt(int64(c.direction)))
+	switch {
+	case c.direction == 0 && c.rate.GreaterThanOrEqual(params.EntryRate):
+		c.direction = 1
+	case c.direction == 0 && params.Reverse && c.rate.LessThanOrEqual(params.EntryRate.Neg()):
+		c.direction = -1
+	case c.direction != 0 && signed.LessThan(params.ExitRate):
+		c.direction = 0
+	}
+	if c.direction != was {
+		logger.Info("funding carry direction changed",
+			"symbol", params.Symbol,
+			"rate", c.rate,
+			"from", was,
+			"to", c.direction,
+		)
+	}
+
+	dir := decimal.NewFromInt(int64(c.direction))
+	var orders []carryOrder
+	for _, leg := range []struct {
+		venue  Venue
+		symbol string
+		mid    decimal.Decimal
+		sign   decimal.Decimal
+	}{
+		{c.spot, params.SpotSymbol, c.spotMid, dir},
+		{c.perp, params.Symbol, c.perpMid, dir.Neg()},
+	} {
+		target := params.Notional.Div(leg.mid).Mul(leg.sign)
+		pos := leg.venue.Account.GetPosition(leg.symbol).Amount
+		diff := target.Sub(pos).Div(params.StepSize).Round(0).Mul(params.StepSize)
+		if diff.IsZero() {
+			continue
+		}
+		// Open legs are traded only when they drift over the threshold.
+		if c.direction != 0 && c.direction == was &&
+			diff.Abs().Mul(leg.mid).LessThanOrEqual(params.RebalanceThreshold.Mul(params.Notional)) {
+			continue
+		}
+
+		side := models.OrderSideBuy
+		if diff.IsNegative() {
+			side = models.OrderSideSell
+		}
+		o := carryOrder{
+			venue: leg.venue,
+			order: models.Order{
+				ClientOrderID: uuid.New().String(),
+				CreatedAt:     time.Now().UTC(),
+				Symbol:        leg.symbol,
+				Side:          side,
+				Type:          models.OrderTypeMarket,
+				Size:          diff.Abs(),
+			},
+		}
+		c.pending[o.order.ClientOrderID] = true
+		orders = append(orders, o)
+	}
+	return orders
+}
+
+// place sends leg orders at once. It is called without the
+// lock, because simulated exchanges report fills synchronously.
+func (c *FundingCarry) place(orders []carryOrder) {
+	var wg sync.WaitGroup
+	for _, o := range orders {
+		o := o
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := o.venue.Orders.PlaceOrder(c.ctx, o.order)
+			if err != nil && !errors.Is(err, ErrPaused) {
+				logger.Warn("failed to place funding carry order",
+					"exchange", o.venue.Exchange,
+					"symbol", o.order.Symbol,
+					"side", o.order.Side,
+					"size", o.order.Size,
+					"err", err,
+				)
+			}
+			if err != nil || res.Status.IsFinal() {
+				c.mux.Lock()
+				delete(c.pending, o.order.ClientOrderID)
+				c.mux.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// OnOrderUpdate lets legs be rebalanced once their orders are finished.
+func (c *FundingCarry) OnOrderUpdate(upd models.OrderUpdate) {
+	if !upd.Status.IsFinal() {
+		return
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.pending[upd.ClientOrderID] && upd.Status != models.OrderStatusFilled {
+		logger.Warn("funding carry order is not filled", "symbol", upd.Symbol, "status", upd.Status)
+	}
+	delete(c.pending, upd.ClientOrderID)
+}
+
+// Funding returns funding accumulated by the strategy.
+func (c *FundingCarry) Funding() decimal.Decimal {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.funding
+}
+
+func (c *FundingCarry) Params() FundingCarryParams {
+	return *c.params.Load()
+}
+
+// SetParams validates and atomically replaces parameters,
+// symbols and spot exchange can not be changed.
+func (c *FundingCarry) SetParams(p FundingCarryParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	old := c.Params()
+	if p.Symbol != old.Symbol || p.SpotSymbol != old.SpotSymbol || p.SpotExchange != old.SpotExchange {
+		return fmt.Errorf("funding carry symbols %s, %s and spot exchange %s can not be changed at runtime",
+			old.Symbol, old.SpotSymbol, old.SpotExchange)
+	}
+
+	c.params.Store(&p)
+	return nil
+}
+
+func (c *FundingCarry) State() (json.RawMessage, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return json.Marshal(fundingCarryState{
+		Direction:   c.direction,
+		Funding:     c.funding,
+		Rate:        c.rate,
+		NextFunding: c.nextFunding,
+	})
+}
+
+func (c *FundingCarry) Restore(state json.RawMessage) error {
+	var st fundingCarryState
+	if err := json.Unmarshal(state, &st); err != nil {
+		return fmt.Errorf("funding carry failed to restore state: %w", err)
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.direction, c.funding = st.Direction, st.Funding
+	c.rate, c.nextFunding = st.Rate, st.NextFunding
+	return nil
+}
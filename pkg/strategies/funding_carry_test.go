@@ -0,0 +1,149 @@
+package strategies
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// positionOrders fills orders at once updating account position.
+type positionOrders struct {
+	acc    *models.Account
+	placed []models.Order
+	mux    sync.Mutex
+}
+
+func (f *positionOrders) PlaceOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	size := order.Size
+	if order.Side == models.OrderSideSell {
+		size = size.Neg()
+	}
+	pos := f.acc.GetPosition(order.Symbol)
+	f.acc.UpdatePosition(order.Symbol, pos.Amount.Add(size), decimal.Zero, time.Now())
+
+	order.Status, order.FilledSize = models.OrderStatusFilled, order.Size
+	f.placed = append(f.placed, order)
+	return &order, nil
+}
+
+func (f *positionOrders) CancelOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	order.Status = models.OrderStatusCanceled
+	return &order, nil
+}
+
+func (f *positionOrders) OpenOrders() []models.Order {
+	return nil
+}
+
+func TestFundingCarry(t *testing.T) {
+	p := DefaultFundingCarryParams()
+	p.SpotExchange, p.SpotSymbol = "spot", p.Symbol
+
+	perp := &positionOrders{acc: models.NewAccount("acc", "perp")}
+	spot := &positionOrders{acc: models.NewAccount("acc", "spot")}
+	c, err := NewFundingCarry(context.Background(),
+		Venue{Exchange: "perp", Account: perp.acc, Orders: perp},
+		Venue{Exchange: "spot", Account: spot.acc, Orders: spot},
+		p,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bbo := func(exchange string, mid float64) {
+		c.See(models.NewBBOMessage(exchange, p.Symbol, start, models.BBO{
+			Bid: models.PriceLevel{Price: decimal.NewFromFloat(mid - 0.05), Size: decimal.NewFromInt(1)},
+			Ask: models.PriceLevel{Price: decimal.NewFromFloat(mid + 0.05), Size: decimal.NewFromInt(1)},
+		}))
+	}
+	funding := func(rate float64, next time.Duration) {
+		c.See(models.NewFundingMessage("perp", p.Symbol, start, models.Funding{
+			MarkPrice:       decimal.NewFromInt(2000),
+			Rate:            decimal.NewFromFloat(rate),
+			NextFundingTime: start.Add(next),
+		}))
+	}
+	positions := func() (string, string) {
+		return spot.acc.GetPosition(p.Symbol).Amount.String(), perp.acc.GetPosition(p.Symbol).Amount.String()
+	}
+
+	bbo("spot", 2000)
+	bbo("perp", 2000)
+	funding(0.00005, 8*time.Hour)
+	if len(spot.placed)+len(perp.placed) != 0 {
+		t.Fatal("expected no trades below entry rate")
+	}
+
+	// Long spot and short perpetual for 1000 each.
+	funding(0.0003, 8*time.Hour)
+	if s, f := positions(); s != "0.5" || f != "-0.5" {
+		t.Fatalf("expected legs 0.5 and -0.5, got %s and %s", s, f)
+	}
+
+	// Small price move is within threshold, large one rebalances.
+	bbo("spot", 2050)
+	if len(spot.placed) != 1 {
+		t.Errorf("expected no rebalance, got %d spot orders", len(spot.placed))
+	}
+	bbo("spot", 2500)
+	if s, _ := positions(); s != "0.4" {
+		t.Errorf("expected spot leg rebalanced to 0.4, got %s", s)
+	}
+
+	// Short position receives settled funding.
+	funding(-0.0001, 16*time.Hour)
+	want := decimal.NewFromFloat(0.3) // 0.5 * 2000 * 0.0003
+	if got := c.Funding(); !got.Equal(want) || !perp.acc.GetFunding(p.Symbol).Equal(want) {
+		t.Errorf("expected funding %v, got %v", want, got)
+	}
+
+	// Flipped funding closes legs.
+	if s, f := positions(); s != "0" || f != "0" {
+		t.Errorf("expected closed legs, got %s and %s", s, f)
+	}
+}
+
+func TestFundingCarrySameVenue(t *testing.T) {
+	p := DefaultFundingCarryParams()
+	p.SpotSymbol = p.Symbol
+	orders := &positionOrders{acc: models.NewAccount("acc", "perp")}
+	venue := Venue{Exchange: "perp", Account: orders.acc, Orders: orders}
+
+	if _, err := NewFundingCarry(context.Background(), venue, venue, p); err == nil {
+		t.Fatal("expected error for both legs on the same contract")
+	}
+
+	// Delivery future stands for spot.
+	p.SpotSymbol = "ethusdt_250328"
+	c, err := NewFundingCarry(context.Background(), venue, venue, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, symbol := range []string{p.Symbol, p.SpotSymbol} {
+		c.See(models.NewBBOMessage("perp", symbol, start, models.BBO{
+			Bid: models.PriceLevel{Price: decimal.NewFromFloat(1999.95), Size: decimal.NewFromInt(1)},
+			Ask: models.PriceLevel{Price: decimal.NewFromFloat(2000.05), Size: decimal.NewFromInt(1)},
+		}))
+	}
+	c.See(models.NewFundingMessage("perp", p.Symbol, start, models.Funding{
+		MarkPrice:       decimal.NewFromInt(2000),
+		Rate:            decimal.NewFromFloat(0.0003),
+		NextFundingTime: start.Add(8 * time.Hour),
+	}))
+
+	spot, perp := orders.acc.GetPosition(p.SpotSymbol).Amount, orders.acc.GetPosition(p.Symbol).Amount
+	if spot.String() != "0.5" || perp.String() != "-0.5" {
+		t.Errorf("expected legs 0.5 and -0.5, got %s and %s", spot, perp)
+	}
+}
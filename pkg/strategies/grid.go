@@ -0,0 +1,406 @@
+package strategies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type GridSpacing string
+
+const (
+	// GridArithmetic levels are equally distant in price.
+	GridArithmetic GridSpacing = "arithmetic"
+	// GridGeometric levels are equally distant in percents.
+	GridGeometric GridSpacing = "geometric"
+)
+
+// GridParams configure Grid strategy.
+type GridParams struct {
+	Symbol string `json:"symbol"`
+	// Lower and Upper are prices of the lowest and the highest levels.
+	Lower   decimal.Decimal `json:"lower"`
+	Upper   decimal.Decimal `json:"upper"`
+	Levels  int             `json:"levels"`
+	Spacing GridSpacing     `json:"spacing"`
+	// OrderSize is a size of order at every level.
+	OrderSize decimal.Decimal `json:"order_size"`
+	TickSize  decimal.Decimal `json:"tick_size"`
+}
+
+func DefaultGridParams() GridParams {
+	return GridParams{
+		Symbol:    "ethusdt",
+		Lower:     decimal.NewFromInt(1800),
+		Upper:     decimal.NewFromInt(2200),
+		Levels:    11,
+		Spacing:   GridArithmetic,
+		OrderSize: decimal.NewFromFloat(0.05),
+		TickSize:  decimal.NewFromFloat(0.01),
+	}
+}
+
+// ParseGridParams parses parameters over the defaults and validates them.
+func ParseGridParams(raw json.RawMessage) (GridParams, error) {
+	p := DefaultGridParams()
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return p, fmt.Errorf("failed to parse grid params: %w", err)
+		}
+	}
+
+	return p, p.Validate()
+}
+
+func (p GridParams) Validate() error {
+	switch {
+	case p.Symbol == "":
+		return errors.New("grid symbol is empty")
+	case !p.Lower.IsPositive():
+		return fmt.Errorf("grid lower price %v is not positive", p.Lower)
+	case !p.Upper.GreaterThan(p.Lower):
+		return fmt.Errorf("grid upper price %v is not over lower price %v", p.Upper, p.Lower)
+	case p.Levels < 2:
+		return fmt.Errorf("grid has %d levels, at least 2 are required", p.Levels)
+	case p.Spacing != GridArithmetic && p.Spacing != GridGeometric:
+		return fmt.Errorf("grid spacing %q is not %s or %s", p.Spacing, GridArithmetic, GridGeometric)
+	case !p.OrderSize.IsPositive():
+		return fmt.Errorf("grid order size %v is not positive", p.OrderSize)
+	case !p.TickSize.IsPositive():
+		return fmt.Errorf("grid tick size %v is not positive", p.TickSize)
+	}
+
+	levels := p.Prices()
+	for i := 1; i < len(levels); i++ {
+		if !levels[i].GreaterThan(levels[i-1]) {
+			return fmt.Errorf("grid levels %v and %v are closer than tick size", levels[i-1], levels[i])
+		}
+	}
+	return nil
+}
+
+// Prices returns ascending prices of grid levels rounded to TickSize.
+func (p GridParams) Prices() []decimal.Decimal {
+	lower, upper := p.Lower.InexactFloat64(), p.Upper.InexactFloat64()
+	n := float64(p.Levels - 1)
+
+	prices := make([]decimal.Decimal, p.Levels)
+	for i := range prices {
+		var price float64
+		switch p.Spacing {
+		case GridGeometric:
+			price = lower * math.Pow(upper/lower, float64(i)/n)
+		default:
+			price = lower + (upper-lower)*float64(i)/n
+		}
+		prices[i] = decimal.NewFromFloat(price).Div(p.TickSize).Round(0).Mul(p.TickSize)
+	}
+	// Bounds are exact, not affected by floating point errors.
+	prices[0], prices[len(prices)-1] = p.Lower, p.Upper
+	return prices
+}
+
+// Grid keeps resting limit buys at levels below price and sells above,
+// leaving the level closest to price empty. When an order is filled,
+// the opposite one is placed at the next level: sell above filled buy
+// and buy below filled sell, and filled level becomes empty.
+// Grid is recovered from open orders at level prices after restart.
+type Grid struct {
+	ctx    context.Context
+	params atomic.Pointer[GridParams]
+	orders Orders
+
+	levels []decimal.Decimal
+	// placed are orders resting at levels by level index.
+	placed map[int]models.Order
+	// gap is index of the empty level or -1 if it is not chosen yet.
+	gap int
+	// synced is set once open orders are adopted.
+	synced bool
+	// saved are client order IDs by level restored from state.
+	saved map[int]string
+
+	mux sync.Mutex
+}
+
+type gridState struct {
+	Levels []decimal.Decimal `json:"levels"`
+	Orders map[int]string    `json:"orders"`
+	Gap    int               `json:"gap"`
+}
+
+func NewGrid(ctx context.Context, orders Orders, params GridParams) *Grid {
+	g := &Grid{
+		ctx:    ctx,
+		orders: orders,
+		placed: make(map[int]models.Order),
+		gap:    -1,
+	}
+	g.params.Store(&params)
+	return g
+}
+
+func (g *Grid) See(e models.ExchangeMessage) {
+	bbo, ok := e.BBO()
+	if !ok || !bbo.Bid.Price.IsPositive() || !bbo.Ask.Price.IsPositive() {
+		return
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	params := g.Params()
+	if !g.synced {
+		g.sync(params)
+	}
+	if levels := params.Prices(); !equalPrices(g.levels, levels) {
+		logger.Info("grid levels changed, rebuilding grid", "symbol", params.Symbol)
+		if _, err := g.cancelLocked(g.ctx); err != nil {
+			logger.Error("failed to cancel grid orders", "symbol", params.Symbol, "err", err)
+		}
+		g.levels, g.placed, g.gap = levels, make(map[int]models.Order), -1
+	}
+
+	mid := bbo.Bid.Price.Add(bbo.Ask.Price).Div(decimal.NewFromInt(2))
+	if g.gap < 0 {
+		g.gap = g.nearestFree(mid)
+	}
+
+	for i, price := range g.levels {
+		if _, ok := g.placed[i]; ok || i == g.gap {
+			continue
+		}
+
+		// Post-only orders crossing the book would be rejected.
+		side := models.OrderSideBuy
+		switch {
+		case price.LessThan(bbo.Ask.Price) && price.LessThan(mid):
+		case price.GreaterThan(bbo.Bid.Price) && price.GreaterThan(mid):
+			side = models.OrderSideSell
+		default:
+			continue
+		}
+		if err := g.place(params, i, side); errors.Is(err, ErrPaused) {
+			return
+		}
+	}
+}
+
+// sync adopts open orders of the grid: ones saved in state
+// or, without state, ones resting at level prices.
+func (g *Grid) sync(params GridParams) {
+	if g.levels == nil {
+		g.levels = params.Prices()
+	}
+
+	for _, o := range g.orders.OpenOrders() {
+		if o.Symbol != params.Symbol {
+			continue
+		}
+		for i, price := range g.levels {
+			_, taken := g.placed[i]
+			if (g.saved != nil && g.saved[i] == o.ClientOrderID) ||
+				(g.saved == nil && !taken && o.Price.Equal(price)) {
+				g.placed[i] = o
+				break
+			}
+		}
+	}
+
+	if len(g.placed) > 0 {
+		logger.Info("recovered grid orders", "symbol", params.Symbol, "orders", len(g.placed))
+	}
+	g.synced, g.saved = true, nil
+}
+
+// nearestFree returns index of the level without order closest to price.
+func (g *Grid) nearestFree(price decimal.Decimal) int {
+	gap := -1
+	var dist decimal.Decimal
+	for i, p := range g.levels {
+		if _, ok := g.placed[i]; ok {
+			continue
+		}
+		if d := p.Sub(price).Abs(); gap < 0 || d.LessThan(dist) {
+			gap, dist = i, d
+		}
+	}
+	return gap
+}
+
+func (g *Grid) place(params GridParams, level int, side models.OrderSide) error {
+	res, err := g.orders.PlaceOrder(g.ctx, models.Order{
+		ClientOrderID: uuid.New().String(),
+		CreatedAt:     time.Now().UTC(),
+		Symbol:        params.Symbol,
+		Side:          side,
+		Type:          models.OrderTypeLimit,
+		TimeInForce:   models.TimeInForceGTX,
+		Size:          params.OrderSize,
+		Price:         g.levels[level],
+	})
+	if err != nil {
+		if !errors.Is(err, ErrPaused) {
+			logger.Warn("failed to place grid order",
+				"symbol", params.Symbol,
+				"side", side,
+				"price", g.levels[level],
+				"err", err,
+			)
+		}
+		return err
+	}
+	if !res.Status.IsFinal() {
+		g.placed[level] = *res
+	}
+	return nil
+}
+
+// OnOrderUpdate places the opposite order when grid order is filled.
+// Partially filled orders are left resting until they are filled,
+// canceled ones are placed again on the next market data update.
+func (g *Grid) OnOrderUpdate(upd models.OrderUpdate) {
+	if !upd.Status.IsFinal() {
+		return
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	level := -1
+	for i, o := range g.placed {
+		if o.ClientOrderID == upd.ClientOrderID {
+			level = i
+		}
+	}
+	if level < 0 {
+		return
+	}
+
+	params := g.Params()
+	delete(g.placed, level)
+	if upd.Status != models.OrderStatusFilled {
+		logger.Warn("grid order is gone", "symbol", params.Symbol, "price", g.levels[level], "status", upd.Status)
+		return
+	}
+	g.gap = level
+
+	next, side := level+1, models.OrderSideSell
+	if upd.Side == models.OrderSideSell {
+		next, side = level-1, models.OrderSideBuy
+	}
+	logger.Info("grid order filled",
+		"symbol", params.Symbol,
+		"side", upd.Side,
+		"price", g.levels[level],
+	)
+	if _, ok := g.placed[next]; ok || next < 0 || next >= len(g.levels) {
+		return
+	}
+	_ = g.place(params, next, side)
+}
+
+// CancelOrders cancels grid orders, the grid is rebuilt
+// from current price on the next market data update.
+func (g *Grid) CancelOrders(ctx context.Context) (int, error) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	n, err := g.cancelLocked(ctx)
+	g.gap = -1
+	return n, err
+}
+
+func (g *Grid) cancelLocked(ctx context.Context) (int, error) {
+	var (
+		n        int
+		firstErr error
+	)
+	for i, o := range g.placed {
+		if _, err := g.orders.CancelOrder(ctx, o); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to cancel grid order at %v: %w", o.Price, err)
+			}
+			continue
+		}
+		delete(g.placed, i)
+		n++
+	}
+	return n, firstErr
+}
+
+func (g *Grid) Params() GridParams {
+	return *g.params.Load()
+}
+
+// SetParams validates and atomically replaces parameters, symbol
+// can not be changed. Grid is rebuilt if its levels changed.
+func (g *Grid) SetParams(p GridParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if old := g.Params(); p.Symbol != old.Symbol {
+		return fmt.Errorf("grid symbol can not be changed from %s to %s at runtime", old.Symbol, p.Symbol)
+	}
+
+	g.params.Store(&p)
+	return nil
+}
+
+func (g *Grid) State() (json.RawMessage, error) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	st := gridState{
+		Levels: g.levels,
+		Orders: make(map[int]string, len(g.placed)),
+		Gap:    g.gap,
+	}
+	for i, o := range g.placed {
+		st.Orders[i] = o.ClientOrderID
+	}
+	return json.Marshal(st)
+}
+
+// Restore restores grid levels and its orders, which are
+// picked up from open orders on the next market data update.
+func (g *Grid) Restore(state json.RawMessage) error {
+	var st gridState
+	if err := json.Unmarshal(state, &st); err != nil {
+		return fmt.Errorf("grid failed to restore state: %w", err)
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	g.levels, g.gap = st.Levels, st.Gap
+	g.placed = make(map[int]models.Order)
+	g.saved = st.Orders
+	if g.saved == nil {
+		g.saved = make(map[int]string)
+	}
+	g.synced = false
+	return nil
+}
+
+func equalPrices(a, b []decimal.Decimal) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
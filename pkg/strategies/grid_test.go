@@ -0,0 +1,118 @@
+package strategies
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestGridPrices(t *testing.T) {
+	p := DefaultGridParams()
+	p.Lower, p.Upper, p.Levels = decimal.NewFromInt(100), decimal.NewFromInt(400), 3
+
+	want := map[GridSpacing][]string{
+		GridArithmetic: {"100", "250", "400"},
+		GridGeometric:  {"100", "200", "400"},
+	}
+	for spacing, prices := range want {
+		p.Spacing = spacing
+		got := p.Prices()
+		for i := range prices {
+			if got[i].String() != prices[i] {
+				t.Errorf("%s: expected %v, got %v", spacing, prices, got)
+				break
+			}
+		}
+	}
+
+	p.Levels = 1000
+	if err := p.Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	p.TickSize = decimal.NewFromInt(1)
+	if err := p.Validate(); err == nil {
+		t.Error("expected error for levels closer than tick size")
+	}
+}
+
+func TestGrid(t *testing.T) {
+	p := DefaultGridParams()
+	p.Lower, p.Upper, p.Levels = decimal.NewFromInt(1900), decimal.NewFromInt(2100), 5
+
+	bbo := func(bid float64) models.ExchangeMessage {
+		return models.NewBBOMessage("ex", p.Symbol, time.Now(), models.BBO{
+			Bid: models.PriceLevel{Price: decimal.NewFromFloat(bid), Size: decimal.NewFromInt(1)},
+			Ask: models.PriceLevel{Price: decimal.NewFromFloat(bid + 0.1), Size: decimal.NewFromInt(1)},
+		})
+	}
+	sides := func(orders *fakeOrders) map[string]models.OrderSide {
+		res := make(map[string]models.OrderSide)
+		for _, o := range orders.open {
+			res[o.Price.String()] = o.Side
+		}
+		return res
+	}
+
+	orders := newFakeOrders()
+	g := NewGrid(context.Background(), orders, p)
+	g.See(bbo(2010))
+
+	// Level 2000 closest to price is left empty.
+	got := sides(orders)
+	want := map[string]models.OrderSide{
+		"1900": models.OrderSideBuy,
+		"1950": models.OrderSideBuy,
+		"2050": models.OrderSideSell,
+		"2100": models.OrderSideSell,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for price, side := range want {
+		if got[price] != side {
+			t.Errorf("expected %s at %s, got %v", side, price, got)
+		}
+	}
+
+	// Filled buy at 1950 is replaced with sell at 2000.
+	var filled models.Order
+	for _, o := range orders.open {
+		if o.Price.Equal(decimal.NewFromInt(1950)) {
+			filled = o
+		}
+	}
+	delete(orders.open, filled.ClientOrderID)
+	g.OnOrderUpdate(models.OrderUpdate{
+		ClientOrderID: filled.ClientOrderID,
+		Status:        models.OrderStatusFilled,
+		Side:          models.OrderSideBuy,
+		Symbol:        p.Symbol,
+	})
+	g.See(bbo(1949))
+	if got := sides(orders); got["2000"] != models.OrderSideSell || got["1950"] != "" || len(got) != 4 {
+		t.Errorf("expected sell at 2000 and empty 1950, got %v", got)
+	}
+
+	// Grid is recovered from open orders after restart.
+	placed := len(orders.placed)
+	state, err := g.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, restored := range []bool{true, false} {
+		g := NewGrid(context.Background(), orders, p)
+		if restored {
+			if err := g.Restore(state); err != nil {
+				t.Fatal(err)
+			}
+		}
+		g.See(bbo(1949))
+		if len(orders.placed) != placed || len(orders.open) != 4 {
+			t.Errorf("restored %v: expected grid to be recovered, got %d orders placed", restored, len(orders.placed)-placed)
+		}
+	}
+}
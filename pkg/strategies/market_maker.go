@@ -0,0 +1,399 @@
+package strategies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// MarketMakerParams configure MarketMaker strategy.
+type MarketMakerParams struct {
+	Symbol    string          `json:"symbol"`
+	OrderSize decimal.Decimal `json:"order_size"`
+	// MaxPosition is an inventory limit, quotes which could
+	// take position over it are reduced or not placed.
+	MaxPosition decimal.Decimal `json:"max_position"`
+	// RiskAversion (gamma) skews quotes against inventory
+	// and widens spread with volatility.
+	RiskAversion float64 `json:"risk_aversion"`
+	// Liquidity (k) is order book liquidity, quotes are
+	// the tighter the higher it is.
+	Liquidity float64 `json:"liquidity"`
+	// Horizon is a time in seconds inventory is expected to be held.
+	Horizon float64 `json:"horizon"`
+	// VolWindow is a number of mid price changes
+	// volatility is estimated over.
+	VolWindow int `json:"vol_window"`
+	// MinSpread is a minimal spread relative to mid price.
+	MinSpread decimal.Decimal `json:"min_spread"`
+	// RequoteThreshold is a minimal quote price change relative to mid
+	// price, quotes moving less are not canceled and replaced.
+	RequoteThreshold decimal.Decimal `json:"requote_threshold"`
+	TickSize         decimal.Decimal `json:"tick_size"`
+}
+
+func DefaultMarketMakerParams() MarketMakerParams {
+	return MarketMakerParams{
+		Symbol:           "ethusdt",
+		OrderSize:        decimal.NewFromFloat(0.05),
+		MaxPosition:      decimal.NewFromFloat(0.5),
+		RiskAversion:     0.1,
+		Liquidity:        1.5,
+		Horizon:          60,
+		VolWindow:        100,
+		MinSpread:        decimal.NewFromFloat(0.0002),
+		RequoteThreshold: decimal.NewFromFloat(0.0001),
+		TickSize:         decimal.NewFromFloat(0.01),
+	}
+}
+
+// ParseMarketMakerParams parses parameters over the defaults and validates them.
+func ParseMarketMakerParams(raw json.RawMessage) (MarketMakerParams, error) {
+	p := DefaultMarketMakerParams()
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return p, fmt.Errorf("failed to parse market maker params: %w", err)
+		}
+	}
+
+	return p, p.Validate()
+}
+
+func (p MarketMakerParams) Validate() error {
+	switch {
+	case p.Symbol == "":
+		return errors.New("market maker symbol is empty")
+	case !p.OrderSize.IsPositive():
+		return fmt.Errorf("market maker order size %v is not positive", p.OrderSize)
+	case p.MaxPosition.LessThan(p.OrderSize):
+		return fmt.Errorf("market maker max position %v is less than order size %v", p.MaxPosition, p.OrderSize)
+	case p.RiskAversion <= 0:
+		return fmt.Errorf("market maker risk aversion %v is not positive", p.RiskAversion)
+	case p.Liquidity <= 0:
+		return fmt.Errorf("market maker liquidity %v is not positive", p.Liquidity)
+	case p.Horizon <= 0:
+		return fmt.Errorf("market maker horizon %v is not positive", p.Horizon)
+	case p.VolWindow < 2:
+		return fmt.Errorf("market maker volatility window %d is less than 2", p.VolWindow)
+	case p.MinSpread.IsNegative():
+		return fmt.Errorf("market maker min spread %v is negative", p.MinSpread)
+	case p.RequoteThreshold.IsNegative():
+		return fmt.Errorf("market maker requote threshold %v is negative", p.RequoteThreshold)
+	case !p.TickSize.IsPositive():
+		return fmt.Errorf("market maker tick size %v is not positive", p.TickSize)
+	}
+	return nil
+}
+
+// Quotes returns bid and ask prices around reservation price
+// of Avellaneda-Stoikov model:
+//
+//	r = mid - q * gamma * variance * horizon
+//	spread = gamma * variance * horizon + 2 / gamma * ln(1 + gamma / k)
+//
+// where variance is mid price variance per second and q is position
+// in order sizes. Spread is at least MinSpread, prices are rounded
+// away from mid to TickSize.
+func (p MarketMakerParams) Quotes(mid, variance float64, position decimal.Decimal) (bid, ask decimal.Decimal) {
+	q := position.Div(p.OrderSize).InexactFloat64()
+	risk := p.RiskAversion * variance * p.Horizon
+	reservation := mid - q*risk
+	spread := risk + 2/p.RiskAversion*math.Log(1+p.RiskAversion/p.Liquidity)
+	if minSpread := p.MinSpread.InexactFloat64() * mid; spread < minSpread {
+		spread = minSpread
+	}
+
+	tick := p.TickSize.InexactFloat64()
+	bid = decimal.NewFromFloat(math.Floor((reservation - spread/2) / tick)).Mul(p.TickSize)
+	ask = decimal.NewFromFloat(math.Ceil((reservation + spread/2) / tick)).Mul(p.TickSize)
+	return bid, ask
+}
+
+// QuoteSizes returns sizes of quotes keeping position within MaxPosition.
+func (p MarketMakerParams) QuoteSizes(position decimal.Decimal) (bid, ask decimal.Decimal) {
+	bid = decimal.Min(p.OrderSize, p.MaxPosition.Sub(position))
+	ask = decimal.Min(p.OrderSize, p.MaxPosition.Add(position))
+	return decimal.Max(bid, decimal.Zero), decimal.Max(ask, decimal.Zero)
+}
+
+// volSample is a mid price change over time in seconds.
+type volSample struct {
+	Change float64 `json:"change"`
+	Time   float64 `json:"time"`
+}
+
+// MarketMaker quotes both sides of the book with post-only limit orders
+// around reservation price skewed against inventory, see Quotes.
+// Quotes are replaced when they move over RequoteThreshold,
+// nothing is quoted until volatility window is filled.
+type MarketMaker struct {
+	ctx    context.Context
+	params atomic.Pointer[MarketMakerParams]
+	acc    *models.Account
+	orders Orders
+
+	samples  []volSample
+	next     int
+	lastMid  float64
+	lastTime time.Time
+	quotes   map[models.OrderSide]models.Order
+
+	mux sync.Mutex
+}
+
+type marketMakerState struct {
+	Samples  []volSample                 `json:"samples"`
+	LastMid  float64                     `json:"last_mid"`
+	LastTime time.Time                   `json:"last_time"`
+	Quotes   map[models.OrderSide]string `json:"quotes"`
+}
+
+func NewMarketMaker(
+	ctx context.Context,
+	acc *models.Account,
+	orders Orders,
+	params MarketMakerParams,
+) *MarketMaker {
+	m := &MarketMaker{
+		ctx:    ctx,
+		acc:    acc,
+		orders: orders,
+		quotes: make(map[models.OrderSide]models.Order),
+	}
+	m.params.Store(&params)
+	return m
+}
+
+func (m *MarketMaker) See(e models.ExchangeMessage) {
+	bbo, ok := e.BBO()
+	if !ok || !bbo.Bid.Price.IsPositive() || !bbo.Ask.Price.IsPositive() {
+		return
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	params := m.Params()
+	mid := bbo.Bid.Price.Add(bbo.Ask.Price).Div(decimal.NewFromInt(2))
+	variance, ok := m.observe(params, mid.InexactFloat64(), e.Timestamp)
+	if !ok {
+		return
+	}
+
+	pos := m.acc.GetPosition(params.Symbol).Amount
+	bid, ask := params.Quotes(mid.InexactFloat64(), variance, pos)
+	bidSize, askSize := params.QuoteSizes(pos)
+
+	// Post-only orders crossing the book are rejected.
+	bid = decimal.Min(bid, bbo.Ask.Price.Sub(params.TickSize))
+	ask = decimal.Max(ask, bbo.Bid.Price.Add(params.TickSize))
+
+	m.syncQuotes()
+	threshold := mid.Mul(params.RequoteThreshold)
+	m.quote(params, models.OrderSideBuy, bid, bidSize, threshold)
+	m.quote(params, models.OrderSideSell, ask, askSize, threshold)
+}
+
+// observe adds mid price to volatility window and returns variance
+// of mid price per second once the window is filled.
+func (m *MarketMaker) observe(params MarketMakerParams, mid float64, ts time.Time) (float64, bool) {
+	if len(m.samples) != params.VolWindow && m.next != len(m.samples) {
+		// Window was resized by new parameters: keep the latest samples.
+		m.samples = m.ordered()
+		if len(m.samples) > params.VolWindow {
+			m.samples = m.samples[len(m.samples)-params.VolWindow:]
+		}
+		m.next = len(m.samples) % params.VolWindow
+	}
+	if m.lastMid > 0 && mid != m.lastMid {
+		s := volSample{Change: mid - m.lastMid, Time: ts.Sub(m.lastTime).Seconds()}
+		if len(m.samples) < params.VolWindow {
+			m.samples = append(m.samples, s)
+		} else {
+			m.samples[m.next] = s
+		}
+		m.next = (m.next + 1) % params.VolWindow
+	}
+	if mid != m.lastMid {
+		m.lastMid, m.lastTime = mid, ts
+	}
+
+	if len(m.samples) < params.VolWindow {
+		return 0, false
+	}
+
+	var squares, elapsed float64
+	for _, s := range m.samples {
+		squares += s.Change * s.Change
+		elapsed += s.Time
+	}
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return squares / elapsed, true
+}
+
+// syncQuotes forgets quotes which are not open anymore.
+func (m *MarketMaker) syncQuotes() {
+	if len(m.quotes) == 0 {
+		return
+	}
+
+	open := make(map[string]bool)
+	for _, o := range m.orders.OpenOrders() {
+		open[o.ClientOrderID] = true
+	}
+	for side, q := range m.quotes {
+		if !open[q.ClientOrderID] {
+			delete(m.quotes, side)
+		}
+	}
+}
+
+// quote keeps the side quoted at price, replacing quote
+// if its price moved over threshold or size changed.
+func (m *MarketMaker) quote(params MarketMakerParams, side models.OrderSide, price, size, threshold decimal.Decimal) {
+	if q, ok := m.quotes[side]; ok {
+		if q.Size.Equal(size) && q.Price.Sub(price).Abs().LessThanOrEqual(threshold) {
+			return
+		}
+		if _, err := m.orders.CancelOrder(m.ctx, q); err != nil {
+			// Quote is kept until it is gone from open orders,
+			// so the side is never quoted twice.
+			logger.Warn("failed to cancel quote",
+				"symbol", params.Symbol,
+				"client_order_id", q.ClientOrderID,
+				"err", err,
+			)
+			return
+		}
+		delete(m.quotes, side)
+	}
+
+	if !size.IsPositive() {
+		return
+	}
+
+	res, err := m.orders.PlaceOrder(m.ctx, models.Order{
+		ClientOrderID: uuid.New().String(),
+		CreatedAt:     time.Now().UTC(),
+		Symbol:        params.Symbol,
+		Side:          side,
+		Type:          models.OrderTypeLimit,
+		TimeInForce:   models.TimeInForceGTX,
+		Size:          size,
+		Price:         price,
+	})
+	if err != nil {
+		if !errors.Is(err, ErrPaused) {
+			logger.Warn("failed to place quote", "symbol", params.Symbol, "side", side, "price", price, "err", err)
+		}
+		return
+	}
+	if !res.Status.IsFinal() {
+		m.quotes[side] = *res
+	}
+}
+
+// CancelOrders cancels quotes, e.g. when the strategy is paused.
+func (m *MarketMaker) CancelOrders(ctx context.Context) (int, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	var (
+		n        int
+		firstErr error
+	)
+	for side, q := range m.quotes {
+		if _, err := m.orders.CancelOrder(ctx, q); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to cancel %s quote: %w", side, err)
+			}
+			continue
+		}
+		delete(m.quotes, side)
+		n++
+	}
+	return n, firstErr
+}
+
+func (m *MarketMaker) Params() MarketMakerParams {
+	return *m.params.Load()
+}
+
+// SetParams validates and atomically replaces parameters,
+// symbol can not be changed.
+func (m *MarketMaker) SetParams(p MarketMakerParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if old := m.Params(); p.Symbol != old.Symbol {
+		return fmt.Errorf("market maker symbol can not be changed from %s to %s at runtime", old.Symbol, p.Symbol)
+	}
+
+	m.params.Store(&p)
+	return nil
+}
+
+// ordered returns volatility samples from the oldest one.
+// Caller must hold the lock.
+func (m *MarketMaker) ordered() []volSample {
+	samples := make([]volSample, 0, len(m.samples))
+	samples = append(samples, m.samples[m.next:]...)
+	return append(samples, m.samples[:m.next]...)
+}
+
+func (m *MarketMaker) State() (json.RawMessage, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	st := marketMakerState{
+		Samples:  m.ordered(),
+		LastMid:  m.lastMid,
+		LastTime: m.lastTime,
+		Quotes:   make(map[models.OrderSide]string, len(m.quotes)),
+	}
+	for side, q := range m.quotes {
+		st.Quotes[side] = q.ClientOrderID
+	}
+	return json.Marshal(st)
+}
+
+// Restore restores volatility window and picks up quotes
+// which are still open.
+func (m *MarketMaker) Restore(state json.RawMessage) error {
+	var st marketMakerState
+	if err := json.Unmarshal(state, &st); err != nil {
+		return fmt.Errorf("market maker failed to restore state: %w", err)
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	window := m.Params().VolWindow
+	if len(st.Samples) > window {
+		st.Samples = st.Samples[len(st.Samples)-window:]
+	}
+	m.samples, m.next = st.Samples, len(st.Samples)%window
+	m.lastMid, m.lastTime = st.LastMid, st.LastTime
+
+	m.quotes = make(map[models.OrderSide]models.Order)
+	for _, o := range m.orders.OpenOrders() {
+		for side, id := range st.Quotes {
+			if o.ClientOrderID == id {
+				m.quotes[side] = o
+			}
+		}
+	}
+	return nil
+}
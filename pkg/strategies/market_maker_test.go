@@ -0,0 +1,156 @@
+package strategies
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// fakeOrders keeps placed orders open until they are canceled.
+type fakeOrders struct {
+	open     map[string]models.Order
+	placed   []models.Order
+	canceled int
+}
+
+func newFakeOrders() *fakeOrders {
+	return &fakeOrders{open: make(map[string]models.Order)}
+}
+
+func (f *fakeOrders) PlaceOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	order.ExchangeOrderID = strconv.Itoa(len(f.placed) + 1)
+	order.Status = models.OrderStatusPlaced
+	f.placed = append(f.placed, order)
+	f.open[order.ClientOrderID] = order
+	return &order, nil
+}
+
+func (f *fakeOrders) CancelOrder(_ context.Context, order models.Order) (*models.Order, error) {
+	delete(f.open, order.ClientOrderID)
+	f.canceled++
+	order.Status = models.OrderStatusCanceled
+	return &order, nil
+}
+
+func (f *fakeOrders) OpenOrders() []models.Order {
+	var orders []models.Order
+	for _, o := range f.open {
+		orders = append(orders, o)
+	}
+	return orders
+}
+
+func TestMarketMakerQuotes(t *testing.T) {
+	p := DefaultMarketMakerParams()
+	p.MinSpread = decimal.Zero
+
+	bid, ask := p.Quotes(2000, 0.01, decimal.Zero)
+	if !bid.LessThan(decimal.NewFromInt(2000)) || !ask.GreaterThan(decimal.NewFromInt(2000)) {
+		t.Fatalf("expected quotes around mid, got %v %v", bid, ask)
+	}
+
+	// Long inventory moves quotes down to sell it.
+	longBid, longAsk := p.Quotes(2000, 0.01, p.MaxPosition)
+	if !longBid.LessThan(bid) || !longAsk.LessThan(ask) {
+		t.Errorf("expected quotes skewed down, got %v %v", longBid, longAsk)
+	}
+
+	// Higher volatility widens spread.
+	wideBid, wideAsk := p.Quotes(2000, 1, decimal.Zero)
+	if !wideAsk.Sub(wideBid).GreaterThan(ask.Sub(bid)) {
+		t.Errorf("expected wider spread, got %v %v", wideBid, wideAsk)
+	}
+
+	p.MinSpread = decimal.NewFromFloat(0.01)
+	bid, ask = p.Quotes(2000, 0.01, decimal.Zero)
+	if ask.Sub(bid).LessThan(decimal.NewFromInt(20)) {
+		t.Errorf("expected min spread, got %v %v", bid, ask)
+	}
+
+	bidSize, askSize := p.QuoteSizes(p.MaxPosition)
+	if !bidSize.IsZero() || !askSize.Equal(p.OrderSize) {
+		t.Errorf("expected only ask at max position, got %v %v", bidSize, askSize)
+	}
+}
+
+func TestMarketMakerRequote(t *testing.T) {
+	p := DefaultMarketMakerParams()
+	p.VolWindow = 2
+	p.RequoteThreshold = decimal.NewFromFloat(0.001)
+
+	orders := newFakeOrders()
+	mm := NewMarketMaker(context.Background(), models.NewAccount("acc", "ex"), orders, p)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	see := func(sec int, bid float64) {
+		mm.See(models.NewBBOMessage("ex", p.Symbol, start.Add(time.Duration(sec)*time.Second), models.BBO{
+			Bid: models.PriceLevel{Price: decimal.NewFromFloat(bid), Size: decimal.NewFromInt(1)},
+			Ask: models.PriceLevel{Price: decimal.NewFromFloat(bid + 0.1), Size: decimal.NewFromInt(1)},
+		}))
+	}
+
+	see(0, 2000)
+	see(1, 2000.5)
+	if len(orders.placed) != 0 {
+		t.Fatalf("expected no quotes while volatility is warming up, got %d", len(orders.placed))
+	}
+
+	see(2, 2000)
+	if len(orders.open) != 2 {
+		t.Fatalf("expected two quotes, got %d", len(orders.open))
+	}
+	for _, o := range orders.placed {
+		if o.TimeInForce != models.TimeInForceGTX || o.Type != models.OrderTypeLimit {
+			t.Errorf("expected post-only limit order, got %+v", o)
+		}
+	}
+
+	// Small move keeps quotes.
+	see(3, 2000.5)
+	if len(orders.placed) != 2 {
+		t.Errorf("expected quotes to be kept, got %d orders", len(orders.placed))
+	}
+
+	see(4, 2010)
+	if len(orders.placed) != 4 || orders.canceled != 2 || len(orders.open) != 2 {
+		t.Errorf("expected quotes to be replaced, got %d placed, %d canceled", len(orders.placed), orders.canceled)
+	}
+
+	if n, err := mm.CancelOrders(context.Background()); err != nil || n != 2 || len(orders.open) != 0 {
+		t.Errorf("expected quotes to be canceled, got %d, %v", n, err)
+	}
+}
+
+func TestMarketMakerShrinkWindow(t *testing.T) {
+	p := DefaultMarketMakerParams()
+	p.VolWindow = 4
+	mm := NewMarketMaker(context.Background(), models.NewAccount("acc", "ex"), newFakeOrders(), p)
+
+	// Changes 1..6 a second apart wrap the window of 4.
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mid := 100.0
+	mm.observe(p, mid, start)
+	for i := 1; i <= 6; i++ {
+		mid += float64(i)
+		mm.observe(p, mid, start.Add(time.Duration(i)*time.Second))
+	}
+
+	// The latest changes 5 and 6 are kept.
+	p.VolWindow = 2
+	variance, ok := mm.observe(p, mid, start.Add(6*time.Second))
+	if !ok || variance != (25+36)/2.0 {
+		t.Errorf("expected variance %v of latest changes, got %v %v", (25+36)/2.0, variance, ok)
+	}
+
+	// The next change replaces the oldest one.
+	mid += 7
+	variance, ok = mm.observe(p, mid, start.Add(7*time.Second))
+	if !ok || variance != (36+49)/2.0 {
+		t.Errorf("expected variance %v after wrap, got %v %v", (36+49)/2.0, variance, ok)
+	}
+}
@@ -2,37 +2,127 @@ package strategies
 
 import (
 	"context"
-	"log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"degen/pkg/execution"
+	"degen/pkg/indicators"
 	"degen/pkg/models"
+	"degen/pkg/sizing"
 
 	"github.com/shopspring/decimal"
 )
 
-const (
-	patience = 1
-	symbol   = "ethusdt"
-	slippage = 0.005
-)
+// MonkeyParams configure Monkey strategy.
+type MonkeyParams struct {
+	Symbol string `json:"symbol"`
+	// Patience is a number of consecutive price moves
+	// in one direction before opening a position.
+	Patience int `json:"patience"`
+	// Slippage is an expected profit margin relative to price
+	// required to close a position.
+	Slippage decimal.Decimal `json:"slippage"`
+	// OrderSize is a position size of the default fixed sizing.
+	OrderSize decimal.Decimal `json:"order_size"`
+	// Sizing is a model positions are sized with.
+	Sizing sizing.Config `json:"sizing"`
+	// Execution is an algorithm orders are worked with,
+	// a single market order by default.
+	Execution execution.Config `json:"execution"`
+}
+
+// DefaultMonkeyParams returns parameters Monkey used to have hardcoded.
+func DefaultMonkeyParams() MonkeyParams {
+	return MonkeyParams{
+		Symbol:    "ethusdt",
+		Patience:  1,
+		Slippage:  decimal.NewFromFloat(0.005),
+		OrderSize: decimal.NewFromFloat(0.25),
+	}
+}
+
+// ParseMonkeyParams parses parameters over the defaults and validates them.
+func ParseMonkeyParams(raw json.RawMessage) (MonkeyParams, error) {
+	p := DefaultMonkeyParams()
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return p, fmt.Errorf("failed to parse monkey params: %w", err)
+		}
+	}
+
+	return p, p.Validate()
+}
+
+func (p MonkeyParams) Validate() error {
+	switch {
+	case p.Symbol == "":
+		return errors.New("monkey symbol is empty")
+	case p.Patience < 0:
+		return fmt.Errorf("monkey patience %d is negative", p.Patience)
+	case p.Slippage.IsNegative() || p.Slippage.GreaterThanOrEqual(decimal.NewFromInt(1)):
+		return fmt.Errorf("monkey slippage %v is out of [0, 1) range", p.Slippage)
+	case !p.OrderSize.IsPositive():
+		return fmt.Errorf("monkey order size %v is not positive", p.OrderSize)
+	}
+	if err := p.Execution.Validate(); err != nil {
+		return fmt.Errorf("monkey %w", err)
+	}
+	if _, err := p.model(); err != nil {
+		return fmt.Errorf("monkey: %w", err)
+	}
+	return nil
+}
+
+// model returns sizing model of the parameters,
+// fixed size defaults to order size.
+func (p MonkeyParams) model() (sizing.Model, error) {
+	cfg := p.Sizing
+	if cfg.Size.IsZero() {
+		cfg.Size = p.OrderSize
+	}
+	return sizing.New(cfg)
+}
 
 type Monkey struct {
-	ch               chan models.OrderSide
+	params           atomic.Pointer[MonkeyParams]
+	ch               chan sizing.Target
 	cntUp, cntDown   int
 	prevBid, prevAsk models.PriceLevel
 	numEvents        uint32
 	acc              *models.Account
+	onSignal         func(sizing.Target)
+	done             <-chan struct{}
+	// candles and vol estimate volatility of 1 minute returns.
+	candles   *indicators.Candles
+	vol       *indicators.RealizedVol
+	volWindow int
+
+	mux sync.Mutex
+}
+
+type monkeyState struct {
+	CntUp   int               `json:"cnt_up"`
+	CntDown int               `json:"cnt_down"`
+	PrevBid models.PriceLevel `json:"prev_bid"`
+	PrevAsk models.PriceLevel `json:"prev_ask"`
 }
 
 func NewMonkey(
 	ctx context.Context,
 	acc *models.Account,
+	params MonkeyParams,
 ) *Monkey {
 	m := &Monkey{
-		ch:  make(chan models.OrderSide),
-		acc: acc,
+		ch:      make(chan sizing.Target),
+		acc:     acc,
+		done:    ctx.Done(),
+		candles: indicators.NewCandles(time.Minute),
 	}
+	m.params.Store(&params)
 
 	go func() {
 		ticker := time.NewTicker(time.Second)
@@ -51,20 +141,27 @@ func NewMonkey(
 }
 
 func (m *Monkey) See(e models.ExchangeMessage) {
-	pos := m.acc.GetPosition(symbol)
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	params := m.Params()
+	pos := m.acc.GetPosition(params.Symbol)
+	slippage := params.Slippage
+	patience := params.Patience
 	switch e.MsgType {
 	case models.MsgTypeBBO:
-		bbo := e.Payload.(models.BBO)
+		bbo, _ := e.BBO()
+		m.seeVolatility(params, e)
 		if !m.prevAsk.Price.IsZero() {
 			if m.prevAsk.Price.GreaterThan(bbo.Ask.Price) {
 				m.cntUp++
-				log.Printf("^^^ %d\n", m.cntUp)
+				logger.Debug("ask tick", "symbol", params.Symbol, "count", m.cntUp)
 
 				// Closing short position if expected PnL > profitMargin.
-				profitMargin := bbo.Ask.Price.Mul(decimal.NewFromFloat(slippage))
+				profitMargin := bbo.Ask.Price.Mul(slippage)
 				if pos.Amount.IsNegative() &&
 					pos.EntryPrice.GreaterThan(bbo.Ask.Price.Add(profitMargin)) {
-					m.ch <- models.OrderSideBuy
+					m.close(params, bbo)
 					m.prevAsk = bbo.Ask
 					return
 				}
@@ -77,7 +174,7 @@ func (m *Monkey) See(e models.ExchangeMessage) {
 				if m.cntUp > patience {
 					// Opening long position if price is going up.
 					if atomic.AddUint32(&m.numEvents, 1) < 4 {
-						m.ch <- models.OrderSideBuy
+						m.open(params, models.OrderSideBuy, bbo)
 					}
 					m.cntUp = 0
 				}
@@ -92,12 +189,12 @@ func (m *Monkey) See(e models.ExchangeMessage) {
 		if !m.prevBid.Price.IsZero() {
 			if m.prevBid.Price.LessThan(bbo.Bid.Price) {
 				m.cntDown++
-				log.Printf("vvv %d\n", m.cntDown)
+				logger.Debug("bid tick", "symbol", params.Symbol, "count", m.cntDown)
 
-				profitMargin := bbo.Bid.Price.Mul(decimal.NewFromFloat(slippage))
+				profitMargin := bbo.Bid.Price.Mul(slippage)
 				if pos.Amount.IsPositive() &&
 					pos.EntryPrice.LessThan(bbo.Bid.Price.Sub(profitMargin)) {
-					m.ch <- models.OrderSideSell
+					m.close(params, bbo)
 					m.prevBid = bbo.Bid
 					return
 				}
@@ -110,7 +207,7 @@ func (m *Monkey) See(e models.ExchangeMessage) {
 				if m.cntDown > patience {
 					// Opening short position if price is going up.
 					if atomic.AddUint32(&m.numEvents, 1) < 4 {
-						m.ch <- models.OrderSideSell
+						m.open(params, models.OrderSideSell, bbo)
 					}
 					m.cntDown = 0
 				}
@@ -123,6 +220,130 @@ func (m *Monkey) See(e models.ExchangeMessage) {
 	}
 }
 
-func (m *Monkey) Say() <-chan models.OrderSide {
+// seeVolatility updates volatility estimate with 1 minute candles of mid
+// price, if the sizing model uses it. Caller must hold the lock.
+func (m *Monkey) seeVolatility(params MonkeyParams, msg models.ExchangeMessage) {
+	if params.Sizing.Model != sizing.ModelVolTarget {
+		return
+	}
+	if m.vol == nil || m.volWindow != params.Sizing.VolWindow {
+		m.vol, m.volWindow = indicators.NewRealizedVol(params.Sizing.VolWindow), params.Sizing.VolWindow
+	}
+	if candle, ok := m.candles.Add(msg); ok {
+		m.vol.Update(candle.Close)
+	}
+}
+
+// open signals target position on the side sized by the sizing model.
+func (m *Monkey) open(params MonkeyParams, side models.OrderSide, bbo models.BBO) {
+	model, err := params.model()
+	if err != nil {
+		logger.Error("monkey failed to create sizing model", "err", err)
+		return
+	}
+	mid := indicators.Mid(bbo)
+	price := decimal.NewFromFloat(mid)
+	in := sizing.Input{
+		Price:  price,
+		Equity: sizing.Equity(m.acc, params.Sizing.Asset, params.Symbol, price),
+	}
+	if m.vol != nil && m.vol.Ready() {
+		in.Volatility = m.vol.Value()
+	}
+
+	size := model.Position(in)
+	if !size.IsPositive() {
+		logger.Debug("position is not sized", "symbol", params.Symbol, "side", side)
+		return
+	}
+	if side == models.OrderSideSell {
+		size = size.Neg()
+	}
+	m.signal(sizing.Target{Symbol: params.Symbol, Position: size, Price: price})
+}
+
+// close signals flat target position.
+func (m *Monkey) close(params MonkeyParams, bbo models.BBO) {
+	m.signal(sizing.Target{
+		Symbol:   params.Symbol,
+		Position: decimal.Zero,
+		Price:    decimal.NewFromFloat(indicators.Mid(bbo)),
+	})
+}
+
+// OnSignal makes See call fn synchronously instead of sending
+// to Say() channel, so backtests are deterministic.
+func (m *Monkey) OnSignal(fn func(sizing.Target)) {
+	m.mux.Lock()
+	m.onSignal = fn
+	m.mux.Unlock()
+}
+
+func (m *Monkey) signal(t sizing.Target) {
+	if m.onSignal != nil {
+		m.onSignal(t)
+		return
+	}
+	select {
+	case m.ch <- t:
+	case <-m.done:
+	}
+}
+
+// Say returns channel of target positions.
+func (m *Monkey) Say() <-chan sizing.Target {
 	return m.ch
 }
+
+func (m *Monkey) Sizing() sizing.Config {
+	return m.Params().Sizing
+}
+
+func (m *Monkey) Execution() execution.Config {
+	return m.Params().Execution
+}
+
+func (m *Monkey) Params() MonkeyParams {
+	return *m.params.Load()
+}
+
+// SetParams validates and atomically replaces parameters of the running
+// strategy. Symbol can not be changed, because market data subscriptions
+// depend on it.
+func (m *Monkey) SetParams(p MonkeyParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if old := m.Params(); p.Symbol != old.Symbol {
+		return fmt.Errorf("monkey symbol can not be changed from %s to %s at runtime", old.Symbol, p.Symbol)
+	}
+
+	m.params.Store(&p)
+	return nil
+}
+
+func (m *Monkey) State() (json.RawMessage, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return json.Marshal(monkeyState{
+		CntUp:   m.cntUp,
+		CntDown: m.cntDown,
+		PrevBid: m.prevBid,
+		PrevAsk: m.prevAsk,
+	})
+}
+
+func (m *Monkey) Restore(state json.RawMessage) error {
+	var st monkeyState
+	if err := json.Unmarshal(state, &st); err != nil {
+		return fmt.Errorf("monkey failed to restore state: %w", err)
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.cntUp, m.cntDown = st.CntUp, st.CntDown
+	m.prevBid, m.prevAsk = st.PrevBid, st.PrevAsk
+	return nil
+}
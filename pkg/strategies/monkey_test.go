@@ -0,0 +1,80 @@
+package strategies
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+	"degen/pkg/sizing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMonkeySetParams(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewMonkey(ctx, models.NewAccount("acc", "ex"), DefaultMonkeyParams())
+
+	p := DefaultMonkeyParams()
+	p.Patience = 5
+	p.OrderSize = decimal.NewFromInt(1)
+	if err := m.SetParams(p); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Params(); got.Patience != 5 || !got.OrderSize.Equal(p.OrderSize) {
+		t.Errorf("params are not applied: %+v", got)
+	}
+
+	invalid := p
+	invalid.OrderSize = decimal.Zero
+	if err := m.SetParams(invalid); err == nil {
+		t.Error("expected error for zero order size")
+	}
+
+	moved := p
+	moved.Symbol = "btcusdt"
+	if err := m.SetParams(moved); err == nil {
+		t.Error("expected error for symbol change")
+	}
+
+	if got := m.Params(); got.Patience != 5 || got.Symbol != "ethusdt" {
+		t.Errorf("params changed after failed update: %+v", got)
+	}
+}
+
+func TestMonkeyTargets(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	acc := models.NewAccount("acc", "ex")
+	acc.UpdateBalance("usdt", decimal.NewFromInt(10000), time.Now())
+	p := DefaultMonkeyParams()
+	p.Sizing = sizing.Config{Model: sizing.ModelPercent, Percent: 50}
+	m := NewMonkey(ctx, acc, p)
+
+	var targets []sizing.Target
+	m.OnSignal(func(t sizing.Target) { targets = append(targets, t) })
+	see := func(ask int64) {
+		m.See(models.NewBBOMessage("ex", "ethusdt", time.Now(), models.BBO{
+			Bid: models.PriceLevel{Price: decimal.NewFromInt(99), Size: decimal.NewFromInt(1)},
+			Ask: models.PriceLevel{Price: decimal.NewFromInt(ask), Size: decimal.NewFromInt(1)},
+		}))
+	}
+
+	// Two ask ticks down open long position of half of equity.
+	for _, ask := range []int64{103, 102, 101} {
+		see(ask)
+	}
+	if len(targets) != 1 || !targets[0].Position.Equal(decimal.NewFromInt(50)) || !targets[0].Price.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("expected long target of 50, got %+v", targets)
+	}
+
+	// Profitable short position is closed.
+	acc.UpdatePosition("ethusdt", decimal.NewFromInt(-1), decimal.NewFromInt(200), time.Now())
+	see(100)
+	if len(targets) != 2 || !targets[1].Position.IsZero() {
+		t.Errorf("expected flat target, got %+v", targets)
+	}
+}
@@ -0,0 +1,359 @@
+package strategies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// PairsParams configure Pairs strategy.
+type PairsParams struct {
+	// Symbol is traded against HedgeSymbol, spread of the pair is
+	// log(Symbol) - alpha - ratio * log(HedgeSymbol).
+	Symbol      string `json:"symbol"`
+	HedgeSymbol string `json:"hedge_symbol"`
+	// Window is a number of samples of prices taken every Interval
+	// seconds, which hedge ratio and spread z-score are estimated over.
+	Window   int     `json:"window"`
+	Interval float64 `json:"interval"`
+	// Spread is entered when its z-score is over EntryZ, closed when
+	// it reverts under ExitZ (or beyond) and stopped when it diverges
+	// over StopZ.
+	EntryZ float64 `json:"entry_z"`
+	ExitZ  float64 `json:"exit_z"`
+	StopZ  float64 `json:"stop_z"`
+	// Notional is a size of Symbol leg in quote asset,
+	// HedgeSymbol leg is scaled by hedge ratio.
+	Notional      decimal.Decimal `json:"notional"`
+	StepSize      decimal.Decimal `json:"step_size"`
+	HedgeStepSize decimal.Decimal `json:"hedge_step_size"`
+}
+
+func DefaultPairsParams() PairsParams {
+	return PairsParams{
+		Symbol:        "ethusdt",
+		HedgeSymbol:   "btcusdt",
+		Window:        300,
+		Interval:      1,
+		EntryZ:        2,
+		ExitZ:         0.5,
+		StopZ:         4,
+		Notional:      decimal.NewFromInt(1000),
+		StepSize:      decimal.NewFromFloat(0.001),
+		HedgeStepSize: decimal.NewFromFloat(0.001),
+	}
+}
+
+// ParsePairsParams parses parameters over the defaults and validates them.
+func ParsePairsParams(raw json.RawMessage) (PairsParams, error) {
+	p := DefaultPairsParams()
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return p, fmt.Errorf("failed to parse pairs params: %w", err)
+		}
+	}
+
+	return p, p.Validate()
+}
+
+func (p PairsParams) Validate() error {
+	switch {
+	case p.Symbol == "" || p.HedgeSymbol == "":
+		return errors.New("pairs symbol is empty")
+	case p.Symbol == p.HedgeSymbol:
+		return fmt.Errorf("pairs symbols are both %s", p.Symbol)
+	case p.Window < 3:
+		return fmt.Errorf("pairs window %d is less than 3", p.Window)
+	case p.Interval <= 0:
+		return fmt.Errorf("pairs interval %v is not positive", p.Interval)
+	case p.ExitZ < 0 || p.ExitZ >= p.EntryZ || p.EntryZ >= p.StopZ:
+		return fmt.Errorf("pairs z-scores must be 0 <= exit %v < entry %v < stop %v", p.ExitZ, p.EntryZ, p.StopZ)
+	case !p.Notional.IsPositive():
+		return fmt.Errorf("pairs notional %v is not positive", p.Notional)
+	case !p.StepSize.IsPositive() || !p.HedgeStepSize.IsPositive():
+		return fmt.Errorf("pairs step sizes %v and %v must be positive", p.StepSize, p.HedgeStepSize)
+	}
+	return nil
+}
+
+// fitSpread regresses ys on xs and returns hedge ratio and z-score of
+// the last sample residual. It is not ok if xs or residuals are constant.
+func fitSpread(ys, xs []float64) (ratio, z float64, ok bool) {
+	n := float64(len(ys))
+	var meanX, meanY float64
+	for i := range ys {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX, meanY = meanX/n, meanY/n
+
+	var cov, varX float64
+	for i := range ys {
+		cov += (xs[i] - meanX) * (ys[i] - meanY)
+		varX += (xs[i] - meanX) * (xs[i] - meanX)
+	}
+	if varX == 0 {
+		return 0, 0, false
+	}
+	ratio = cov / varX
+	alpha := meanY - ratio*meanX
+
+	// Residuals of least squares have zero mean.
+	var variance, last float64
+	for i := range ys {
+		last = ys[i] - alpha - ratio*xs[i]
+		variance += last * last
+	}
+	std := math.Sqrt(variance / n)
+	if std < 1e-12 {
+		return ratio, 0, false
+	}
+	return ratio, last / std, true
+}
+
+// Pairs trades mean reversion of spread between two symbols. Hedge ratio
+// and spread z-score are estimated by regression of log prices over the
+// rolling window. When z-score is over EntryZ, spread is sold: Symbol is
+// sold and HedgeSymbol bought for Notional scaled by hedge ratio, and the
+// reverse below -EntryZ. Position is closed when z-score reverts under
+// ExitZ or stopped over StopZ, after which the spread is entered again
+// only once z-score gets back under EntryZ.
+// Both legs are placed at once as a batch, which unwinds a single placed
+// leg, legs it fails to unwind are held as the spread.
+type Pairs struct {
+	ctx    context.Context
+	params atomic.Pointer[PairsParams]
+	orders BatchOrders
+
+	mids  map[string]decimal.Decimal
+	fresh map[string]bool
+	// ys and xs are log prices of Symbol and HedgeSymbol
+	// in a ring buffer, next is index of the next sample.
+	ys, xs     []float64
+	next, seen int
+	sampledAt  time.Time
+
+	// position is 1 for long spread, -1 for short and 0 without it.
+	position int
+	// held are signed sizes of legs by symbol.
+	held    map[string]decimal.Decimal
+	stopped bool
+
+	mux sync.Mutex
+}
+
+type pairsState struct {
+	Position int                        `json:"position"`
+	Held     map[string]decimal.Decimal `json:"held"`
+	Stopped  bool                       `json:"stopped"`
+}
+
+func NewPairs(ctx context.Context, orders BatchOrders, params PairsParams) *Pairs {
+	p := &Pairs{
+		ctx:    ctx,
+		orders: orders,
+		mids:   make(map[string]decimal.Decimal),
+		fresh:  make(map[string]bool),
+		ys:     make([]float64, params.Window),
+		xs:     make([]float64, params.Window),
+		held:   make(map[string]decimal.Decimal),
+	}
+	p.params.Store(&params)
+	return p
+}
+
+func (p *Pairs) See(e models.ExchangeMessage) {
+	bbo, ok := e.BBO()
+	if !ok || !bbo.Bid.Price.IsPositive() || !bbo.Ask.Price.IsPositive() {
+		return
+	}
+	params := p.Params()
+	if e.Symbol != params.Symbol && e.Symbol != params.HedgeSymbol {
+		return
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.mids[e.Symbol] = bbo.Bid.Price.Add(bbo.Ask.Price).Div(decimal.NewFromInt(2))
+	p.fresh[e.Symbol] = true
+	// Both prices have to be updated since the previous sample.
+	if len(p.fresh) < 2 || e.Timestamp.Sub(p.sampledAt).Seconds() < params.Interval {
+		return
+	}
+	p.sampledAt, p.fresh = e.Timestamp, make(map[string]bool)
+	y, x := p.mids[params.Symbol], p.mids[params.HedgeSymbol]
+
+	p.ys[p.next], p.xs[p.next] = math.Log(y.InexactFloat64()), math.Log(x.InexactFloat64())
+	// Samples are kept in time order with the last one at the end.
+	p.next = (p.next + 1) % len(p.ys)
+	if p.seen++; p.seen < len(p.ys) {
+		return
+	}
+	ys := append(append([]float64{}, p.ys[p.next:]...), p.ys[:p.next]...)
+	xs := append(append([]float64{}, p.xs[p.next:]...), p.xs[:p.next]...)
+	ratio, z, ok := fitSpread(ys, xs)
+	if !ok {
+		return
+	}
+
+	switch {
+	case p.position == 0 && p.stopped:
+		p.stopped = math.Abs(z) >= params.EntryZ
+	case p.position == 0 && z > params.EntryZ:
+		p.enter(params, -1, ratio, z)
+	case p.position == 0 && z < -params.EntryZ:
+		p.enter(params, 1, ratio, z)
+	case p.position != 0 && float64(p.position)*z > -params.ExitZ:
+		p.exit(params, "reverted", z)
+	case p.position != 0 && -float64(p.position)*z > params.StopZ:
+		p.exit(params, "stopped", z)
+		p.stopped = p.position == 0
+	}
+}
+
+// enter buys (dir 1) or sells (dir -1) spread. Caller must hold the lock.
+func (p *Pairs) enter(params PairsParams, dir int, ratio, z float64) {
+	sign := decimal.NewFromInt(int64(dir))
+	size := params.Notional.Div(p.mids[params.Symbol]).Mul(sign)
+	hedge := params.Notional.Mul(decimal.NewFromFloat(ratio)).Div(p.mids[params.HedgeSymbol]).Mul(sign.Neg())
+	legs := map[string]decimal.Decimal{
+		params.Symbol:      size.Div(params.StepSize).Round(0).Mul(params.StepSize),
+		params.HedgeSymbol: hedge.Div(params.HedgeStepSize).Round(0).Mul(params.HedgeStepSize),
+	}
+
+	logger.Info("pairs spread entered",
+		"symbol", params.Symbol,
+		"hedge_symbol", params.HedgeSymbol,
+		"direction", dir,
+		"ratio", ratio,
+		"z", z,
+	)
+	// Failed legs are unwound by the batch, ones left open
+	// are held as the spread and closed by exit.
+	if placed, err := p.place(params, legs); err == nil || len(placed) > 0 {
+		p.position = dir
+	}
+}
+
+// exit closes held legs. Caller must hold the lock.
+func (p *Pairs) exit(params PairsParams, reason string, z float64) {
+	logger.Info("pairs spread closed",
+		"symbol", params.Symbol,
+		"hedge_symbol", params.HedgeSymbol,
+		"reason", reason,
+		"z", z,
+	)
+
+	legs := make(map[string]decimal.Decimal, len(p.held))
+	for symbol, size := range p.held {
+		legs[symbol] = size.Neg()
+	}
+	// Legs failed to close are retried on the next sample.
+	if _, err := p.place(params, legs); err == nil {
+		p.position = 0
+	}
+}
+
+// place sends market orders for signed sizes of legs at once,
+// updates held sizes with the placed ones and returns them.
+// Caller must hold the lock.
+func (p *Pairs) place(params PairsParams, legs map[string]decimal.Decimal) (map[string]decimal.Decimal, error) {
+	var orders []models.Order
+	for symbol, size := range legs {
+		if size.IsZero() {
+			continue
+		}
+		side := models.OrderSideBuy
+		if size.IsNegative() {
+			side = models.OrderSideSell
+		}
+		orders = append(orders, models.Order{
+			ClientOrderID: uuid.New().String(),
+			CreatedAt:     time.Now().UTC(),
+			Symbol:        symbol,
+			Side:          side,
+			Type:          models.OrderTypeMarket,
+			Size:          size.Abs(),
+		})
+	}
+
+	res, err := p.orders.PlaceOrders(p.ctx, orders)
+	placed := make(map[string]decimal.Decimal)
+	for i, o := range res {
+		if o == nil {
+			continue
+		}
+		size := legs[orders[i].Symbol]
+		placed[orders[i].Symbol] = size
+		if held := p.held[orders[i].Symbol].Add(size); held.IsZero() {
+			delete(p.held, orders[i].Symbol)
+		} else {
+			p.held[orders[i].Symbol] = held
+		}
+	}
+
+	if err != nil && !errors.Is(err, ErrPaused) {
+		logger.Warn("failed to place pairs orders", "symbol", params.Symbol, "err", err)
+	}
+	return placed, err
+}
+
+func (p *Pairs) Params() PairsParams {
+	return *p.params.Load()
+}
+
+// SetParams validates and atomically replaces parameters,
+// symbols and window can not be changed.
+func (p *Pairs) SetParams(params PairsParams) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	old := p.Params()
+	if params.Symbol != old.Symbol || params.HedgeSymbol != old.HedgeSymbol || params.Window != old.Window {
+		return fmt.Errorf("pairs symbols %s, %s and window %d can not be changed at runtime",
+			old.Symbol, old.HedgeSymbol, old.Window)
+	}
+
+	p.params.Store(&params)
+	return nil
+}
+
+func (p *Pairs) State() (json.RawMessage, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return json.Marshal(pairsState{
+		Position: p.position,
+		Held:     p.held,
+		Stopped:  p.stopped,
+	})
+}
+
+// Restore restores held legs, prices window is collected again.
+func (p *Pairs) Restore(state json.RawMessage) error {
+	var st pairsState
+	if err := json.Unmarshal(state, &st); err != nil {
+		return fmt.Errorf("pairs failed to restore state: %w", err)
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.position, p.stopped = st.Position, st.Stopped
+	p.held = st.Held
+	if p.held == nil {
+		p.held = make(map[string]decimal.Decimal)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package strategies
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"degen/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// batchOrders places market orders in batches, failing orders on reject
+// symbol. Placed orders of a failed batch are unwound but on stuck symbol.
+type batchOrders struct {
+	positionOrders
+	reject, stuck string
+}
+
+func (f *batchOrders) PlaceOrders(ctx context.Context, orders []models.Order) ([]*models.Order, error) {
+	res := make([]*models.Order, len(orders))
+	var err error
+	for i, o := range orders {
+		if o.Symbol == f.reject {
+			err = errors.New("rejected")
+			continue
+		}
+		res[i], _ = f.PlaceOrder(ctx, o)
+	}
+	if err == nil {
+		return res, nil
+	}
+
+	for i, o := range res {
+		if o == nil || o.Symbol == f.stuck {
+			continue
+		}
+		undo := *o
+		undo.Side = models.OrderSideBuy
+		if o.Side == models.OrderSideBuy {
+			undo.Side = models.OrderSideSell
+		}
+		_, _ = f.PlaceOrder(ctx, undo)
+		res[i] = nil
+	}
+	return res, err
+}
+
+func TestFitSpread(t *testing.T) {
+	var ys, xs []float64
+	for i := 0; i < 50; i++ {
+		x := math.Log(100 + 10*math.Sin(float64(i)))
+		xs = append(xs, x)
+		ys = append(ys, 1+1.5*x+0.001*math.Cos(float64(i*7)))
+	}
+	ratio, _, ok := fitSpread(ys, xs)
+	if !ok || math.Abs(ratio-1.5) > 0.01 {
+		t.Errorf("expected hedge ratio 1.5, got %v (%v)", ratio, ok)
+	}
+
+	ys[len(ys)-1] += 0.05
+	if _, z, _ := fitSpread(ys, xs); z < 3 {
+		t.Errorf("expected large z-score of outlier, got %v", z)
+	}
+
+	if _, _, ok := fitSpread([]float64{1, 2, 3}, []float64{1, 1, 1}); ok {
+		t.Error("expected fit to fail on constant prices")
+	}
+}
+
+func TestPairs(t *testing.T) {
+	p := DefaultPairsParams()
+	p.Window, p.StopZ = 30, 3
+
+	orders := &batchOrders{positionOrders: positionOrders{acc: models.NewAccount("acc", "ex")}}
+	pairs := NewPairs(context.Background(), orders, p)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sec := 0
+	// see feeds both symbols with Symbol price deviating from the pair by dev.
+	see := func(dev float64) {
+		x := 40000 + 2000*math.Sin(float64(sec)/5)
+		y := x / 20 * (1 + dev + 0.0005*math.Cos(float64(sec*7)))
+		ts := start.Add(time.Duration(sec) * time.Second)
+		for symbol, price := range map[string]float64{p.HedgeSymbol: x, p.Symbol: y} {
+			pairs.See(models.NewBBOMessage("ex", symbol, ts, models.BBO{
+				Bid: models.PriceLevel{Price: decimal.NewFromFloat(price), Size: decimal.NewFromInt(1)},
+				Ask: models.PriceLevel{Price: decimal.NewFromFloat(price), Size: decimal.NewFromInt(1)},
+			}))
+		}
+		sec++
+	}
+	positions := func() (decimal.Decimal, decimal.Decimal) {
+		return orders.acc.GetPosition(p.Symbol).Amount, orders.acc.GetPosition(p.HedgeSymbol).Amount
+	}
+
+	for i := 0; i < p.Window; i++ {
+		see(0)
+	}
+	if len(orders.placed) != 0 {
+		t.Fatalf("expected no trades without divergence, got %d", len(orders.placed))
+	}
+
+	// Rich Symbol is sold against HedgeSymbol and bought back on reversion.
+	see(0.01)
+	if y, x := positions(); !y.IsNegative() || !x.IsPositive() || pairs.position != -1 {
+		t.Fatalf("expected short spread, got %v and %v", y, x)
+	}
+	see(0)
+	if y, x := positions(); !y.IsZero() || !x.IsZero() || pairs.position != 0 {
+		t.Fatalf("expected closed spread, got %v and %v", y, x)
+	}
+
+	// Diverging spread is stopped and not entered again at once.
+	for i := 0; i < p.Window; i++ {
+		see(0)
+	}
+	see(0.01)
+	see(0.05)
+	if y, x := positions(); !y.IsZero() || !x.IsZero() || !pairs.stopped {
+		t.Fatalf("expected stopped spread, got %v and %v", y, x)
+	}
+	placed := len(orders.placed)
+	see(0.05)
+	if len(orders.placed) != placed {
+		t.Errorf("expected no entry after stop, got %d orders", len(orders.placed)-placed)
+	}
+
+	// Single placed leg is unwound.
+	for i := 0; i < p.Window; i++ {
+		see(0)
+	}
+	orders.reject = p.HedgeSymbol
+	see(0.01)
+	if y, x := positions(); !y.IsZero() || !x.IsZero() || pairs.position != 0 {
+		t.Errorf("expected leg to be unwound, got %v and %v", y, x)
+	}
+
+	// Leg failed to unwind is held and closed on reversion.
+	orders.stuck = p.Symbol
+	see(0.01)
+	if y, x := positions(); !y.IsNegative() || !x.IsZero() || pairs.position != -1 {
+		t.Fatalf("expected single held leg, got %v and %v", y, x)
+	}
+	orders.reject, orders.stuck = "", ""
+	see(0)
+	if y, x := positions(); !y.IsZero() || !x.IsZero() || pairs.position != 0 {
+		t.Errorf("expected held leg to be closed, got %v and %v", y, x)
+	}
+}
@@ -0,0 +1,90 @@
+package strategies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"degen/pkg/execution"
+	"degen/pkg/logging"
+	"degen/pkg/models"
+	"degen/pkg/sizing"
+)
+
+var logger = logging.New("strategies")
+
+// ErrPaused is returned by Orders of a paused strategy.
+var ErrPaused = errors.New("strategy is paused")
+
+// Strategy is a running strategy instance fed with market data.
+type Strategy interface {
+	Stateful
+	See(msg models.ExchangeMessage)
+}
+
+// Stateful strategies can save their state into journal
+// snapshots and restore it after restart.
+type Stateful interface {
+	State() (json.RawMessage, error)
+	Restore(state json.RawMessage) error
+}
+
+// Orders is an order management API strategies trade with.
+type Orders interface {
+	PlaceOrder(ctx context.Context, order models.Order) (*models.Order, error)
+	CancelOrder(ctx context.Context, order models.Order) (*models.Order, error)
+	// OpenOrders returns open orders of the account.
+	OpenOrders() []models.Order
+}
+
+// Batch is implemented by Orders able to place several orders at once,
+// all or none: placed orders are unwound if any of them fails. Results
+// are in order of orders, on error only orders left open are set.
+type Batch interface {
+	PlaceOrders(ctx context.Context, orders []models.Order) ([]*models.Order, error)
+}
+
+// BatchOrders are Orders placing several orders at once.
+type BatchOrders interface {
+	Orders
+	Batch
+}
+
+// Hedger is implemented by Orders able to place orders hedging already
+// filled ones, which are not rejected while the strategy is paused.
+type Hedger interface {
+	PlaceHedge(ctx context.Context, order models.Order) (*models.Order, error)
+}
+
+// Venue is an exchange account strategies trading
+// on several exchanges use.
+type Venue struct {
+	Exchange string
+	Account  *models.Account
+	Orders   Orders
+}
+
+// OrderWatcher strategies are notified about updates
+// of orders on their symbols, no update is dropped.
+type OrderWatcher interface {
+	OnOrderUpdate(upd models.OrderUpdate)
+}
+
+// Targeting strategies report target positions instead of placing
+// orders, orders reaching them are sized by the engine.
+type Targeting interface {
+	Say() <-chan sizing.Target
+	// OnSignal makes the strategy report targets to fn
+	// synchronously instead of Say() channel.
+	OnSignal(fn func(sizing.Target))
+	// Sizing returns limits orders are sized with.
+	Sizing() sizing.Config
+	// Execution returns algorithm orders are worked with.
+	Execution() execution.Config
+}
+
+// Resting strategies keep orders in the book, which have to be
+// canceled when the strategy is paused or stopped.
+type Resting interface {
+	CancelOrders(ctx context.Context) (int, error)
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"degen/pkg/accounts"
+	"degen/pkg/admin"
+	"degen/pkg/bus"
+	"degen/pkg/config"
+	"degen/pkg/connectors/binance"
+	"degen/pkg/journal"
+	"degen/pkg/latency"
+	"degen/pkg/logging"
+	"degen/pkg/metrics"
+	"degen/pkg/models"
+	"degen/pkg/paper"
+)
+
+// feedLog logs market data, sampled as it is written for every update.
+var feedLog = logging.New("feed").Sampled(100)
+
+func newSigner(creds accounts.Credentials) (binance.Signer, error) {
+	if creds.KeyFile != "" {
+		return binance.LoadPEMSigner(creds.KeyFile)
+	}
+	return binance.NewHMACSigner(creds.Secret), nil
+}
+
+// trade runs strategies on live market data. In paper mode orders
+// are simulated, no API keys are needed and the journal is not used.
+func trade(ctx context.Context, cfg *config.Config, paperMode bool) error {
+	var (
+		jrnl  *journal.Journal
+		state *journal.State
+		err   error
+	)
+	if !paperMode {
+		if state, err = journal.Load(cfg.Journal); err != nil {
+			return err
+		}
+		if jrnl, err = journal.Open(cfg.Journal); err != nil {
+			return err
+		}
+		defer jrnl.Close()
+	}
+
+	e := newEngine(cfg, jrnl)
+
+	ch := make(chan models.ExchangeMessage, 100)
+	emit := func(msg models.ExchangeMessage) {
+		select {
+		case ch <- msg:
+		case <-ctx.Done():
+		}
+	}
+
+	// Simulated exchanges do not wait for consumers of their updates.
+	paperQueue := paper.NewQueue(ctx, emit)
+
+	// Every account has its own connector signing with its API keys,
+	// market data is subscribed to by the first account of an exchange.
+	for _, exCfg := range cfg.Exchanges {
+		exCfg := exCfg
+		var (
+			creds  accounts.Credentials
+			signer binance.Signer = binance.NewHMACSigner("")
+		)
+		if !paperMode {
+			if creds, err = exCfg.Credentials.Resolve(); err != nil {
+				return fmt.Errorf("%s/%s credentials: %w", exCfg.Name, exCfg.Account, err)
+			}
+			if signer, err = newSigner(creds); err != nil {
+				return fmt.Errorf("failed to load %s/%s API key: %w", exCfg.Name, exCfg.Account, err)
+			}
+		}
+
+		bnc := binance.NewBinanceWithSigner(ctx, creds.Key, signer, exCfg.APIURL, exCfg.WSURL)
+		if bnc == nil {
+			return ctx.Err()
+		}
+		bnc.SetName(exCfg.Name)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := bnc.Close(ctx); err != nil {
+				logger.Error("failed to close connector", "exchange", exCfg.Name, "account", exCfg.Account, "err", err)
+			}
+		}()
+
+		if paperMode {
+			sim := paper.New(exCfg.Name, exCfg.Account, cfg.Paper, paperQueue.Emit)
+			v, err := e.addVenue(exCfg, creds, sim, sim)
+			if err != nil {
+				return err
+			}
+			v.paper = sim
+		} else {
+			bnc.SetAccountID(exCfg.Account)
+			if exCfg.WSAPIURL != "" {
+				bnc.UseWSAPI(ctx, exCfg.WSAPIURL)
+			}
+			if _, err := e.addVenue(exCfg, creds, bnc.Orders, bnc.API); err != nil {
+				return err
+			}
+		}
+
+		go bnc.Listen(ctx, ch)
+
+		if !cfg.Primary(exCfg) {
+			continue
+		}
+		if err := bnc.SubscribeBookTickers(ctx, cfg.Symbols(exCfg.Name)); err != nil {
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+		if symbols := cfg.TradeSymbols(exCfg.Name); len(symbols) > 0 {
+			if err := bnc.SubscribeBookAggTrades(ctx, symbols); err != nil {
+				return fmt.Errorf("failed to subscribe to trades: %w", err)
+			}
+		}
+		if symbols := cfg.FundingSymbols(exCfg.Name); len(symbols) > 0 {
+			if err := bnc.SubscribeMarkPrices(ctx, symbols); err != nil {
+				return fmt.Errorf("failed to subscribe to funding: %w", err)
+			}
+		}
+	}
+
+	if err := e.addStrategies(ctx); err != nil {
+		return err
+	}
+
+	// Restoring state from the journal, then reconciling it
+	// with the exchange before strategies are allowed to trade.
+	if state != nil {
+		e.restore(state)
+	}
+	if err := e.reconcile(ctx); err != nil {
+		return fmt.Errorf("failed to reconcile state: %w", err)
+	}
+
+	// Strategy parameters are reloaded from the config file on SIGHUP.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-hup:
+				logger.Info("reloading strategy parameters", "config", cfg.Path)
+				if err := e.reloadParams(); err != nil {
+					logger.Error("failed to reload strategy parameters", "err", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if cfg.Metrics.Listen != "" {
+		metrics.OnCollect(e.collectMetrics)
+		go func() {
+			if err := metrics.Default.ListenAndServe(ctx, cfg.Metrics.Listen); err != nil {
+				logger.Error("metrics server failed", "err", err)
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	consume := func(sub *bus.Subscription, h models.Handlers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range sub.C() {
+				h.Handle(msg)
+			}
+		}()
+	}
+
+	eventBus := bus.New()
+	e.bus = eventBus
+
+	if cfg.Admin.Listen != "" {
+		token, err := cfg.Admin.Token()
+		if err != nil {
+			return err
+		}
+		srv, err := admin.New(e, token)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := srv.ListenAndServe(ctx, cfg.Admin.Listen); err != nil {
+				logger.Error("admin server failed", "err", err)
+			}
+		}()
+	}
+
+	for _, r := range e.runners {
+		r := r
+		// Only BBOs are conflated, other market data must not be
+		// dropped and goes through a separate blocking subscription.
+		var events []models.MsgType
+		for _, t := range r.feeds {
+			if t != models.MsgTypeBBO {
+				events = append(events, t)
+			}
+		}
+
+		consume(eventBus.Subscribe(bus.Options{
+			Name: r.name,
+			Filter: bus.Filter{
+				Exchanges: r.exchanges(),
+				Symbols:   r.symbols,
+				MsgTypes:  []models.MsgType{models.MsgTypeBBO},
+			},
+			QueueSize: 10,
+			Policy:    bus.PolicyConflate,
+		}), models.Handlers{
+			BBO: func(msg models.ExchangeMessage, bbo models.BBO) {
+				// Arguments are boxed on every update otherwise.
+				if feedLog.Enabled(logging.LevelDebug) {
+					feedLog.Debug("bbo",
+						"exchange", msg.Exchange,
+						"symbol", msg.Symbol,
+						"bid", bbo.Bid.Price,
+						"ask", bbo.Ask.Price,
+					)
+				}
+				r.see(msg)
+				e.latency.ObserveMessage(msg)
+				e.latency.Record(latency.Stream(msg), latency.StageStrategy, time.Since(msg.DispatchedAt))
+			},
+		})
+
+		if len(events) > 0 {
+			consume(eventBus.Subscribe(bus.Options{
+				Name: r.name + "_events",
+				Filter: bus.Filter{
+					Exchanges: r.exchanges(),
+					Symbols:   r.symbols,
+					MsgTypes:  events,
+				},
+				Policy: bus.PolicyBlock,
+			}), models.Handlers{
+				Trade: func(msg models.ExchangeMessage, _ models.Trade) {
+					r.see(msg)
+				},
+				Funding: func(msg models.ExchangeMessage, f models.Funding) {
+					feedLog.Debug("funding",
+						"exchange", msg.Exchange,
+						"symbol", msg.Symbol,
+						"rate", f.Rate,
+						"mark_price", f.MarkPrice,
+					)
+					r.strategy.See(msg)
+				},
+			})
+		}
+
+		if h, ok := r.watchOrders(); ok {
+			consume(eventBus.Subscribe(bus.Options{
+				Name: r.name + "_orders",
+				Filter: bus.Filter{
+					Exchanges: r.exchanges(),
+					Symbols:   r.symbols,
+					MsgTypes:  []models.MsgType{models.MsgTypeOrderStatus},
+				},
+				Policy: bus.PolicyBlock,
+			}), h)
+		}
+
+		if r.targeting == nil {
+			continue
+		}
+		go func() {
+			for {
+				select {
+				case t := <-r.targeting.Say():
+					e.placeTarget(ctx, r, t)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	if paperMode {
+		consume(eventBus.Subscribe(bus.Options{
+			Name:      "paper",
+			Filter:    bus.Filter{MsgTypes: []models.MsgType{models.MsgTypeBBO}},
+			QueueSize: 10,
+			Policy:    bus.PolicyConflate,
+		}), models.Handlers{
+			BBO: e.onBBO,
+		})
+	}
+
+	consume(eventBus.Subscribe(bus.Options{
+		Name:      "pnl",
+		Filter:    bus.Filter{MsgTypes: []models.MsgType{models.MsgTypeBBO}},
+		QueueSize: 10,
+		Policy:    bus.PolicyConflate,
+	}), models.Handlers{
+		BBO: func(msg models.ExchangeMessage, bbo models.BBO) {
+			e.tracker.OnBBO(msg.Exchange, msg.Symbol, bbo)
+		},
+	})
+
+	consume(eventBus.Subscribe(bus.Options{
+		Name: "orders",
+		Filter: bus.Filter{MsgTypes: []models.MsgType{
+			models.MsgTypeFill,
+			models.MsgTypeOrderStatus,
+		}},
+		Policy: bus.PolicyBlock,
+	}), e.orderHandlers())
+
+	consume(eventBus.Subscribe(bus.Options{
+		Name: "account",
+		Filter: bus.Filter{MsgTypes: []models.MsgType{
+			models.MsgTypeBalanceUpdate,
+			models.MsgTypePositionUpdate,
+		}},
+		Policy: bus.PolicyBlock,
+	}), e.accountHandlers())
+
+	go func() {
+		var lastChange time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+				changed := false
+				for _, acc := range e.accs.List() {
+					for _, b := range acc.Balances() {
+						if b.UpdatedAt.After(lastChange) {
+							lastChange = b.UpdatedAt
+							changed = true
+						}
+					}
+				}
+				if changed {
+					e.logPnL()
+				}
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.snapshot()
+				for _, st := range eventBus.Stats() {
+					logger.Info("bus subscriber",
+						"name", st.Name,
+						"queued", st.Queued,
+						"dropped", st.Dropped,
+						"conflated", st.Conflated,
+						"lag", st.LastLag,
+						"max_lag", st.MaxLag,
+					)
+				}
+				for _, st := range e.latency.Stats() {
+					logger.Info("latency",
+						"stream", st.Stream,
+						"stage", st.Stage,
+						"count", st.Count,
+						"p50", st.P50,
+						"p90", st.P90,
+						"p99", st.P99,
+						"max", st.Max,
+					)
+				}
+			}
+		}
+	}()
+
+	eventBus.Run(ctx, ch)
+	wg.Wait()
+
+	// Strategy orders resting in the book are not left behind.
+	cancelCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, r := range e.runners {
+		r.cancelOrders(cancelCtx)
+	}
+	e.snapshot()
+
+	return nil
+}